@@ -1,26 +1,60 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/codegen"
+	"github.com/renato0307/go-mcp-rest/internal/specload"
 )
 
 // CLI defines the command-line interface structure
 type CLI struct {
-	Spec           string `name:"spec" help:"Path or URL to the OpenAPI spec" required:""`
-	OutputDir      string `name:"output-dir" help:"Output directory for the generated client code" default:"./generated/api"`
-	Filename       string `name:"filename" help:"Name of the generated file" default:"client.go"`
-	Package        string `name:"package" help:"Package name for the generated code" default:"api"`
-	GenerateTypes  bool   `name:"generate-types" help:"Generate type definitions" default:"true"`
-	GenerateClient bool   `name:"generate-client" help:"Generate client code" default:"true"`
+	Spec               string        `name:"spec" help:"Path or URL to the OpenAPI spec, - reads standard input" required:""`
+	OutputDir          string        `name:"output-dir" help:"Output directory for the generated client code" default:"./generated/api"`
+	Filename           string        `name:"filename" help:"Name of the generated file" default:"client.go"`
+	Package            string        `name:"package" help:"Package name for the generated code" default:"api"`
+	GenerateTypes      bool          `name:"generate-types" help:"Generate type definitions" default:"true"`
+	GenerateClient     bool          `name:"generate-client" help:"Generate client code" default:"true"`
+	StrictServer       bool          `name:"generate-strict-server" help:"Generate the strict server interface wrapping the generated server"`
+	ChiServer          bool          `name:"generate-chi-server" help:"Generate chi server boilerplate"`
+	EchoServer         bool          `name:"generate-echo-server" help:"Generate echo server boilerplate"`
+	FiberServer        bool          `name:"generate-fiber-server" help:"Generate fiber server boilerplate"`
+	GinServer          bool          `name:"generate-gin-server" help:"Generate gin server boilerplate"`
+	GorillaServer      bool          `name:"generate-gorilla-server" help:"Generate Gorilla server boilerplate"`
+	IrisServer         bool          `name:"generate-iris-server" help:"Generate iris server boilerplate"`
+	StdHTTPServer      bool          `name:"generate-std-http-server" help:"Generate net/http server boilerplate"`
+	EmbeddedSpec       bool          `name:"generate-embedded-spec" help:"Embed the spec in the generated code"`
+	TypeMappings       []string      `name:"type-mapping" help:"Go type of the schemas of a format, as format=goType with the type after its import path such as uuid=github.com/google/uuid.UUID, repeatable" sep:"none"`
+	OutputFormat       string        `name:"output-format" help:"Format of the generation report printed to stdout" enum:"text,json" default:"text"`
+	SpecHeaders        []string      `name:"spec-header" help:"Header sent when fetching a remote spec, as Name: Value, repeatable" sep:"none"`
+	SpecBearer         string        `name:"spec-bearer" help:"Bearer token sent when fetching a remote spec"`
+	SpecCacheDir       string        `name:"spec-cache-dir" help:"Directory caching the fetched remote specs by URL, shared with mcp-rest-server-gen, no cache when empty"`
+	SpecCacheTTL       time.Duration `name:"spec-cache-ttl" help:"Time a spec cached in --spec-cache-dir is reused before being fetched again" default:"1h"`
+	SynthesizeIDs      bool          `name:"synthesize-ids" help:"Give the operations without an operationId one made of their method and path, such as GetBooksById for GET /books/{id}"`
+	DedupeStrategy     string        `name:"dedupe-strategy" aliases:"on-duplicate" help:"What to do with the operations sharing an operationId, rename suffixes their IDs with the path segment telling them apart" enum:"error,rename" default:"error"`
+	OapiCodegenVersion string        `name:"oapi-codegen-version" help:"Version of oapi-codegen the client must be generated with, such as v2.4.1, the generation fails when the one built into the generator differs"`
+}
+
+// Report summarizes a generation run for machine consumption
+type Report struct {
+	Operations []string         `json:"operations"`
+	Files      []string         `json:"files"`
+	Warnings   []string         `json:"warnings"`
+	Timings    map[string]int64 `json:"timings"`
 }
 
 func main() {
@@ -29,157 +63,265 @@ func main() {
 		kong.Name("mcp-rest-client-gen"),
 		kong.Description("Generate Go client code from OpenAPI spec using oapi-codegen"))
 
+	// The client is generated by the oapi-codegen library built into the
+	// generator, the version pinned by the build is checked instead of installed
+	if cli.OapiCodegenVersion != "" {
+		if err := checkOapiCodegenVersion(cli.OapiCodegenVersion); err != nil {
+			ctx.FatalIfErrorf(err)
+		}
+	}
+
+	start := time.Now()
+	report := Report{
+		Operations: []string{},
+		Files:      []string{},
+		Warnings:   []string{},
+		Timings:    map[string]int64{},
+	}
+
 	// Get the spec content
-	specContent, err := getSpecContent(cli.Spec)
+	headers, err := specload.Headers(cli.SpecHeaders, cli.SpecBearer)
 	if err != nil {
-		ctx.FatalIfErrorf(err, "Error getting spec content")
+		ctx.FatalIfErrorf(err, "Error parsing spec headers")
 	}
-
-	// Save the spec content to a temporary file
-	tempDir, err := os.MkdirTemp("", "oapi-codegen")
+	typeMappings, err := parseTypeMappings(cli.TypeMappings)
 	if err != nil {
-		ctx.FatalIfErrorf(err, "Error creating temp directory")
+		ctx.FatalIfErrorf(err, "Error parsing type mappings")
 	}
-	defer os.RemoveAll(tempDir)
-
-	tempSpecPath := filepath.Join(tempDir, "spec.yaml")
-	if err := os.WriteFile(tempSpecPath, specContent, 0644); err != nil {
-		ctx.FatalIfErrorf(err, "Error writing spec to temp file")
+	specContent, source, err := getSpecContent(cli.Spec, headers, cli.SpecCacheDir, cli.SpecCacheTTL)
+	if err != nil {
+		ctx.FatalIfErrorf(err, "Error getting spec content")
 	}
+	report.Timings["loadMs"] = time.Since(start).Milliseconds()
 
-	// Create config file for oapi-codegen
-	configPath := filepath.Join(tempDir, "config.yaml")
-	configContent := createConfigContent(cli.Package, cli.GenerateTypes, cli.GenerateClient)
-	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
-		ctx.FatalIfErrorf(err, "Error writing config file")
+	// Operations without an operationId are given one and the duplicate
+	// operations are renamed as mcp-rest-server-gen does, so the client methods
+	// match the tools, or the duplicates are reported all at once
+	if cli.SynthesizeIDs || cli.DedupeStrategy == "rename" {
+		renamed, renames, err := renameSpecOperations(specContent, source, cli.SynthesizeIDs, cli.DedupeStrategy == "rename")
+		if err != nil {
+			ctx.FatalIfErrorf(err, "Error renaming operations")
+		}
+		for _, rename := range renames {
+			log.Println(rename)
+		}
+		specContent = renamed
 	}
-
-	// Check if oapi-codegen is installed
-	if _, err := exec.LookPath("oapi-codegen"); err != nil {
-		log.Println("oapi-codegen not found. Installing...")
-		cmd := exec.Command("go", "install", "github.com/kin-openapi/oapi-codegen/v2/cmd/oapi-codegen@latest")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			ctx.FatalIfErrorf(err, "Error installing oapi-codegen")
+	if cli.DedupeStrategy != "rename" {
+		if err := checkSpecOperations(specContent, source); err != nil {
+			ctx.FatalIfErrorf(err, "Error checking operation IDs")
 		}
 	}
 
+	operations, err := listOperations(specContent, source)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not list the spec operations: %v", err))
+	}
+	report.Operations = append(report.Operations, operations...)
+
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(cli.OutputDir, 0755); err != nil {
 		ctx.FatalIfErrorf(err, "Error creating output directory")
 	}
 
-	// Try the direct command approach first
-	log.Println("Generating client code...")
-
-	// First check if we got a new enough version of oapi-codegen that supports the "-config" flag
-	versionCmd := exec.Command("oapi-codegen", "--version")
-	versionOutput, _ := versionCmd.CombinedOutput()
-	log.Printf("oapi-codegen version: %s\n", string(versionOutput))
-
-	// Create a complete command with all parameters directly
-	var cmd *exec.Cmd
-	if cli.GenerateClient && cli.GenerateTypes {
-		cmd = exec.Command("oapi-codegen",
-			"-package", cli.Package,
-			"-generate", "types,client",
-			tempSpecPath)
-	} else if cli.GenerateClient {
-		cmd = exec.Command("oapi-codegen",
-			"-package", cli.Package,
-			"-generate", "client",
-			tempSpecPath)
-	} else if cli.GenerateTypes {
-		cmd = exec.Command("oapi-codegen",
-			"-package", cli.Package,
-			"-generate", "types",
-			tempSpecPath)
-	} else {
-		ctx.FatalIfErrorf(fmt.Errorf("invalid options"), "At least one of generate-types or generate-client must be true")
-	}
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Println("Error output from oapi-codegen:")
-		log.Println(string(output))
-		// Try alternative approach with config file
-		configCmd := exec.Command("oapi-codegen", "-config", configPath, tempSpecPath)
-		output, err = configCmd.CombinedOutput()
-		if err != nil {
-			log.Println("Config file approach also failed:")
-			log.Println(string(output))
-			log.Printf("Config file content:\n%s\n", configContent)
-			ctx.FatalIfErrorf(err, "Error running oapi-codegen")
-		}
+	log.Printf("Generating client code with oapi-codegen %s...\n", oapiCodegenVersion())
+	outputFilePath := filepath.Join(cli.OutputDir, cli.Filename)
+	if err := generateClient(specContent, source, outputFilePath, cli.Package, cli.generateOptions(), typeMappings); err != nil {
+		ctx.FatalIfErrorf(err, "Error generating client code")
+	}
+	report.Files = append(report.Files, outputFilePath)
+	report.Timings["totalMs"] = time.Since(start).Milliseconds()
+
+	log.Printf("Successfully generated client code at %s\n", outputFilePath)
+
+	if cli.OutputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		ctx.FatalIfErrorf(encoder.Encode(report), "Error writing report")
 	}
+}
 
-	// Check if output is empty
-	if len(output) == 0 {
-		log.Println("Warning: oapi-codegen produced empty output")
-		ctx.FatalIfErrorf(fmt.Errorf("empty output"), "oapi-codegen generated empty output")
+// listOperations returns the sorted operation IDs declared in the spec
+func listOperations(specContent []byte, source specSource) ([]string, error) {
+	doc, err := source.load(specContent)
+	if err != nil {
+		return nil, err
 	}
 
-	// Write the generated code to the output file
-	outputFilePath := filepath.Join(cli.OutputDir, cli.Filename)
-	if err := os.WriteFile(outputFilePath, output, 0644); err != nil {
-		ctx.FatalIfErrorf(err, "Error writing output file")
+	var operations []string
+	for _, pathItem := range doc.Paths.Map() {
+		for _, operation := range pathItem.Operations() {
+			if operation.OperationID != "" {
+				operations = append(operations, operation.OperationID)
+			}
+		}
 	}
+	sort.Strings(operations)
 
-	log.Printf("Successfully generated client code at %s\n", outputFilePath)
+	return operations, nil
+}
+
+// specSource is where the spec content was read from, its relative references
+// are resolved against the location and read with readFromURI
+type specSource struct {
+	location    *url.URL
+	readFromURI openapi3.ReadFromURIFunc
 }
 
-// getSpecContent retrieves the OpenAPI spec content from a URL or file path
-func getSpecContent(specPath string) ([]byte, error) {
+// load parses the spec content along with the files and URLs it references.
+// The content may have been rewritten once read, so it is loaded with its
+// location as LoadFromFile and LoadFromURI do for the spec files and URLs.
+func (source specSource) load(specContent []byte) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	if source.readFromURI != nil {
+		loader.ReadFromURIFunc = source.readFromURI
+	}
+	return loader.LoadFromDataWithPath(specContent, source.location)
+}
+
+// getSpecContent retrieves the OpenAPI spec content from a URL, sent with the
+// given headers and cached in the cache directory for the ttl, a file path or
+// standard input, with the source its references are read from
+func getSpecContent(specPath string, headers http.Header, cacheDir string, cacheTTL time.Duration) ([]byte, specSource, error) {
+	// A dash reads the piped spec, its relative references are resolved
+	// against the working directory
+	if specPath == "-" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, specSource{}, err
+		}
+		content, err := io.ReadAll(os.Stdin)
+		return content, specSource{location: &url.URL{Path: filepath.ToSlash(filepath.Join(dir, "openapi.yaml"))}}, err
+	}
+
 	// Check if the spec path is a URL
 	if strings.HasPrefix(specPath, "http://") || strings.HasPrefix(specPath, "https://") {
+		specURL, err := url.Parse(specPath)
+		if err != nil {
+			return nil, specSource{}, fmt.Errorf("invalid spec URL: %w", err)
+		}
+
+		// The files the spec references are fetched with the same headers
+		client := specload.HTTPClient(specURL, headers)
+		source := specSource{
+			location:    specURL,
+			readFromURI: openapi3.URIMapCache(openapi3.ReadFromURIs(openapi3.ReadFromHTTP(client), openapi3.ReadFromFile)),
+		}
+		if content, ok := specload.Cached(cacheDir, specPath, cacheTTL); ok {
+			log.Printf("Using the spec cached in %s\n", cacheDir)
+			return content, source, nil
+		}
+
 		// Fetch the spec from the URL
-		resp, err := http.Get(specPath)
+		resp, err := client.Get(specPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch spec from URL: %w", err)
+			return nil, specSource{}, fmt.Errorf("failed to fetch spec from URL: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("received non-OK response: %s", resp.Status)
+			return nil, specSource{}, fmt.Errorf("received non-OK response: %s", resp.Status)
 		}
 
-		return io.ReadAll(resp.Body)
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, specSource{}, err
+		}
+		if cacheDir != "" {
+			if err := specload.Cache(cacheDir, specPath, content); err != nil {
+				log.Printf("Warning: could not cache the spec: %v\n", err)
+			}
+		}
+		return content, source, nil
 	}
 
 	// Otherwise, read from the file
-	return os.ReadFile(specPath)
+	content, err := os.ReadFile(specPath)
+	return content, specSource{location: &url.URL{Path: filepath.ToSlash(specPath)}}, err
 }
 
-// createConfigContent creates the configuration content for oapi-codegen
-func createConfigContent(packageName string, generateTypes, generateClient bool) string {
-	// Start with package name
-	config := fmt.Sprintf("package: %s\n", packageName)
+// oapiCodegenVersion returns the version of the oapi-codegen library built
+// into the generator, set by go.mod
+func oapiCodegenVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/oapi-codegen/oapi-codegen/v2" {
+				return dep.Version
+			}
+		}
+	}
+	return "(unknown version)"
+}
+
+// checkOapiCodegenVersion fails when the oapi-codegen version built into the
+// generator is not the wanted one, given with or without its v prefix
+func checkOapiCodegenVersion(want string) error {
+	if version := oapiCodegenVersion(); "v"+strings.TrimPrefix(want, "v") != version {
+		return fmt.Errorf("the generator is built with oapi-codegen %s, not %s; build it with the wanted version in go.mod", version, want)
+	}
+	return nil
+}
 
-	// Add output target
-	config += "output: stdout\n"
+// generateOptions returns the oapi-codegen generators requested by the flags
+func (cli CLI) generateOptions() codegen.GenerateOptions {
+	return codegen.GenerateOptions{
+		Models:        cli.GenerateTypes,
+		Client:        cli.GenerateClient,
+		Strict:        cli.StrictServer,
+		ChiServer:     cli.ChiServer,
+		EchoServer:    cli.EchoServer,
+		FiberServer:   cli.FiberServer,
+		GinServer:     cli.GinServer,
+		GorillaServer: cli.GorillaServer,
+		IrisServer:    cli.IrisServer,
+		StdHTTPServer: cli.StdHTTPServer,
+		EmbeddedSpec:  cli.EmbeddedSpec,
+	}
+}
 
-	// Add generate options
-	config += "generate:\n"
+// hasServer reports whether one of the server generators is requested
+func hasServer(generate codegen.GenerateOptions) bool {
+	return generate.ChiServer || generate.EchoServer || generate.FiberServer || generate.GinServer ||
+		generate.GorillaServer || generate.IrisServer || generate.StdHTTPServer
+}
 
-	if generateTypes {
-		config += "  models: true\n"
-	} else {
-		config += "  models: false\n"
+// generateClient generates the code of the spec content with the requested
+// oapi-codegen generators into the output file, the schemas of the mapped
+// formats given their Go type
+func generateClient(specContent []byte, source specSource, outputFilePath, packageName string, generate codegen.GenerateOptions, typeMappings map[string]goType) error {
+	if !generate.Models && !generate.Client && !hasServer(generate) && !generate.EmbeddedSpec {
+		return fmt.Errorf("at least one of generate-types, generate-client, a server or generate-embedded-spec must be true")
+	}
+	if generate.Strict && !hasServer(generate) {
+		return fmt.Errorf("generate-strict-server wraps a generated server, request one such as generate-chi-server")
 	}
 
-	if generateClient {
-		config += "  client: true\n"
-	} else {
-		config += "  client: false\n"
+	doc, err := source.load(specContent)
+	if err != nil {
+		return fmt.Errorf("error loading spec: %w", err)
+	}
+	// oapi-codegen takes the external references for other packages, the
+	// referenced schemas are moved into the components instead
+	doc.InternalizeRefs(context.Background(), nil)
+	if len(typeMappings) > 0 {
+		log.Printf("Mapped %d schemas to custom Go types\n", applyTypeMappings(doc, typeMappings))
 	}
 
-	// Set other generators to false
-	config += "  echo-server: false\n"
-	config += "  chi-server: false\n"
-	config += "  fiber-server: false\n"
-	config += "  gin-server: false\n"
-	config += "  strict-server: false\n"
+	config := codegen.Configuration{
+		PackageName: packageName,
+		Generate:    generate,
+	}.UpdateDefaults()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid oapi-codegen configuration: %w", err)
+	}
 
-	return config
+	code, err := codegen.Generate(doc, config)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFilePath, []byte(code), 0644); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+	return nil
 }
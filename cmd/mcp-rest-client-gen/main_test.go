@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	// generator is the binary of the generator built for the tests of the run
+	generator     string
+	generatorErr  error
+	generatorOnce sync.Once
+)
+
+// TestMain removes the generator binary once the tests ran
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if generator != "" {
+		os.RemoveAll(filepath.Dir(generator))
+	}
+	os.Exit(code)
+}
+
+// runGenerator runs the generator with the flags, returning its standard
+// output. The generator is built once and skipped in short mode.
+func runGenerator(t *testing.T, args ...string) ([]byte, error) {
+	t.Helper()
+	return pipeGenerator(t, nil, args...)
+}
+
+// pipeGenerator runs the generator as runGenerator does, with the standard
+// input read from stdin
+func pipeGenerator(t *testing.T, stdin io.Reader, args ...string) ([]byte, error) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building the generator is skipped in short mode")
+	}
+	generatorOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "mcp-rest-client-gen")
+		if err != nil {
+			generatorErr = err
+			return
+		}
+		generator = filepath.Join(dir, "mcp-rest-client-gen")
+		if output, err := exec.Command("go", "build", "-o", generator, ".").CombinedOutput(); err != nil {
+			generatorErr = fmt.Errorf("%w\n%s", err, output)
+		}
+	})
+	if generatorErr != nil {
+		t.Fatalf("building the generator: %v", generatorErr)
+	}
+
+	cmd := exec.Command(generator, args...)
+	cmd.Stdin = stdin
+	stdout, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		t.Logf("generator logs:\n%s", exitErr.Stderr)
+	}
+	return stdout, err
+}
+
+func TestJSONReport(t *testing.T) {
+	dir := t.TempDir()
+	stdout, err := runGenerator(t, "--spec", "testdata/books.yaml", "--output-dir", dir, "--output-format", "json")
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(stdout, &report); err != nil {
+		t.Fatalf("the report %q is not JSON: %v", stdout, err)
+	}
+	if len(report.Operations) != 2 || report.Operations[0] != "AddBook" || report.Operations[1] != "ListBooks" {
+		t.Errorf("the reported operations are %v, want the 2 of the spec", report.Operations)
+	}
+	if len(report.Files) != 1 || report.Files[0] != filepath.Join(dir, "client.go") {
+		t.Errorf("the reported files are %v", report.Files)
+	}
+}
+
+func TestStdinSpec(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runGenerator(t, "--spec", "testdata/books.yaml", "--output-dir", dir); err != nil {
+		t.Fatalf("generating from the file: %v", err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "client.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The piped spec gives the client of the same spec read from its file
+	spec, err := os.Open("testdata/books.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spec.Close()
+	dir = t.TempDir()
+	if _, err := pipeGenerator(t, spec, "--spec", "-", "--output-dir", dir); err != nil {
+		t.Fatalf("generating from standard input: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "client.go")); err != nil || string(got) != string(want) {
+		t.Errorf("the client generated from standard input differs from the one of the file: %v\n%s", err, got)
+	}
+}
+
+func TestOapiCodegenVersion(t *testing.T) {
+	goMod, err := os.ReadFile("../../go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := regexp.MustCompile(`github.com/oapi-codegen/oapi-codegen/v2 (v\S+)`).FindSubmatch(goMod)
+	if version == nil {
+		t.Fatal("go.mod does not require oapi-codegen")
+	}
+
+	// The version of go.mod is accepted with or without its v prefix
+	for _, wanted := range []string{string(version[1]), strings.TrimPrefix(string(version[1]), "v")} {
+		if _, err := runGenerator(t, "--spec", "testdata/books.yaml", "--output-dir", t.TempDir(), "--oapi-codegen-version", wanted); err != nil {
+			t.Errorf("generating with oapi-codegen %s: %v", wanted, err)
+		}
+	}
+
+	dir := t.TempDir()
+	_, err = runGenerator(t, "--spec", "testdata/books.yaml", "--output-dir", dir, "--oapi-codegen-version", "v2.0.0")
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || !strings.Contains(string(exitErr.Stderr), "the generator is built with oapi-codegen "+string(version[1])+", not v2.0.0") {
+		t.Errorf("generating with another oapi-codegen version failed with %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "client.go")); !os.IsNotExist(err) {
+		t.Errorf("a client was generated with another oapi-codegen version: %v", err)
+	}
+}
+
+func TestTypeMapping(t *testing.T) {
+	dir := t.TempDir()
+	_, err := runGenerator(t, "--spec", "testdata/formats.yaml", "--output-dir", dir,
+		"--type-mapping", "uuid=github.com/google/uuid.UUID", "--type-mapping", "isbn=string")
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+	code, err := os.ReadFile(filepath.Join(dir, "client.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parameters and the nested properties are mapped too, the fields
+	// are aligned by gofmt
+	for _, want := range []string{
+		`"github.com/google/uuid"`,
+		`Id +\*uuid\.UUID +` + "`json:\"id,omitempty\"`",
+		`Isbn +\*string +` + "`json:\"isbn,omitempty\"`",
+		`Owner +\*uuid\.UUID +` + "`form:\"owner,omitempty\" json:\"owner,omitempty\"`",
+	} {
+		if !regexp.MustCompile(want).Match(code) {
+			t.Errorf("the generated client does not match %s", want)
+		}
+	}
+	if got := len(regexp.MustCompile(`Id +\*uuid\.UUID`).FindAll(code, -1)); got != 2 {
+		t.Errorf("the generated client has %d uuid ids, want the book and edition ones", got)
+	}
+
+	_, err = runGenerator(t, "--spec", "testdata/formats.yaml", "--output-dir", t.TempDir(), "--type-mapping", "uuid=github.com/google/uuid.")
+	if exitErr, ok := err.(*exec.ExitError); !ok || !strings.Contains(string(exitErr.Stderr), `invalid type mapping "uuid=github.com/google/uuid.", expected the type after its import path`) {
+		t.Errorf("generating with an invalid type mapping failed with %v", err)
+	}
+}
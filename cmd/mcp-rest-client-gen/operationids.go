@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/renato0307/go-mcp-rest/internal/specload"
+)
+
+// renameSpecOperations returns the spec content, as JSON, with IDs given to
+// the operations without one when synthesize is set and the duplicate
+// operations renamed when rename is set, and the changes made. The content is
+// returned unchanged when there is nothing to change.
+func renameSpecOperations(specContent []byte, source specSource, synthesize, rename bool) ([]byte, []string, error) {
+	doc, err := source.load(specContent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var renames []string
+	if synthesize {
+		for _, id := range specload.SynthesizeIDs(doc) {
+			renames = append(renames, "Synthesized operation ID "+id)
+		}
+	}
+	if rename {
+		for _, renamed := range specload.RenameDuplicates(doc) {
+			renames = append(renames, "Renamed duplicate operation "+renamed)
+		}
+	}
+	if len(renames) == 0 {
+		return specContent, nil, nil
+	}
+	renamed, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return renamed, renames, nil
+}
+
+// checkSpecOperations returns an error listing the operations of the spec
+// content sharing an operationId, the specs the loader cannot read are left
+// to oapi-codegen
+func checkSpecOperations(specContent []byte, source specSource) error {
+	doc, err := source.load(specContent)
+	if err != nil {
+		return nil
+	}
+	return specload.CheckDuplicates(doc)
+}
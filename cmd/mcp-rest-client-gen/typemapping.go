@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// goType is the Go type given to the schemas of a format, with the import
+// path of its package, empty for the predeclared types
+type goType struct {
+	name       string
+	importPath string
+}
+
+// parseTypeMappings parses the type mappings given as format=goType, the
+// type qualified by its import path such as github.com/google/uuid.UUID
+func parseTypeMappings(values []string) (map[string]goType, error) {
+	mappings := make(map[string]goType, len(values))
+	for _, value := range values {
+		format, typ, ok := strings.Cut(value, "=")
+		format, typ = strings.TrimSpace(format), strings.TrimSpace(typ)
+		if !ok || format == "" || typ == "" {
+			return nil, fmt.Errorf("invalid type mapping %q, expected format=goType", value)
+		}
+
+		dot := strings.LastIndex(typ, ".")
+		if dot < 0 {
+			mappings[format] = goType{name: typ}
+			continue
+		}
+		importPath, name := typ[:dot], typ[dot+1:]
+		if name == "" || strings.HasSuffix(importPath, "/") {
+			return nil, fmt.Errorf("invalid type mapping %q, expected the type after its import path such as github.com/google/uuid.UUID", value)
+		}
+		mappings[format] = goType{name: path.Base(importPath) + "." + name, importPath: importPath}
+	}
+	return mappings, nil
+}
+
+// applyTypeMappings sets the x-go-type and x-go-type-import extensions read
+// by oapi-codegen on the schemas of the spec with a mapped format, returning
+// the number of schemas mapped
+func applyTypeMappings(doc *openapi3.T, mappings map[string]goType) int {
+	mapped := 0
+	seen := make(map[*openapi3.Schema]bool)
+
+	var walk func(ref *openapi3.SchemaRef)
+	walk = func(ref *openapi3.SchemaRef) {
+		if ref == nil || ref.Value == nil || seen[ref.Value] {
+			return
+		}
+		schema := ref.Value
+		seen[schema] = true
+
+		if typ, ok := mappings[schema.Format]; ok && schema.Format != "" {
+			if schema.Extensions == nil {
+				schema.Extensions = make(map[string]any)
+			}
+			schema.Extensions["x-go-type"] = typ.name
+			if typ.importPath != "" {
+				schema.Extensions["x-go-type-import"] = map[string]any{"path": typ.importPath}
+			}
+			mapped++
+		}
+
+		walk(schema.Items)
+		walk(schema.Not)
+		walk(schema.AdditionalProperties.Schema)
+		for _, composed := range [][]*openapi3.SchemaRef{schema.AllOf, schema.OneOf, schema.AnyOf} {
+			for _, child := range composed {
+				walk(child)
+			}
+		}
+		for _, property := range schema.Properties {
+			walk(property)
+		}
+	}
+	walkParameters := func(parameters openapi3.Parameters) {
+		for _, parameter := range parameters {
+			if parameter != nil && parameter.Value != nil {
+				walk(parameter.Value.Schema)
+			}
+		}
+	}
+	walkContent := func(content openapi3.Content) {
+		for _, mediaType := range content {
+			if mediaType != nil {
+				walk(mediaType.Schema)
+			}
+		}
+	}
+
+	if doc.Components != nil {
+		for _, schema := range doc.Components.Schemas {
+			walk(schema)
+		}
+		for _, parameter := range doc.Components.Parameters {
+			walkParameters(openapi3.Parameters{parameter})
+		}
+		for _, body := range doc.Components.RequestBodies {
+			if body != nil && body.Value != nil {
+				walkContent(body.Value.Content)
+			}
+		}
+		for _, response := range doc.Components.Responses {
+			if response != nil && response.Value != nil {
+				walkContent(response.Value.Content)
+			}
+		}
+	}
+	for _, pathItem := range doc.Paths.Map() {
+		walkParameters(pathItem.Parameters)
+		for _, operation := range pathItem.Operations() {
+			walkParameters(operation.Parameters)
+			if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+				walkContent(operation.RequestBody.Value.Content)
+			}
+			if operation.Responses == nil {
+				continue
+			}
+			for _, response := range operation.Responses.Map() {
+				if response != nil && response.Value != nil {
+					walkContent(response.Value.Content)
+				}
+			}
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// toolArguments returns the tool arguments added to the operation besides the
+// ones of the client, handled by the generated server itself
+func toolArguments(op OperationInfo) []ConfigField {
+	var fields []ConfigField
+
+	if CLI.AllowFieldProjection {
+		if op.hasParameter("fields") {
+			warnf("parameter fields of %s is shadowed by the field projection argument", op.ID)
+		}
+		fields = append(fields, ConfigField{
+			Name: "Fields", Type: jen.Index().String(),
+			Tags: map[string]string{
+				"json":                   "fields,omitempty",
+				"jsonschema_description": "Optional list of response fields to return, nested fields are separated by dots (e.g. author.name), all fields are returned when empty",
+			},
+		})
+	}
+
+	if CLI.AllowResponseFormat {
+		fields = append(fields, formatArgument(op))
+	}
+
+	if CLI.AllowCustomHeaders {
+		if op.hasParameter("headers") {
+			warnf("parameter headers of %s is shadowed by the custom headers argument", op.ID)
+		}
+		fields = append(fields, ConfigField{
+			Name: "Headers", Type: jen.Map(jen.String()).String(),
+			Tags: map[string]string{
+				"json":                   "headers,omitempty",
+				"jsonschema_description": "Optional extra headers set on the API requests of the call, by header name",
+			},
+		})
+	}
+
+	if op.HasPageToken {
+		for _, name := range []string{"offset", "limit"} {
+			if op.hasParameter(name) {
+				warnf("parameter %s of %s is shadowed by the page token adapter argument", name, op.ID)
+			}
+		}
+		fields = append(fields,
+			ConfigField{
+				Name: "Offset", Type: jen.Int(),
+				Tags: map[string]string{
+					"json":                   "offset,omitempty",
+					"jsonschema_description": "Optional number of items to skip, the pages are fetched and discarded as needed",
+				},
+			},
+			ConfigField{
+				Name: "Limit", Type: jen.Int(),
+				Tags: map[string]string{
+					"json":                   "limit,omitempty",
+					"jsonschema_description": "Optional maximum number of items to return, fetching further pages as needed",
+				},
+			},
+		)
+	}
+
+	fields = append(fields, cursorArgument(op)...)
+
+	if params := filterParameters(op); len(params) > 0 {
+		fields = append(fields, filterQueryArgument(op, params))
+	}
+
+	if CLI.PreviewMutations && op.IsMutating() {
+		if op.hasParameter("dryRun") {
+			warnf("parameter dryRun of %s is shadowed by the dry-run argument", op.ID)
+		}
+		fields = append(fields, ConfigField{
+			Name: "DryRun", Type: jen.Bool(),
+			Tags: map[string]string{
+				"json":                   "dryRun,omitempty",
+				"jsonschema_description": "When true the request is not sent, the method, URL and body that would be sent are returned instead",
+			},
+		})
+	}
+
+	if CLI.MaxTimeout > 0 {
+		if op.hasParameter("timeoutSeconds") {
+			warnf("parameter timeoutSeconds of %s is shadowed by the timeout argument", op.ID)
+		}
+		fields = append(fields, ConfigField{
+			Name: "TimeoutSeconds", Type: jen.Int(),
+			Tags: map[string]string{
+				"json":                   "timeoutSeconds,omitempty",
+				"jsonschema_description": fmt.Sprintf("Optional timeout of the call in seconds, overriding the default one, at most %d", int(CLI.MaxTimeout.Seconds())),
+			},
+		})
+	}
+
+	return fields
+}
+
+// hasParameter reports whether the operation declares a parameter with the name
+func (op OperationInfo) hasParameter(name string) bool {
+	for _, param := range op.Parameters {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// argumentsTypeName returns the name of the type holding the tool arguments
+// of an operation with extra arguments
+func argumentsTypeName(op OperationInfo) string {
+	return op.ID + "Arguments"
+}
+
+// argumentsField returns the name of the field holding the client arguments
+// in the tool arguments type, object bodies and parameters are embedded so
+// their properties stay at the top level of the tool arguments
+func argumentsField(op OperationInfo) string {
+	if op.HasRequestBody && !op.BodyIsObject {
+		return "Body"
+	}
+	return op.ParameterType
+}
+
+// needsArgumentsType reports whether the operation tool arguments differ from
+// the client arguments, optional bodies are held by pointer to detect absence
+func needsArgumentsType(op OperationInfo, extra []ConfigField) bool {
+	return len(extra) > 0 || (op.HasRequestBody && !op.BodyRequired) || len(booleanQueryParameters(op)) > 0 || len(op.BodyVariants) > 0 || op.BodyTemplate != "" || op.FreeFormBody
+}
+
+// booleanQueryParameters returns the names of the boolean query parameters of
+// the operation accepting loose values, none when coercion is disabled
+func booleanQueryParameters(op OperationInfo) []string {
+	// Operations with a body take the body as arguments, not the parameters
+	if !CLI.CoerceBooleanParams || op.HasRequestBody {
+		return nil
+	}
+
+	var names []string
+	for _, param := range op.Parameters {
+		if param.In == openapi3.ParameterInQuery && param.IsBoolean && param.ContentType == "" {
+			names = append(names, param.Name)
+		}
+	}
+	return names
+}
+
+// addArgumentsUnmarshal adds the method decoding the tool arguments of the
+// operation after translating its filter query into the filter parameters and
+// coercing the loose values of its boolean parameters
+func addArgumentsUnmarshal(f *jen.File, op OperationInfo, names []string, filters []ParameterInfo, rules map[string]map[string]string) {
+	var body []jen.Code
+	comment := "UnmarshalJSON decodes the arguments"
+	if len(filters) > 0 {
+		comment += ", translating the filter query into the parameters"
+		body = append(body,
+			jen.Var().Err().Error(),
+			filterQueryCode(filters, rules),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+		)
+	}
+	if len(names) > 0 {
+		nameCodes := make([]jen.Code, 0, len(names))
+		for _, name := range names {
+			nameCodes = append(nameCodes, jen.Lit(name))
+		}
+		comment += ", accepting loose values for the boolean parameters"
+		assign := ":="
+		if len(filters) > 0 {
+			assign = "="
+		}
+		body = append(body,
+			jen.List(jen.Id("data"), jen.Err()).Op(assign).Id("coerceBooleans").Call(append([]jen.Code{jen.Id("data")}, nameCodes...)...),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+		)
+	}
+
+	name := argumentsTypeName(op)
+	f.Comment(comment)
+	f.Func().Params(jen.Id("a").Op("*").Id(name)).Id("UnmarshalJSON").Params(
+		jen.Id("data").Index().Byte(),
+	).Error().Block(append(body,
+		jen.Type().Id("plain").Id(name),
+		jen.Return(jen.Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Parens(jen.Op("*").Id("plain")).Call(jen.Id("a")))),
+	)...)
+}
+
+// addCoerceBooleans adds the function rewriting loose boolean values of JSON
+// arguments into booleans
+func addCoerceBooleans(f *jen.File) {
+	f.Comment("coerceBooleans rewrites the string and number values of the named arguments such as")
+	f.Comment("yes, 1 or off into booleans, failing on the values with no clear meaning")
+	f.Func().Id("coerceBooleans").Params(
+		jen.Id("data").Index().Byte(),
+		jen.Id("names").Op("...").String(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		// Numbers are kept as written so the other arguments are not rounded
+		jen.Var().Id("arguments").Map(jen.String()).Any(),
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("data"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("arguments")), jen.Err().Op("!=").Nil()).Block(
+			// Left to the regular decoding to report
+			jen.Return(jen.Id("data"), jen.Nil()),
+		),
+
+		jen.For(jen.List(jen.Id("_"), jen.Id("name")).Op(":=").Range().Id("names")).Block(
+			jen.Var().Id("text").String(),
+			jen.Switch(jen.Id("value").Op(":=").Id("arguments").Index(jen.Id("name")).Assert(jen.Type())).Block(
+				jen.Case(jen.String()).Block(
+					jen.Id("text").Op("=").Qual("strings", "ToLower").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("value"))),
+				),
+				jen.Case(jen.Qual("encoding/json", "Number")).Block(
+					jen.List(jen.Id("number"), jen.Id("_")).Op(":=").Id("value").Dot("Float64").Call(),
+					jen.Id("text").Op("=").Qual("strconv", "FormatFloat").Call(jen.Id("number"), jen.LitByte('g'), jen.Lit(-1), jen.Lit(64)),
+				),
+				jen.Default().Block(
+					jen.Continue(),
+				),
+			),
+			jen.Switch(jen.Id("text")).Block(
+				jen.Case(jen.Lit("true"), jen.Lit("yes"), jen.Lit("y"), jen.Lit("on"), jen.Lit("1")).Block(
+					jen.Id("arguments").Index(jen.Id("name")).Op("=").True(),
+				),
+				jen.Case(jen.Lit("false"), jen.Lit("no"), jen.Lit("n"), jen.Lit("off"), jen.Lit("0")).Block(
+					jen.Id("arguments").Index(jen.Id("name")).Op("=").False(),
+				),
+				jen.Default().Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("argument %s must be a boolean, got %q"), jen.Id("name"), jen.Id("text"))),
+				),
+			),
+		),
+
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id("arguments"))),
+	)
+}
+
+// addArgumentsType adds the type holding the client arguments of the
+// operation together with the extra tool arguments
+func addArgumentsType(f *jen.File, op OperationInfo, extra []ConfigField) {
+	clientType := jen.Qual(CLI.ClientImport, op.ParameterType)
+	if op.HasRequestBody && !op.BodyRequired {
+		clientType = jen.Op("*").Add(clientType)
+	}
+
+	fields := []jen.Code{clientType}
+	switch {
+	case op.BodyTemplate != "":
+		fields = configFieldsCode(op.BodyTemplateFields)
+	case len(op.BodyVariants) > 0:
+		fields = variantFields(op)
+	case op.FreeFormBody:
+		fields = []jen.Code{freeFormBodyField(op)}
+	case argumentsField(op) == "Body":
+		tag := "body"
+		if !op.BodyRequired {
+			tag = "body,omitempty"
+		}
+		fields = []jen.Code{jen.Id("Body").Add(clientType).Tag(map[string]string{"json": tag})}
+	}
+	fields = append(fields, configFieldsCode(extra)...)
+
+	name := argumentsTypeName(op)
+	f.Comment(name + " are the arguments of the " + op.ID + " tool")
+	f.Type().Id(name).Struct(fields...)
+}
+
+// optionalBodyCode returns the statements encoding the optional body of the
+// operation into the requestBody bytes, left empty when the body is absent.
+// The required inline unions are encoded the same way, always.
+func optionalBodyCode(op OperationInfo) []jen.Code {
+	field := jen.Id("arguments").Dot(argumentsField(op))
+	present := field.Clone().Op("!=").Nil()
+	var build []jen.Code
+
+	// Discriminated bodies are absent when no variant is selected
+	if len(op.BodyVariants) > 0 {
+		field = jen.Id("unionBody")
+		present = jen.Id("arguments").Dot("Discriminator").Op("!=").Lit("")
+		build = unionBodyCode(op)
+	}
+	build = append(build,
+		jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(field.Clone()),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error encoding "+op.ID+" body: %v"), jen.Err())),
+		),
+		jen.Id("requestBody").Op("=").Id("data"),
+	)
+	if op.BodyRequired {
+		return append([]jen.Code{jen.Var().Id("requestBody").Index().Byte()}, build...)
+	}
+	return []jen.Code{
+		jen.Var().Id("requestBody").Index().Byte(),
+		jen.If(present).Block(build...),
+	}
+}
+
+// requestBodyReader returns the reader over the requestBody bytes passed to
+// the client, made anew for each attempt so a retried call sends the whole
+// body again
+func requestBodyReader() jen.Code {
+	return jen.Qual("bytes", "NewReader").Call(jen.Id("requestBody"))
+}
+
+// isObjectBody reports whether the JSON request body of the operation is an
+// object, so it can be embedded in the tool arguments
+func isObjectBody(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+
+	mediaType := operation.RequestBody.Value.Content.Get("application/json")
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return false
+	}
+
+	schema := mediaType.Schema.Value
+	return schema.Type.Is(openapi3.TypeObject) || len(schema.Properties) > 0 || len(schema.AllOf) > 0
+}
+
+// addProjectFields adds the functions projecting a JSON response down to the
+// requested fields
+func addProjectFields(f *jen.File) {
+	f.Comment("projectFields keeps only the given dot-separated field paths of the JSON body,")
+	f.Comment("arrays are projected element by element")
+	f.Func().Id("projectFields").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("fields").Index().String(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		// Numbers are kept as written so large integers are not rounded
+		jen.Var().Id("value").Any(),
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("body"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("value")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("response is not JSON: %w"), jen.Err())),
+		),
+
+		jen.Id("paths").Op(":=").Make(jen.Index().Index().String(), jen.Lit(0), jen.Len(jen.Id("fields"))),
+		jen.For(jen.List(jen.Id("_"), jen.Id("field")).Op(":=").Range().Id("fields")).Block(
+			jen.Id("paths").Op("=").Append(jen.Id("paths"), jen.Qual("strings", "Split").Call(jen.Id("field"), jen.Lit("."))),
+		),
+
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id("projectValue").Call(jen.Id("value"), jen.Id("paths")))),
+	)
+
+	f.Comment("projectValue keeps only the given field paths of the JSON value")
+	f.Func().Id("projectValue").Params(
+		jen.Id("value").Any(),
+		jen.Id("paths").Index().Index().String(),
+	).Any().Block(
+		jen.Switch(jen.Id("v").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Index().Any()).Block(
+				jen.Id("projected").Op(":=").Make(jen.Index().Any(), jen.Len(jen.Id("v"))),
+				jen.For(jen.List(jen.Id("i"), jen.Id("item")).Op(":=").Range().Id("v")).Block(
+					jen.Id("projected").Index(jen.Id("i")).Op("=").Id("projectValue").Call(jen.Id("item"), jen.Id("paths")),
+				),
+				jen.Return(jen.Id("projected")),
+			),
+			jen.Case(jen.Map(jen.String()).Any()).Block(
+				// Group the remaining path segments by their first field
+				jen.Id("nested").Op(":=").Make(jen.Map(jen.String()).Index().Index().String()),
+				jen.Id("whole").Op(":=").Make(jen.Map(jen.String()).Bool()),
+				jen.For(jen.List(jen.Id("_"), jen.Id("path")).Op(":=").Range().Id("paths")).Block(
+					jen.If(jen.Len(jen.Id("path")).Op("==").Lit(1)).Block(
+						jen.Id("whole").Index(jen.Id("path").Index(jen.Lit(0))).Op("=").True(),
+					).Else().Block(
+						jen.Id("nested").Index(jen.Id("path").Index(jen.Lit(0))).Op("=").Append(
+							jen.Id("nested").Index(jen.Id("path").Index(jen.Lit(0))),
+							jen.Id("path").Index(jen.Lit(1).Op(":")),
+						),
+					),
+				),
+
+				jen.Id("projected").Op(":=").Make(jen.Map(jen.String()).Any()),
+				jen.For(jen.List(jen.Id("key"), jen.Id("child")).Op(":=").Range().Id("v")).Block(
+					jen.If(jen.Id("whole").Index(jen.Id("key"))).Block(
+						jen.Id("projected").Index(jen.Id("key")).Op("=").Id("child"),
+					).Else().If(jen.List(jen.Id("rest"), jen.Id("ok")).Op(":=").Id("nested").Index(jen.Id("key")), jen.Id("ok")).Block(
+						jen.Id("projected").Index(jen.Id("key")).Op("=").Id("projectValue").Call(jen.Id("child"), jen.Id("rest")),
+					),
+				),
+				jen.Return(jen.Id("projected")),
+			),
+		),
+		jen.Return(jen.Id("value")),
+	)
+}
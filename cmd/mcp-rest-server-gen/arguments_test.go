@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFieldProjection(t *testing.T) {
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json",
+		`[{"Id":9007199254740993,"Name":"Dune","Author":"Frank Herbert","Publisher":{"Name":"Chilton","Country":"US"}},`+
+			`{"Id":2,"Name":"Emma","Author":"Jane Austen"}]`))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--allow-field-projection")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The large identifiers are kept as written
+	result := session.callTool(t, "ListBooks", map[string]any{"fields": []string{"Id", "Publisher.Name"}})
+	if want := `[{"Id":9007199254740993,"Publisher":{"Name":"Chilton"}},{"Id":2}]`; result.IsError || result.text() != want {
+		t.Errorf("the projected response is %q, want %q", result.text(), want)
+	}
+
+	// Without fields the response is returned whole
+	result = session.callTool(t, "ListBooks", map[string]any{})
+	if want := `"Author":"Jane Austen"`; result.IsError || !strings.Contains(result.text(), want) {
+		t.Errorf("the response without projection is %q", result.text())
+	}
+}
+
+func TestOptionalBody(t *testing.T) {
+	bodies := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- r.URL.Path + " " + string(body)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/optional.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		tool      string
+		arguments map[string]any
+		want      string
+	}{
+		{"AddBook", map[string]any{"Name": "Dune"}, `/books {"Name":"Dune"}`},
+		{"AddBook", map[string]any{}, `/books `},
+		{"SetTags", map[string]any{"body": []string{"classic"}}, `/tags ["classic"]`},
+		{"SetTags", map[string]any{}, `/tags `},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, test.tool, test.arguments); result.IsError {
+			t.Fatalf("%s with %v failed: %s", test.tool, test.arguments, result.text())
+		}
+		if got := <-bodies; got != test.want {
+			t.Errorf("%s with %v sent %q, want %q", test.tool, test.arguments, got, test.want)
+		}
+	}
+}
+
+func TestCoerceBooleanParams(t *testing.T) {
+	queries := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.RawQuery
+		respond(http.StatusOK, "application/json", `{}`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/bools.yaml", "--auth-type", "none", "--coerce-boolean-params")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		archived any
+		want     string
+	}{
+		{"yes", "archived=true"},
+		{"1", "archived=true"},
+		{1, "archived=true"},
+		{"false", "archived=false"},
+		{"OFF", "archived=false"},
+		{true, "archived=true"},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, "ListBooks", map[string]any{"archived": test.archived}); result.IsError {
+			t.Errorf("archived %#v failed: %s", test.archived, result.text())
+			continue
+		}
+		if got := <-queries; got != test.want {
+			t.Errorf("archived %#v sent %q, want %q", test.archived, got, test.want)
+		}
+	}
+
+	result := session.callTool(t, "ListBooks", map[string]any{"archived": "maybe"})
+	if !result.IsError || !strings.Contains(result.text(), `argument archived must be a boolean, got "maybe"`) {
+		t.Errorf("the invalid value returned %+v", result)
+	}
+}
+
+func TestCustomHeaders(t *testing.T) {
+	code := generate(t, booksSpec)
+	assertNotContains(t, code, "customHeaders")
+
+	requests := make(chan http.Header, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Header
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--allow-custom-headers")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	result := session.callTool(t, "ListBooks", map[string]any{
+		"headers": map[string]string{"X-Tenant": "acme", "X-Trace-Id": "42"},
+	})
+	if result.IsError {
+		t.Fatalf("ListBooks failed with %s", result.text())
+	}
+	header := <-requests
+	if header.Get("X-Tenant") != "acme" || header.Get("X-Trace-Id") != "42" {
+		t.Errorf("the request headers are %v", header)
+	}
+	// The headers argument does not replace the authentication
+	if header.Get("Authorization") == "" {
+		t.Errorf("the request with custom headers has no authorization")
+	}
+
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks without headers failed with %s", result.text())
+	}
+	if header := <-requests; header.Get("X-Tenant") != "" {
+		t.Errorf("the headers of the previous call were sent again: %v", header)
+	}
+}
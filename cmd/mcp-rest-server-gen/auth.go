@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/renato0307/go-mcp-rest/internal/specload"
+)
+
+// securityProvider is the import path of the oapi-codegen security providers
+const securityProvider = "github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider"
+
+// authScheme describes the credentials read by the generated server and the
+// security provider built from them
+type authScheme struct {
+	// Type is the auth type, none when the server sends no credentials
+	Type string
+	// Var is the name of the variable holding the security provider
+	Var string
+	// Provider is the name of the security provider type
+	Provider string
+	// Params are the arguments passed to the provider constructor before the
+	// credentials
+	Params []jen.Code
+	// Credentials are the configuration fields holding the credentials, passed
+	// in order to the provider constructor
+	Credentials []ConfigField
+	// Settings are the configuration fields of the auth that are not
+	// credentials, such as the token endpoint
+	Settings []ConfigField
+	// Generated reports whether the provider type is emitted in the generated
+	// server instead of being an oapi-codegen security provider
+	Generated bool
+	// Parts are the auth schemes combined when the spec requires several
+	// security schemes together, Var then holds their combined request editor
+	Parts []authScheme
+}
+
+// newAuthScheme returns the auth scheme of the given auth type
+func newAuthScheme(authType string) (authScheme, error) {
+	switch authType {
+	case "basic":
+		return authScheme{
+			Type:     authType,
+			Var:      "basicAuth",
+			Provider: "SecurityProviderBasicAuth",
+			Credentials: []ConfigField{
+				{Name: "Username", Type: jen.String(), Tags: map[string]string{"help": "API username", "env": CLI.UsernameEnv}},
+				{Name: "Password", Type: jen.String(), Tags: map[string]string{"help": "API password", "env": CLI.PasswordEnv}},
+			},
+		}, nil
+	case "bearer":
+		return authScheme{
+			Type:     authType,
+			Var:      "bearerAuth",
+			Provider: "SecurityProviderBearerToken",
+			Credentials: []ConfigField{
+				{Name: "Token", Type: jen.String(), Tags: map[string]string{"help": "API bearer token", "env": CLI.TokenEnv}},
+			},
+		}, nil
+	case "apikey":
+		return newAPIKeyAuthScheme("header", CLI.APIKeyName), nil
+	case "oauth2":
+		if CLI.TokenURL == "" {
+			return authScheme{}, fmt.Errorf("the token endpoint of the oauth2 auth is unknown, set it with --token-url")
+		}
+		return newOAuth2AuthScheme(CLI.TokenURL), nil
+	case "none":
+		return authScheme{Type: authType}, nil
+	}
+	return authScheme{}, fmt.Errorf("unknown auth type %q", authType)
+}
+
+// newAPIKeyAuthScheme returns the auth scheme sending an API key in the
+// given location, header, query or cookie, under the given name
+func newAPIKeyAuthScheme(in, name string) authScheme {
+	return authScheme{
+		Type:     "apikey",
+		Var:      "apiKeyAuth",
+		Provider: "SecurityProviderApiKey",
+		Params:   []jen.Code{jen.Lit(in), jen.Lit(name)},
+		Credentials: []ConfigField{
+			{Name: "ApiKey", Type: jen.String(), Tags: map[string]string{"help": "API key", "env": CLI.APIKeyEnv}},
+		},
+	}
+}
+
+// newOAuth2AuthScheme returns the auth scheme sending the access tokens of
+// the client credentials flow of the given token endpoint
+func newOAuth2AuthScheme(tokenURL string) authScheme {
+	return authScheme{
+		Type:      "oauth2",
+		Var:       "tokenAuth",
+		Provider:  "TokenSource",
+		Generated: true,
+		Params:    []jen.Code{jen.Id("cli").Dot("TokenUrl")},
+		Credentials: []ConfigField{
+			{Name: "ClientId", Type: jen.String(), Tags: map[string]string{"help": "OAuth2 client ID", "env": CLI.ClientIDEnv}},
+			{Name: "ClientSecret", Type: jen.String(), Tags: map[string]string{"help": "OAuth2 client secret", "env": CLI.ClientSecretEnv}},
+		},
+		Settings: []ConfigField{
+			{Name: "TokenUrl", Type: jen.String(), Tags: map[string]string{"help": "OAuth2 token endpoint", "default": tokenURL, "env": CLI.TokenURLEnv}},
+		},
+	}
+}
+
+// newCombinedAuthScheme returns the auth sending the credentials of all the
+// auth schemes of the named security schemes, required together by the spec.
+// The fields and variables they share are suffixed with the scheme name.
+func newCombinedAuthScheme(names []string, parts []authScheme) authScheme {
+	counts := make(map[string]int)
+	for _, part := range parts {
+		counts[part.Var]++
+		for _, field := range append(append([]ConfigField{}, part.Credentials...), part.Settings...) {
+			counts[field.Name]++
+		}
+	}
+	rename := func(fields []ConfigField, name string) []ConfigField {
+		renamed := make([]ConfigField, 0, len(fields))
+		for _, field := range fields {
+			if counts[field.Name] > 1 {
+				tags := make(map[string]string, len(field.Tags))
+				for key, value := range field.Tags {
+					tags[key] = value
+				}
+				tags["help"] += " of the " + name + " security scheme"
+				if env := tags["env"]; env != "" {
+					tags["env"] = env + "_" + envSuffix(name)
+				}
+				field = ConfigField{Name: field.Name + specload.CamelCase(name), Type: field.Type, Tags: tags}
+			}
+			renamed = append(renamed, field)
+		}
+		return renamed
+	}
+
+	combined := authScheme{Type: "combined", Var: "combinedAuth"}
+	for i, part := range parts {
+		if counts[part.Var] > 1 {
+			part.Var += specload.CamelCase(names[i])
+		}
+		part.Credentials = rename(part.Credentials, names[i])
+		part.Settings = rename(part.Settings, names[i])
+		if part.Type == "oauth2" {
+			part.Params = []jen.Code{jen.Id("cli").Dot(part.Settings[0].Name)}
+		}
+		combined.Credentials = append(combined.Credentials, part.Credentials...)
+		combined.Settings = append(combined.Settings, part.Settings...)
+		combined.Generated = combined.Generated || part.Generated
+		combined.Parts = append(combined.Parts, part)
+	}
+	return combined
+}
+
+// envSuffix returns the name in upper snake case, for the environment
+// variables of the credentials of a security scheme
+func envSuffix(name string) string {
+	var suffix strings.Builder
+	previous := rune(0)
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			r = '_'
+		case unicode.IsUpper(r) && (unicode.IsLower(previous) || unicode.IsDigit(previous)):
+			suffix.WriteByte('_')
+		}
+		suffix.WriteRune(unicode.ToUpper(r))
+		previous = r
+	}
+	return suffix.String()
+}
+
+// enabled reports whether the server sends credentials
+func (a authScheme) enabled() bool {
+	return a.Type != "none"
+}
+
+// intercept returns the request editor applying the auth
+func (a authScheme) intercept() *jen.Statement {
+	if len(a.Parts) > 0 {
+		return jen.Id(a.Var)
+	}
+	return jen.Id(a.Var).Dot("Intercept")
+}
+
+// tokenSources returns the variables holding the generated token sources of
+// the auth, with the ones of the combined auth schemes
+func (a authScheme) tokenSources() []jen.Code {
+	var sources []jen.Code
+	if a.Generated && len(a.Parts) == 0 {
+		sources = append(sources, jen.Id(a.Var))
+	}
+	for _, part := range a.Parts {
+		sources = append(sources, part.tokenSources()...)
+	}
+	return sources
+}
+
+// setupCode returns the statements creating the security provider from the
+// credentials of the command line, or the providers of the combined auth
+// schemes and their combined request editor
+func (a authScheme) setupCode() []jen.Code {
+	if len(a.Parts) == 0 {
+		return []jen.Code{
+			jen.List(jen.Id(a.Var), jen.Err()).Op(":=").Add(a.providerCall(
+				a.credentialArgs(func(field ConfigField) jen.Code { return jen.Id("cli").Dot(field.Name) }),
+			)),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Qual("log", "Fatal").Call(jen.Err()),
+			),
+		}
+	}
+
+	var code []jen.Code
+	editors := make([]jen.Code, 0, len(a.Parts))
+	for _, part := range a.Parts {
+		code = append(code, part.setupCode()...)
+		editors = append(editors, part.intercept())
+	}
+	return append(code, jen.Id(a.Var).Op(":=").Id("combineAuth").Call(editors...))
+}
+
+// addCombineAuth adds the function applying the request editors of several
+// security schemes to each request
+func addCombineAuth(f *jen.File) {
+	f.Comment("combineAuth returns a request editor applying all the editors, the security schemes the API")
+	f.Comment("requires together")
+	f.Func().Id("combineAuth").Params(
+		jen.Id("editors").Op("...").Qual(CLI.ClientImport, "RequestEditorFn"),
+	).Qual(CLI.ClientImport, "RequestEditorFn").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).Error().Block(
+			jen.For(jen.List(jen.Id("_"), jen.Id("editor")).Op(":=").Range().Id("editors")).Block(
+				jen.If(jen.Err().Op(":=").Id("editor").Call(jen.Id("ctx"), jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Err()),
+				),
+			),
+			jen.Return(jen.Nil()),
+		)),
+	)
+}
+
+// credentialArgs returns the credential expressions passed to the provider
+// constructor, read from the given value
+func (a authScheme) credentialArgs(from func(field ConfigField) jen.Code) []jen.Code {
+	args := make([]jen.Code, 0, len(a.Credentials))
+	for _, field := range a.Credentials {
+		args = append(args, from(field))
+	}
+	return args
+}
+
+// providerType returns the security provider type
+func (a authScheme) providerType() *jen.Statement {
+	if a.Generated {
+		return jen.Id(strings.ToLower(a.Provider[:1]) + a.Provider[1:])
+	}
+	return jen.Qual(securityProvider, a.Provider)
+}
+
+// providerCall returns the call of the security provider constructor
+func (a authScheme) providerCall(credentials []jen.Code) *jen.Statement {
+	args := append(append([]jen.Code{}, a.Params...), credentials...)
+	if a.Generated {
+		return jen.Id("new" + a.Provider).Call(args...)
+	}
+	return jen.Qual(securityProvider, "New"+a.Provider).Call(args...)
+}
+
+// credentialParam returns the lower camel case name of a credential, used
+// for parameters and JSON arguments
+func credentialParam(field ConfigField) string {
+	return strings.ToLower(field.Name[:1]) + field.Name[1:]
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestMissingCredentials(t *testing.T) {
+	code := generate(t, booksSpec, "--auth-type", "none")
+	assertNotContains(t, code, "missingCredentials")
+
+	binary := buildServer(t, booksSpec)
+	cmd := exec.Command(binary)
+	cmd.Env = append(os.Environ(), "API_USERNAME=", "API_PASSWORD=secret")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("the server started without the API username")
+	}
+	if !strings.Contains(string(output), "missing API credentials, set API_USERNAME") {
+		t.Errorf("the server failed with %q", output)
+	}
+}
+
+func TestEffectiveSecurity(t *testing.T) {
+	authorizations := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Method + " " + r.Header.Get("Authorization")
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	// The public operation and the one inheriting the global security share
+	// their path
+	binary := buildServer(t, "testdata/mixed-security.yaml")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	if got := <-authorizations; got != "GET " {
+		t.Errorf("the public operation was called as %q, want no authorization", got)
+	}
+	if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); result.IsError {
+		t.Fatalf("AddBook failed: %s", result.text())
+	}
+	if got, want := <-authorizations, "POST Basic dXNlcjpzZWNyZXQ="; got != want {
+		t.Errorf("the secured operation was called as %q, want %q", got, want)
+	}
+}
+
+// securedSpec returns a spec whose operation requires the security scheme
+func securedSpec(scheme string) string {
+	return `openapi: 3.0.1
+info: {title: Secured, version: "1.0"}
+security:
+  - main: []
+paths:
+  /books:
+    get:
+      operationId: ListBooks
+      parameters:
+        - {name: q, in: query, schema: {type: string}}
+      responses:
+        "200": {description: ok}
+components:
+  securitySchemes:
+    main: ` + scheme + `
+`
+}
+
+func TestAutoAuthType(t *testing.T) {
+	tests := []struct {
+		name, scheme string
+		want         []string
+	}{
+		{"basic", `{type: http, scheme: basic}`, []string{
+			`securityprovider.NewSecurityProviderBasicAuth(cli.Username, cli.Password)`,
+		}},
+		{"bearer", `{type: http, scheme: bearer}`, []string{
+			`securityprovider.NewSecurityProviderBearerToken(cli.Token)`,
+			`env:"API_TOKEN"`,
+		}},
+		{"header key", `{type: apiKey, in: header, name: X-Books-Key}`, []string{
+			`securityprovider.NewSecurityProviderApiKey("header", "X-Books-Key", cli.ApiKey)`,
+		}},
+		{"query key", `{type: apiKey, in: query, name: key}`, []string{
+			`securityprovider.NewSecurityProviderApiKey("query", "key", cli.ApiKey)`,
+		}},
+		{"oauth2", `{type: oauth2, flows: {clientCredentials: {tokenUrl: "https://auth.example.com/token", scopes: {}}}}`, []string{
+			`newTokenSource(cli.TokenUrl, cli.ClientId, cli.ClientSecret)`,
+			`default:"https://auth.example.com/token"`,
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := writeSpec(t, securedSpec(test.scheme))
+			assertContains(t, generate(t, spec, "--auth-type", "auto"), test.want...)
+
+			// Without --auth-type=auto the auth stays basic
+			code := generate(t, spec)
+			assertContains(t, code, `securityprovider.NewSecurityProviderBasicAuth(cli.Username, cli.Password)`)
+			if test.name != "basic" {
+				assertNotContains(t, code, test.want...)
+			}
+		})
+	}
+
+	// The schemes that cannot be detected need an explicit auth type
+	spec := writeSpec(t, securedSpec(`{type: http, scheme: digest}`))
+	if _, err := runGenerator(t, spec, "--auth-type", "auto"); err == nil || !strings.Contains(err.Error(), "is not supported, choose the auth with --auth-type") {
+		t.Errorf("detecting a digest scheme failed with %v", err)
+	}
+}
+
+func TestDetectAuthScheme(t *testing.T) {
+	spec := writeSpec(t, `openapi: 3.0.1
+info: {title: Secured, version: "1.0"}
+security:
+  - key: []
+  - token: []
+paths:
+  /books:
+    get:
+      operationId: ListBooks
+      parameters:
+        - {name: q, in: query, schema: {type: string}}
+      responses:
+        "200": {description: ok}
+components:
+  securitySchemes:
+    key: {type: apiKey, in: header, name: X-Books-Key}
+    token: {type: http, scheme: bearer}
+`)
+
+	// The alternatives of the spec are listed to choose from
+	_, err := runGenerator(t, spec, "--auth-type", "auto")
+	if err == nil || !strings.Contains(err.Error(), "the spec accepts several security alternatives, choose one with --security-alternative or the auth with --auth-type: key, token") {
+		t.Errorf("detecting among several alternatives failed with %v", err)
+	}
+
+	code := generate(t, spec, "--auth-type", "auto", "--security-alternative", "token")
+	assertContains(t, code, `securityprovider.NewSecurityProviderBearerToken(cli.Token)`)
+	assertNotContains(t, code, `NewSecurityProviderApiKey`)
+
+	_, err = runGenerator(t, spec, "--auth-type", "auto", "--security-alternative", "cookie")
+	if err == nil || !strings.Contains(err.Error(), "security alternative cookie is not accepted by the spec, choose one of: key, token") {
+		t.Errorf("selecting an unknown alternative failed with %v", err)
+	}
+
+	// The alternative is only chosen when detecting the auth
+	generate(t, spec, "--security-alternative", "token")
+	assertWarning(t, "--security-alternative is ignored without --auth-type=auto")
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	code := generate(t, booksSpec, "--auth-type", "apikey", "--apikey-name", "X-Books-Key", "--apikey-env", "BOOKS_KEY")
+	assertContains(t, code,
+		`securityprovider.NewSecurityProviderApiKey("header", "X-Books-Key", cli.ApiKey)`,
+		`env:"BOOKS_KEY"`,
+	)
+	assertNotContains(t, code, "Username")
+
+	keys := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get("X-Books-Key") + " " + r.Header.Get("Authorization")
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--auth-type", "apikey", "--apikey-name", "X-Books-Key", "--apikey-env", "BOOKS_KEY")
+	session := startServer(t, binary, []string{"BOOKS_KEY=k3y"}, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	if got, want := <-keys, "k3y "; got != want {
+		t.Errorf("the API was called with the key and authorization %q, want %q", got, want)
+	}
+}
+
+func TestCombinedSecurity(t *testing.T) {
+	spec := "testdata/combined-security.yaml"
+	_, err := runGenerator(t, spec, "--auth-type", "auto")
+	if err == nil || !strings.Contains(err.Error(), "choose one with --security-alternative or the auth with --auth-type: key+tenant, token") {
+		t.Errorf("detecting among the combined alternatives failed with %v", err)
+	}
+
+	// The schemes required together are all satisfied
+	code := generate(t, spec, "--auth-type", "auto", "--security-alternative", "tenant+key")
+	assertContains(t, code,
+		`securityprovider.NewSecurityProviderApiKey("header", "X-Books-Key", cli.ApiKeyKey)`,
+		`securityprovider.NewSecurityProviderApiKey("header", "X-Tenant", cli.ApiKeyTenant)`,
+		`env:"API_KEY_KEY"`,
+		`env:"API_KEY_TENANT"`,
+	)
+	assertNotContains(t, code, "NewSecurityProviderBearerToken")
+
+	headers := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("X-Books-Key") + " " + r.Header.Get("X-Tenant")
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, spec, "--auth-type", "auto", "--security-alternative", "key+tenant")
+	session := startServer(t, binary, []string{"API_KEY_KEY=k3y", "API_KEY_TENANT=acme"}, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	if got, want := <-headers, "k3y acme"; got != want {
+		t.Errorf("the API was called with the key and tenant %q, want %q", got, want)
+	}
+
+	code = generate(t, spec, "--auth-type", "auto", "--security-alternative", "token")
+	assertContains(t, code, `securityprovider.NewSecurityProviderBearerToken(cli.Token)`)
+	assertNotContains(t, code, "NewSecurityProviderApiKey")
+}
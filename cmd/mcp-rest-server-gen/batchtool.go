@@ -0,0 +1,159 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// batchHandlerCode returns the statements keeping the handler of the
+// operation in a variable and adding it to the calls of the batch tool, with
+// the expression of the variable to register as the tool handler
+func batchHandlerCode(op OperationInfo, handler jen.Code) ([]jen.Code, *jen.Statement) {
+	variable := "handler" + op.ID
+	return []jen.Code{
+		jen.Id(variable).Op(":=").Add(handler),
+		jen.Id("batchCalls").Index(jen.Lit(op.ID)).Op("=").Id("batchHandler").Call(jen.Id(variable)),
+	}, jen.Id(variable)
+}
+
+// addBatchTool adds the types of the batch tool arguments and outcomes, and
+// the functions calling the operation tools from it
+func addBatchTool(f *jen.File) {
+	f.Comment("BatchCall is a call of an operation tool in a batch")
+	f.Type().Id("BatchCall").Struct(
+		jen.Id("Operation").String().Tag(map[string]string{
+			"json":                   "operation",
+			"jsonschema":             "required",
+			"jsonschema_description": "ID of the operation, the name of its tool",
+		}),
+		jen.Id("Arguments").Map(jen.String()).Any().Tag(map[string]string{
+			"json":                   "arguments,omitempty",
+			"jsonschema_description": "Arguments of the operation tool, as they would be passed to it",
+		}),
+	)
+
+	f.Comment("BatchToolArguments are the arguments of the batch tool")
+	f.Type().Id("BatchToolArguments").Struct(
+		jen.Id("Calls").Index().Id("BatchCall").Tag(map[string]string{
+			"json":                   "calls",
+			"jsonschema":             "required",
+			"jsonschema_description": "Calls of the operation tools, made one after the other",
+		}),
+	)
+
+	f.Comment("batchOutcome reports the result of a call of the batch, or its error")
+	f.Type().Id("batchOutcome").Struct(
+		jen.Id("Operation").String().Tag(map[string]string{"json": "operation"}),
+		jen.Id("OK").Bool().Tag(map[string]string{"json": "ok"}),
+		jen.Id("Result").Index().Any().Tag(map[string]string{"json": "result,omitempty"}),
+		jen.Id("Error").String().Tag(map[string]string{"json": "error,omitempty"}),
+	)
+
+	f.Comment("batchCall calls an operation tool with its JSON arguments")
+	f.Type().Id("batchCall").Func().Params(jen.Id("data").Index().Byte()).Params(toolResultType(), jen.Error())
+
+	handlerType := handlerSignature(jen.Id("T"))
+	f.Comment("batchHandler returns the batch call decoding the arguments for the tool handler")
+	f.Func().Id("batchHandler").Types(jen.Id("T").Any()).Params(
+		jen.Id("handler").Add(handlerType),
+	).Id("batchCall").Block(
+		jen.Return(jen.Func().Params(jen.Id("data").Index().Byte()).Params(toolResultType(), jen.Error()).Block(
+			jen.Var().Id("arguments").Id("T"),
+			jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("arguments")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid arguments: %w"), jen.Err())),
+			),
+			jen.Return(jen.Id("handler").Call(jen.Id("arguments"))),
+		)),
+	)
+
+	f.Comment("batchResult returns the texts of the contents of the tool result, the JSON ones decoded")
+	f.Func().Id("batchResult").Params(
+		jen.Id("result").Add(toolResultType()),
+	).Index().Any().Block(
+		jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("result")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil()),
+		),
+		jen.Var().Id("decoded").Struct(
+			jen.Id("Content").Index().Struct(
+				jen.Id("Text").String().Tag(map[string]string{"json": "text"}),
+			).Tag(map[string]string{"json": "content"}),
+		),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("decoded")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil()),
+		),
+		jen.Id("texts").Op(":=").Make(jen.Index().Any(), jen.Lit(0), jen.Len(jen.Id("decoded").Dot("Content"))),
+		jen.For(jen.List(jen.Id("_"), jen.Id("content")).Op(":=").Range().Id("decoded").Dot("Content")).Block(
+			jen.If(jen.Qual("encoding/json", "Valid").Call(jen.Index().Byte().Call(jen.Id("content").Dot("Text")))).Block(
+				jen.Id("texts").Op("=").Append(jen.Id("texts"), jen.Qual("encoding/json", "RawMessage").Call(jen.Id("content").Dot("Text"))),
+				jen.Continue(),
+			),
+			jen.Id("texts").Op("=").Append(jen.Id("texts"), jen.Id("content").Dot("Text")),
+		),
+		jen.Return(jen.Id("texts")),
+	)
+}
+
+// registerBatchTool returns the statements registering the tool calling
+// several operation tools at once, reporting the outcome of each call so the
+// failed calls do not hide the successful ones
+func registerBatchTool() []jen.Code {
+	var disabledCheck []jen.Code
+	if CLI.DynamicTools {
+		disabledCheck = []jen.Code{
+			jen.If(jen.Id("tools").Dot("isDisabled").Call(jen.Id("call").Dot("Operation"))).Block(
+				jen.Id("outcome").Dot("Error").Op("=").Lit("tool is disabled"),
+				jen.Id("outcomes").Op("=").Append(jen.Id("outcomes"), jen.Id("outcome")),
+				jen.Continue(),
+			),
+		}
+	}
+
+	return registerTool(
+		"server",
+		"batch",
+		"Calls several operation tools one after the other, returning the result or the error of each call, a failed call does not stop the others",
+		jen.Id("BatchToolArguments"),
+		jen.Func().Params(
+			jen.Id("arguments").Id("BatchToolArguments"),
+		).Params(toolResultType(), jen.Error()).Block(
+			jen.If(jen.Len(jen.Id("arguments").Dot("Calls")).Op("==").Lit(0)).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("no calls in the batch"))),
+			),
+			jen.Id("outcomes").Op(":=").Make(jen.Index().Id("batchOutcome"), jen.Lit(0), jen.Len(jen.Id("arguments").Dot("Calls"))),
+			jen.Id("failed").Op(":=").Lit(0),
+			jen.For(jen.List(jen.Id("_"), jen.Id("call")).Op(":=").Range().Id("arguments").Dot("Calls")).Block(append(append([]jen.Code{
+				jen.Id("outcome").Op(":=").Id("batchOutcome").Values(jen.Dict{jen.Id("Operation"): jen.Id("call").Dot("Operation")}),
+				jen.Id("failed").Op("++"),
+			}, disabledCheck...),
+				jen.List(jen.Id("handle"), jen.Id("ok")).Op(":=").Id("batchCalls").Index(jen.Id("call").Dot("Operation")),
+				jen.If(jen.Op("!").Id("ok")).Block(
+					jen.Id("outcome").Dot("Error").Op("=").Lit("unknown operation"),
+					jen.Id("outcomes").Op("=").Append(jen.Id("outcomes"), jen.Id("outcome")),
+					jen.Continue(),
+				),
+				jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("call").Dot("Arguments")),
+				jen.If(jen.Err().Op("==").Nil()).Block(
+					jen.Var().Id("result").Add(toolResultType()),
+					jen.If(jen.List(jen.Id("result"), jen.Err()).Op("=").Id("handle").Call(jen.Id("data")), jen.Err().Op("==").Nil()).Block(
+						jen.List(jen.Id("outcome").Dot("OK"), jen.Id("outcome").Dot("Result")).Op("=").List(jen.True(), jen.Id("batchResult").Call(jen.Id("result"))),
+						jen.Id("failed").Op("--"),
+					),
+				),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Id("outcome").Dot("Error").Op("=").Err().Dot("Error").Call(),
+				),
+				jen.Id("outcomes").Op("=").Append(jen.Id("outcomes"), jen.Id("outcome")),
+			)...),
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "MarshalIndent").Call(jen.Map(jen.String()).Any().Values(jen.Dict{
+				jen.Lit("succeeded"): jen.Len(jen.Id("outcomes")).Op("-").Id("failed"),
+				jen.Lit("failed"):    jen.Id("failed"),
+				jen.Lit("calls"):     jen.Id("outcomes"),
+			}), jen.Lit(""), jen.Lit("  ")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.Return(toolResult(textContent(jen.String().Call(jen.Id("data")))), jen.Nil()),
+		),
+		nil,
+	)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBatchTool(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			respond(http.StatusConflict, "text/plain", "already added")(w, r)
+			return
+		}
+		respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--batch-tool")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "batch", map[string]any{"calls": []map[string]any{
+		{"operation": "AddBook", "arguments": map[string]any{"Name": "Dune"}},
+		{"operation": "ListBooks", "arguments": map[string]any{}},
+	}})
+	if result.IsError {
+		t.Fatalf("the batch failed: %s", result.text())
+	}
+
+	// The failed call does not stop the batch
+	var outcome struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+		Calls     []struct {
+			Operation string            `json:"operation"`
+			OK        bool              `json:"ok"`
+			Result    []json.RawMessage `json:"result"`
+			Error     string            `json:"error"`
+		} `json:"calls"`
+	}
+	if err := json.Unmarshal([]byte(result.text()), &outcome); err != nil {
+		t.Fatalf("decoding the batch result %s: %v", result.text(), err)
+	}
+	if outcome.Succeeded != 1 || outcome.Failed != 1 || len(outcome.Calls) != 2 {
+		t.Fatalf("the batch result is %s", result.text())
+	}
+	if call := outcome.Calls[0]; call.Operation != "AddBook" || call.OK || call.Error != "error on AddBook: 409 Conflict: already added" {
+		t.Errorf("the failed call outcome is %+v", call)
+	}
+	if call := outcome.Calls[1]; call.Operation != "ListBooks" || !call.OK || len(call.Result) != 1 || !strings.Contains(string(call.Result[0]), `"Name": "Dune"`) {
+		t.Errorf("the successful call outcome is %+v", call)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/codegen"
+)
+
+// bodyTemplateFuncs are the functions available to the body templates, the
+// generated server defines the same ones
+var bodyTemplateFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// loadBodyTemplates reads the body templates given for the operations, the
+// top-level fields used by a template become the arguments of its tool
+func loadBodyTemplates(doc *openapi3.T, operations map[string]OperationInfo) error {
+	for id := range CLI.BodyTemplates {
+		if _, ok := operations[id]; !ok {
+			warnf("body template given for unknown operation %s", id)
+		}
+	}
+
+	for _, pathItem := range doc.Paths.Map() {
+		for _, operation := range pathItem.Operations() {
+			file, ok := CLI.BodyTemplates[operation.OperationID]
+			op, known := operations[operation.OperationID]
+			if !ok || !known {
+				continue
+			}
+			if !op.HasRequestBody {
+				return fmt.Errorf("body template given for %s, which has no request body", op.ID)
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("error reading body template of %s: %w", op.ID, err)
+			}
+			tmpl, err := template.New(op.ID).Funcs(bodyTemplateFuncs).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return fmt.Errorf("invalid body template of %s: %w", op.ID, err)
+			}
+
+			schema := bodySchema(operation)
+			op.BodyTemplate = string(content)
+			op.BodyTemplateFields = templateFields(templateVariables(tmpl), schema)
+			op.BodyVariants = nil
+			if err := checkBodyTemplate(op, tmpl, schema); err != nil {
+				return err
+			}
+			operations[op.ID] = op
+		}
+	}
+	return nil
+}
+
+// bodySchema returns the schema of the JSON request body, nil when none
+func bodySchema(operation *openapi3.Operation) *openapi3.Schema {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return nil
+	}
+
+	mediaType := operation.RequestBody.Value.Content.Get("application/json")
+	if mediaType == nil || mediaType.Schema == nil {
+		return nil
+	}
+	return mediaType.Schema.Value
+}
+
+// templateVariables returns the sorted names of the fields of the template
+// data used by the template. Dot is followed through the template: the
+// fields inside range and with are the ones of their element, not of the
+// data, while $ always refers to the data. Templates invoked with the data
+// as dot are walked as part of the template.
+func templateVariables(tmpl *template.Template) []string {
+	names := make(map[string]bool)
+	walked := make(map[string]bool)
+	var walk func(node parse.Node, root bool)
+	walk = func(node parse.Node, root bool) {
+		switch node := node.(type) {
+		case *parse.ListNode:
+			if node == nil {
+				return
+			}
+			for _, child := range node.Nodes {
+				walk(child, root)
+			}
+		case *parse.ActionNode:
+			walk(node.Pipe, root)
+		case *parse.IfNode:
+			walk(node.Pipe, root)
+			walk(node.List, root)
+			walk(node.ElseList, root)
+		case *parse.RangeNode:
+			walk(node.Pipe, root)
+			walk(node.List, false)
+			walk(node.ElseList, root)
+		case *parse.WithNode:
+			walk(node.Pipe, root)
+			walk(node.List, false)
+			walk(node.ElseList, root)
+		case *parse.TemplateNode:
+			walk(node.Pipe, root)
+			invoked := tmpl.Lookup(node.Name)
+			if !root || invoked == nil || invoked.Tree == nil || walked[node.Name] || !passesDot(node.Pipe) {
+				return
+			}
+			walked[node.Name] = true
+			walk(invoked.Tree.Root, true)
+		case *parse.PipeNode:
+			if node == nil {
+				return
+			}
+			for _, command := range node.Cmds {
+				walk(command, root)
+			}
+		case *parse.CommandNode:
+			for _, arg := range node.Args {
+				walk(arg, root)
+			}
+		case *parse.ChainNode:
+			walk(node.Node, root)
+		case *parse.FieldNode:
+			if root {
+				names[node.Ident[0]] = true
+			}
+		case *parse.VariableNode:
+			if node.Ident[0] == "$" && len(node.Ident) > 1 {
+				names[node.Ident[1]] = true
+			}
+		}
+	}
+	walk(tmpl.Tree.Root, true)
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// passesDot reports whether the pipeline of a template invocation is dot or
+// $, the data of the template
+func passesDot(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return true
+	case *parse.VariableNode:
+		return len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	}
+	return false
+}
+
+// templateFields returns the tool arguments of the template variables, typed
+// and described by the body schema property of the same name if any
+func templateFields(variables []string, schema *openapi3.Schema) []ConfigField {
+	fields := make([]ConfigField, 0, len(variables))
+	for _, name := range variables {
+		var property *openapi3.Schema
+		if schema != nil && schema.Properties[name] != nil {
+			property = schema.Properties[name].Value
+		}
+
+		description := "Value of " + name + " in the request body"
+		var fieldType jen.Code = jen.String()
+		if property != nil {
+			if property.Description != "" {
+				description = property.Description
+			}
+			fieldType = templateFieldType(property)
+		}
+
+		fields = append(fields, ConfigField{
+			Name: codegen.SchemaNameToTypeName(name),
+			Type: fieldType,
+			Tags: map[string]string{"json": name, "jsonschema": "required", "jsonschema_description": description},
+		})
+	}
+	return fields
+}
+
+// templateFieldType returns the Go type of a template variable of the schema
+func templateFieldType(schema *openapi3.Schema) jen.Code {
+	switch {
+	case schema.Type.Is(openapi3.TypeString):
+		return jen.String()
+	case schema.Type.Is(openapi3.TypeInteger):
+		return jen.Int64()
+	case schema.Type.Is(openapi3.TypeNumber):
+		return jen.Float64()
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return jen.Bool()
+	case schema.Type.Is(openapi3.TypeArray) && schema.Items != nil && schema.Items.Value != nil:
+		return jen.Index().Add(templateFieldType(schema.Items.Value))
+	case schema.Type.Is(openapi3.TypeArray):
+		return jen.Index().Any()
+	}
+	return jen.Map(jen.String()).Any()
+}
+
+// sampleValue returns a value of the schema used to check the template, its
+// example or first enum value when given
+func sampleValue(schema *openapi3.Schema) any {
+	switch {
+	case schema == nil:
+		return ""
+	case schema.Example != nil:
+		return schema.Example
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case schema.Type.Is(openapi3.TypeString):
+		return ""
+	case schema.Type.Is(openapi3.TypeInteger), schema.Type.Is(openapi3.TypeNumber):
+		return 0
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return false
+	case schema.Type.Is(openapi3.TypeArray):
+		return []any{}
+	}
+	return map[string]any{}
+}
+
+// checkBodyTemplate renders the template with sample values and checks the
+// result is JSON matching the body schema. Rendering errors and mismatches are
+// only warnings unless strict, as the sample values may not be representative.
+func checkBodyTemplate(op OperationInfo, tmpl *template.Template, schema *openapi3.Schema) error {
+	sampleWarning := func(format string, args ...any) error {
+		message := fmt.Sprintf(format, args...)
+		if CLI.Strict {
+			return fmt.Errorf("%s", message)
+		}
+		warnf("%s", message)
+		return nil
+	}
+
+	data := make(map[string]any)
+	for _, field := range op.BodyTemplateFields {
+		name := field.Tags["json"]
+		var property *openapi3.Schema
+		if schema != nil && schema.Properties[name] != nil {
+			property = schema.Properties[name].Value
+		}
+		data[name] = sampleValue(property)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return sampleWarning("body template of %s cannot be rendered with sample values: %v", op.ID, err)
+	}
+	var value any
+	if err := json.Unmarshal(rendered.Bytes(), &value); err != nil {
+		return fmt.Errorf("body template of %s does not render JSON: %w", op.ID, err)
+	}
+	if schema == nil {
+		return nil
+	}
+
+	if err := schema.VisitJSON(value); err != nil {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			err = fmt.Errorf("%s at /%s", schemaErr.Reason, strings.Join(schemaErr.JSONPointer(), "/"))
+		}
+		return sampleWarning("body template of %s does not match the body schema with sample values: %v", op.ID, err)
+	}
+	return nil
+}
+
+// bodyTemplateVar returns the name of the variable holding the parsed body
+// template of the operation in the generated server
+func bodyTemplateVar(op OperationInfo) string {
+	return strings.ToLower(op.ID[:1]) + op.ID[1:] + "BodyTemplate"
+}
+
+// addBodyTemplate adds the variable holding the parsed body template
+func addBodyTemplate(f *jen.File, op OperationInfo) {
+	f.Comment(bodyTemplateVar(op) + " builds the " + op.ID + " body from the tool arguments")
+	f.Var().Id(bodyTemplateVar(op)).Op("=").Qual("text/template", "Must").Call(
+		jen.Qual("text/template", "New").Call(jen.Lit(op.ID)).
+			Dot("Funcs").Call(jen.Id("bodyTemplateFuncs")).
+			Dot("Option").Call(jen.Lit("missingkey=error")).
+			Dot("Parse").Call(jen.Lit(op.BodyTemplate)),
+	)
+}
+
+// addRenderBody adds the functions rendering a body template with the tool
+// arguments, by their JSON names
+func addRenderBody(f *jen.File) {
+	f.Comment("bodyTemplateFuncs are the functions available to the body templates")
+	f.Var().Id("bodyTemplateFuncs").Op("=").Qual("text/template", "FuncMap").Values(jen.Dict{
+		jen.Lit("json"): jen.Func().Params(jen.Id("value").Any()).Params(jen.String(), jen.Error()).Block(
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("value")),
+			jen.Return(jen.String().Call(jen.Id("data")), jen.Err()),
+		),
+	})
+
+	f.Comment("renderBody renders the body template with the tool arguments and checks the result is JSON")
+	f.Func().Id("renderBody").Params(
+		jen.Id("tmpl").Op("*").Qual("text/template", "Template"),
+		jen.Id("arguments").Any(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.List(jen.Id("encoded"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("arguments")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+
+		// Numbers are kept as written so large integers are not rounded
+		jen.Var().Id("data").Map(jen.String()).Any(),
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("encoded"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("data")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+
+		jen.Var().Id("body").Qual("bytes", "Buffer"),
+		jen.If(jen.Err().Op(":=").Id("tmpl").Dot("Execute").Call(jen.Op("&").Id("body"), jen.Id("data")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.If(jen.Op("!").Qual("encoding/json", "Valid").Call(jen.Id("body").Dot("Bytes").Call())).Block(
+			jen.Return(jen.Nil(), jen.Qual("errors", "New").Call(jen.Lit("template did not render JSON"))),
+		),
+		jen.Return(jen.Id("body").Dot("Bytes").Call(), jen.Nil()),
+	)
+}
+
+// templateBodyCode returns the statements rendering the body template of
+// the operation into the requestBody bytes
+func templateBodyCode(op OperationInfo) []jen.Code {
+	return []jen.Code{
+		jen.List(jen.Id("requestBody"), jen.Err()).Op(":=").Id("renderBody").Call(jen.Id(bodyTemplateVar(op)), jen.Id("arguments")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error building "+op.ID+" body: %v"), jen.Err())),
+		),
+	}
+}
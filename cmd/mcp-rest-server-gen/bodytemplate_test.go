@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	tests := []struct {
+		name, text string
+		want       []string
+	}{
+		{"fields", `{"title": {{json .title}}, "done": {{.done}}}`, []string{"done", "title"}},
+		{"range element", `[{{range .labels}}{{json .name}}{{end}}]`, []string{"labels"}},
+		{"with element", `{{with .owner}}{{json .login}}{{else}}{{json .team}}{{end}}`, []string{"owner", "team"}},
+		{"root variable", `[{{range .labels}}{{json $.prefix}}{{end}}]`, []string{"labels", "prefix"}},
+		{"chained field", `{{(.owner).login}}`, []string{"owner"}},
+		{"declared variable", `{{range $label := .labels}}{{$label.name}}{{end}}`, []string{"labels"}},
+		{"invoked with data", `{{define "title"}}{{json .title}}{{end}}{"title": {{template "title" .}}}`, []string{"title"}},
+		{"invoked with element", `{{define "name"}}{{json .name}}{{end}}{{range .labels}}{{template "name" .}}{{end}}`, []string{"labels"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl := template.Must(template.New(test.name).Funcs(bodyTemplateFuncs).Parse(test.text))
+			if got := templateVariables(tmpl); !slices.Equal(got, test.want) {
+				t.Errorf("the variables are %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBodyTemplate(t *testing.T) {
+	bodies := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		respond(http.StatusOK, "application/json", `{"Id":1}`)(w, r)
+	}))
+	defer upstream.Close()
+
+	file := filepath.Join(t.TempDir(), "addbook.tmpl")
+	text := `{"Name": {{with .Name}}{{json (printf "%s by %s" . $.Author)}}{{else}}""{{end}}}`
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code := generate(t, booksSpec, "--body-templates", "AddBook="+file)
+	assertContains(t, code, "Author string `json:\"Author\" jsonschema:\"required\"", "Name   string `json:\"Name\" jsonschema:\"required\"")
+
+	binary := buildServer(t, booksSpec, "--body-templates", "AddBook="+file)
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune", "Author": "Herbert"}); result.IsError {
+		t.Fatalf("AddBook failed: %s", result.text())
+	}
+	if got, want := <-bodies, `{"Name": "Dune by Herbert"}`; got != want {
+		t.Errorf("the body sent is %s, want %s", got, want)
+	}
+}
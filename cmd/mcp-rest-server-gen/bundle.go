@@ -0,0 +1,198 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// specEntryNames are the root spec file names looked for in a bundle when no
+// entry is given, in order of preference
+var specEntryNames = []string{"openapi.yaml", "openapi.yml", "openapi.json", "swagger.yaml", "swagger.yml", "swagger.json"}
+
+// isSpecBundle reports whether the spec path points to a zip or tarball bundle
+func isSpecBundle(specPath string) bool {
+	if parsedURL, err := url.Parse(specPath); err == nil && parsedURL.Scheme != "" {
+		specPath = parsedURL.Path
+	}
+	lower := strings.ToLower(specPath)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// readSpecBundle reads the bundle from either a file or URL
+func readSpecBundle(specPath string) ([]byte, error) {
+	parsedURL, err := url.Parse(specPath)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return os.ReadFile(specPath)
+	}
+
+	content, _, err := fetchSpec(parsedURL)
+	return content, err
+}
+
+// zipMagic starts the local file headers of the zip archives
+var zipMagic = []byte("PK\x03\x04")
+
+// extractSpecBundle extracts the bundle content into a new temporary
+// directory, the caller is responsible for removing it. The format is told
+// from the content, the bundle URLs may not end with the file extension.
+func extractSpecBundle(content []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "mcp-rest-spec")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp directory: %w", err)
+	}
+
+	if bytes.HasPrefix(content, zipMagic) {
+		err = extractZip(content, dir)
+	} else {
+		err = extractTarGz(content, dir)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("error extracting spec bundle: %w", err)
+	}
+
+	return dir, nil
+}
+
+// bundlePath returns the path of a bundle file in the directory, rejecting
+// names escaping it
+func bundlePath(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid file name in bundle: %s", name)
+	}
+	return path, nil
+}
+
+// writeBundleFile writes a bundle file, creating its parent directories
+func writeBundleFile(dir, name string, r io.Reader) error {
+	path, err := bundlePath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, r)
+	return err
+}
+
+// extractZip extracts the regular files of a zip archive into the directory
+func extractZip(content []byte, dir string) error {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		r, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeBundleFile(dir, entry.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractTarGz extracts the regular files of a gzipped tarball into the directory
+func extractTarGz(content []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := writeBundleFile(dir, header.Name, archive); err != nil {
+			return err
+		}
+	}
+}
+
+// findSpecEntry returns the path of the root spec in the extracted bundle,
+// either the configured entry or the shallowest file with a well-known name
+func findSpecEntry(dir string) (string, error) {
+	if CLI.SpecEntry != "" {
+		path, err := bundlePath(dir, CLI.SpecEntry)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("spec entry %s not found in bundle", CLI.SpecEntry)
+		}
+		return path, nil
+	}
+
+	var candidates []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, name := range specEntryNames {
+			if !entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+				candidates = append(candidates, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no root spec found in bundle, set --spec-entry")
+	}
+
+	// Prefer the shallowest file, then the preferred name
+	rank := func(path string) int {
+		for i, name := range specEntryNames {
+			if strings.EqualFold(filepath.Base(path), name) {
+				return i
+			}
+		}
+		return len(specEntryNames)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		di, dj := strings.Count(candidates[i], string(os.PathSeparator)), strings.Count(candidates[j], string(os.PathSeparator))
+		if di != dj {
+			return di < dj
+		}
+		return rank(candidates[i]) < rank(candidates[j])
+	})
+
+	return candidates[0], nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// bundleFiles returns the files of the bundle fixture by slash-separated name
+func bundleFiles(t *testing.T) map[string][]byte {
+	t.Helper()
+	files := make(map[string][]byte)
+	err := filepath.WalkDir("testdata/bundle", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel("testdata/bundle", path)
+		files[filepath.ToSlash(name)] = content
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// writeZipBundle writes the files as a zip bundle, returning its path
+func writeZipBundle(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	var b bytes.Buffer
+	archive := zip.NewWriter(&b)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "books.zip")
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTarGzBundle writes the files as a gzipped tarball bundle, returning its path
+func writeTarGzBundle(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	var b bytes.Buffer
+	compressed := gzip.NewWriter(&b)
+	archive := tar.NewWriter(compressed)
+	for name, content := range files {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write(content)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "books.tar.gz")
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSpecBundle(t *testing.T) {
+	files := bundleFiles(t)
+	for name, bundle := range map[string]string{"zip": writeZipBundle(t, files), "tar.gz": writeTarGzBundle(t, files)} {
+		t.Run(name, func(t *testing.T) {
+			// The shallowest root spec is used, with the path files it references
+			generate(t, bundle)
+			if want := []string{"AddBook", "ListBooks"}; !slices.Equal(report.Operations, want) {
+				t.Errorf("the operations of the bundle are %v, want %v", report.Operations, want)
+			}
+
+			generate(t, bundle, "--spec-entry", "legacy/openapi.yaml")
+			if want := []string{"ListLegacyBooks"}; !slices.Equal(report.Operations, want) {
+				t.Errorf("the operations of the bundle entry are %v, want %v", report.Operations, want)
+			}
+		})
+	}
+}
+
+func TestSpecBundleEscapingName(t *testing.T) {
+	bundle := writeZipBundle(t, map[string][]byte{"../openapi.yaml": []byte("openapi: 3.0.1")})
+	content, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := extractSpecBundle(content)
+	if err == nil {
+		os.RemoveAll(dir)
+		t.Fatal("a bundle file escaping the directory was extracted")
+	}
+}
+
+func TestIsSpecBundle(t *testing.T) {
+	for path, want := range map[string]bool{
+		"api.zip":                               true,
+		"specs/API.TAR.GZ":                      true,
+		"api.tgz":                               true,
+		"https://example.com/api.zip?token=abc": true,
+		"openapi.yaml":                          false,
+		"https://example.com/openapi.json":      false,
+	} {
+		if got := isSpecBundle(path); got != want {
+			t.Errorf("isSpecBundle(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// redisPackage is the import path of the Redis client used by the redis cache
+// backend
+const redisPackage = "github.com/redis/go-redis/v9"
+
+// cacheFields returns the runtime flags of the GET results cache
+func cacheFields() []ConfigField {
+	fields := []ConfigField{{
+		Name: "CacheTTL", Type: jen.Qual("time", "Duration"),
+		Tags: map[string]string{"help": "Time the successful GET tool results are cached, 0 to disable", "default": CLI.CacheTTL.String()},
+	}}
+	if CLI.CacheBackend == "redis" {
+		fields = append(fields, ConfigField{
+			Name: "RedisURL", Type: jen.String(),
+			Tags: map[string]string{"help": "URL of the Redis server sharing the cached results between replicas, such as redis://localhost:6379/0, the results are cached in memory when empty", "env": CLI.RedisURLEnv},
+		})
+	}
+	return fields
+}
+
+// anyCachedOperation reports whether some operation has its results cached,
+// only the GET ones are
+func anyCachedOperation(operations map[string]OperationInfo) bool {
+	for _, op := range operations {
+		if op.Method == "GET" {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheCode returns the statements creating the cache of the GET results
+func cacheCode() []jen.Code {
+	if CLI.CacheBackend != "redis" {
+		return []jen.Code{
+			jen.Var().Id("cache").Id("resultCache").Op("=").Id("newMemoryCache").Call(),
+		}
+	}
+	return []jen.Code{
+		jen.List(jen.Id("cache"), jen.Err()).Op(":=").Id("newResultCache").Call(jen.Id("cli").Dot("RedisURL")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("log", "Fatalf").Call(jen.Lit("invalid Redis URL: %v"), jen.Err()),
+		),
+	}
+}
+
+// cacheIdentityCode returns the statements and the function giving the
+// identity of the credentials the results are cached for, nil when the server
+// sends none. Replaceable credentials are the ones of the active connection.
+func cacheIdentityCode(f *jen.File, auth authScheme, withAuth, credentialTool bool) ([]jen.Code, jen.Code) {
+	if !withAuth {
+		return nil, jen.Nil()
+	}
+	addCredentialIdentity(f)
+	if credentialTool {
+		return nil, jen.Func().Params().String().Block(
+			jen.Return(jen.Id("active").Dot("Load").Call().Dot("identity")),
+		)
+	}
+	return []jen.Code{
+		jen.Id("cacheIdentity").Op(":=").Id("credentialIdentity").Call(
+			auth.credentialArgs(func(field ConfigField) jen.Code { return jen.Id("cli").Dot(field.Name) })...,
+		),
+	}, jen.Func().Params().String().Block(
+		jen.Return(jen.Id("cacheIdentity")),
+	)
+}
+
+// addCredentialIdentity adds the function identifying the credentials in the
+// cache keys
+func addCredentialIdentity(f *jen.File) {
+	f.Comment("credentialIdentity returns the hash of the credentials, keeping the results cached for")
+	f.Comment("some credentials from the callers of others without storing them")
+	f.Func().Id("credentialIdentity").Params(jen.Id("credentials").Op("...").String()).String().Block(
+		jen.Id("hash").Op(":=").Qual("crypto/sha256", "New").Call(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("credential")).Op(":=").Range().Id("credentials")).Block(
+			jen.Id("hash").Dot("Write").Call(jen.Index().Byte().Call(jen.Id("credential"))),
+			jen.Id("hash").Dot("Write").Call(jen.Index().Byte().Values(jen.Lit(0))),
+		),
+		jen.Return(jen.Qual("encoding/hex", "EncodeToString").Call(jen.Id("hash").Dot("Sum").Call(jen.Nil()))),
+	)
+}
+
+// cacheHandlerCode wraps the handler of a GET operation so its successful
+// results are cached
+func cacheHandlerCode(op OperationInfo, identity, handler jen.Code) *jen.Statement {
+	return jen.Id("cacheHandler").Call(jen.Id("cli").Dot("Host"), identity, jen.Lit(op.ID), jen.Id("cache"), jen.Id("cli").Dot("CacheTTL"), handler)
+}
+
+// addResultCache adds the cache interface used by the handlers with its
+// in-memory implementation, and the Redis one for the redis backend
+func addResultCache(f *jen.File) {
+	f.Comment("resultCache stores the encoded tool results by key, it must be safe for concurrent use")
+	f.Type().Id("resultCache").Interface(
+		jen.Id("Get").Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("key").String()).Params(jen.Index().Byte(), jen.Bool()),
+		jen.Id("Set").Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("key").String(), jen.Id("value").Index().Byte(), jen.Id("ttl").Qual("time", "Duration")),
+	)
+
+	f.Comment("cacheEntry is a result cached in memory until it expires")
+	f.Type().Id("cacheEntry").Struct(
+		jen.Id("value").Index().Byte(),
+		jen.Id("expires").Qual("time", "Time"),
+	)
+
+	f.Comment("memoryCache is the result cache of a single server")
+	f.Type().Id("memoryCache").Struct(
+		jen.Id("mu").Qual("sync", "Mutex"),
+		jen.Id("entries").Map(jen.String()).Id("cacheEntry"),
+	)
+
+	f.Comment("newMemoryCache returns an empty in-memory result cache")
+	f.Func().Id("newMemoryCache").Params().Op("*").Id("memoryCache").Block(
+		jen.Return(jen.Op("&").Id("memoryCache").Values(jen.Dict{
+			jen.Id("entries"): jen.Map(jen.String()).Id("cacheEntry").Values(),
+		})),
+	)
+
+	f.Func().Params(jen.Id("c").Op("*").Id("memoryCache")).Id("Get").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("key").String(),
+	).Params(jen.Index().Byte(), jen.Bool()).Block(
+		jen.Id("c").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("c").Dot("mu").Dot("Unlock").Call(),
+		jen.List(jen.Id("entry"), jen.Id("ok")).Op(":=").Id("c").Dot("entries").Index(jen.Id("key")),
+		jen.If(jen.Op("!").Id("ok")).Block(
+			jen.Return(jen.Nil(), jen.False()),
+		),
+		jen.If(jen.Qual("time", "Now").Call().Dot("After").Call(jen.Id("entry").Dot("expires"))).Block(
+			jen.Delete(jen.Id("c").Dot("entries"), jen.Id("key")),
+			jen.Return(jen.Nil(), jen.False()),
+		),
+		jen.Return(jen.Id("entry").Dot("value"), jen.True()),
+	)
+
+	f.Func().Params(jen.Id("c").Op("*").Id("memoryCache")).Id("Set").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("key").String(),
+		jen.Id("value").Index().Byte(),
+		jen.Id("ttl").Qual("time", "Duration"),
+	).Block(
+		jen.Id("c").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("c").Dot("mu").Dot("Unlock").Call(),
+		jen.Id("now").Op(":=").Qual("time", "Now").Call(),
+		jen.For(jen.List(jen.Id("k"), jen.Id("entry")).Op(":=").Range().Id("c").Dot("entries")).Block(
+			jen.If(jen.Id("now").Dot("After").Call(jen.Id("entry").Dot("expires"))).Block(
+				jen.Delete(jen.Id("c").Dot("entries"), jen.Id("k")),
+			),
+		),
+		jen.Id("c").Dot("entries").Index(jen.Id("key")).Op("=").Id("cacheEntry").Values(jen.Dict{
+			jen.Id("value"):   jen.Id("value"),
+			jen.Id("expires"): jen.Id("now").Dot("Add").Call(jen.Id("ttl")),
+		}),
+	)
+
+	if CLI.CacheBackend == "redis" {
+		addRedisCache(f)
+	}
+}
+
+// addRedisCache adds the result cache shared through a Redis server
+func addRedisCache(f *jen.File) {
+	f.Comment("redisCache is the result cache shared by the servers using the same Redis server, its")
+	f.Comment("failures are logged and treated as cache misses")
+	f.Type().Id("redisCache").Struct(
+		jen.Id("client").Op("*").Qual(redisPackage, "Client"),
+	)
+
+	f.Func().Params(jen.Id("c").Op("*").Id("redisCache")).Id("Get").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("key").String(),
+	).Params(jen.Index().Byte(), jen.Bool()).Block(
+		jen.List(jen.Id("value"), jen.Err()).Op(":=").Id("c").Dot("client").Dot("Get").Call(jen.Id("ctx"), jen.Id("key")).Dot("Bytes").Call(),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.If(jen.Op("!").Qual("errors", "Is").Call(jen.Err(), jen.Qual(redisPackage, "Nil"))).Block(
+				jen.Qual("log/slog", "Warn").Call(jen.Lit("Could not read the result cache"), jen.Lit("error"), jen.Err()),
+			),
+			jen.Return(jen.Nil(), jen.False()),
+		),
+		jen.Return(jen.Id("value"), jen.True()),
+	)
+
+	f.Func().Params(jen.Id("c").Op("*").Id("redisCache")).Id("Set").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("key").String(),
+		jen.Id("value").Index().Byte(),
+		jen.Id("ttl").Qual("time", "Duration"),
+	).Block(
+		jen.If(jen.Err().Op(":=").Id("c").Dot("client").Dot("Set").Call(jen.Id("ctx"), jen.Id("key"), jen.Id("value"), jen.Id("ttl")).Dot("Err").Call(), jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("log/slog", "Warn").Call(jen.Lit("Could not write the result cache"), jen.Lit("error"), jen.Err()),
+		),
+	)
+
+	f.Comment("newResultCache returns the cache shared through the Redis server at the URL, or an")
+	f.Comment("in-memory cache when the URL is empty")
+	f.Func().Id("newResultCache").Params(jen.Id("redisURL").String()).Params(jen.Id("resultCache"), jen.Error()).Block(
+		jen.If(jen.Id("redisURL").Op("==").Lit("")).Block(
+			jen.Return(jen.Id("newMemoryCache").Call(), jen.Nil()),
+		),
+		jen.List(jen.Id("options"), jen.Err()).Op(":=").Qual(redisPackage, "ParseURL").Call(jen.Id("redisURL")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.Return(jen.Op("&").Id("redisCache").Values(jen.Dict{
+			jen.Id("client"): jen.Qual(redisPackage, "NewClient").Call(jen.Id("options")),
+		}), jen.Nil()),
+	)
+}
+
+// addCacheHandler adds the function caching the successful results of the
+// tool calls. The key covers the API host, the identity of the credentials,
+// the operation and the JSON encoding of the arguments, so servers of other
+// APIs or calling with other credentials can share the cache.
+func addCacheHandler(f *jen.File) {
+	handlerType := handlerSignature(jen.Id("T"))
+
+	f.Comment("cacheHandler returns a handler answering the calls with the result cached for their")
+	f.Comment("arguments and credentials, calling the API and caching its result for the ttl otherwise,")
+	f.Comment("identity is nil when no credentials are sent")
+	f.Func().Id("cacheHandler").Types(jen.Id("T").Any()).Params(
+		jen.Id("host").String(),
+		jen.Id("identity").Func().Params().String(),
+		jen.Id("operation").String(),
+		jen.Id("cache").Id("resultCache"),
+		jen.Id("ttl").Qual("time", "Duration"),
+		jen.Id("handler").Add(handlerType.Clone()),
+	).Add(handlerType.Clone()).Block(
+		jen.Return(jen.Func().Params(jen.Id("arguments").Id("T")).Params(toolResultType(), jen.Error()).Block(
+			jen.If(jen.Id("ttl").Op("<=").Lit(0)).Block(
+				jen.Return(jen.Id("handler").Call(jen.Id("arguments"))),
+			),
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("arguments")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Id("handler").Call(jen.Id("arguments"))),
+			),
+			jen.Id("scope").Op(":=").Id("host").Op("+").Lit(" "),
+			jen.If(jen.Id("identity").Op("!=").Nil()).Block(
+				jen.Id("scope").Op("+=").Id("identity").Call().Op("+").Lit(" "),
+			),
+			jen.Id("sum").Op(":=").Qual("crypto/sha256", "Sum256").Call(jen.Append(jen.Index().Byte().Call(jen.Id("scope").Op("+").Id("operation").Op("+").Lit(" ")), jen.Id("data").Op("..."))),
+			jen.Id("key").Op(":=").Lit("mcp-rest:").Op("+").Id("operation").Op("+").Lit(":").Op("+").Qual("encoding/hex", "EncodeToString").Call(jen.Id("sum").Index(jen.Empty(), jen.Empty())),
+			jen.Id("ctx").Op(":=").Qual("context", "Background").Call(),
+
+			jen.If(jen.List(jen.Id("cached"), jen.Id("ok")).Op(":=").Id("cache").Dot("Get").Call(jen.Id("ctx"), jen.Id("key")), jen.Id("ok")).Block(
+				jen.Id("result").Op(":=").New(toolResultStruct()),
+				jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("cached"), jen.Id("result")), jen.Err().Op("==").Nil()).Block(
+					jen.Qual("log/slog", "Debug").Call(jen.Lit("Call answered from the cache"), jen.Lit("operation"), jen.Id("operation")),
+					jen.Return(jen.Id("result"), jen.Nil()),
+				),
+			),
+
+			jen.List(jen.Id("result"), jen.Err()).Op(":=").Id("handler").Call(jen.Id("arguments")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.If(jen.List(jen.Id("encoded"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("result")), jen.Err().Op("==").Nil()).Block(
+				jen.Id("cache").Dot("Set").Call(jen.Id("ctx"), jen.Id("key"), jen.Id("encoded"), jen.Id("ttl")),
+			),
+			jen.Return(jen.Id("result"), jen.Nil()),
+		)),
+	)
+}
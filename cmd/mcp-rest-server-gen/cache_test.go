@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeCacheTest is compiled with the generated server, checking its handlers
+// go through the cache interface with an in-memory fake
+const fakeCacheTest = `package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+type fakeCache struct {
+	entries map[string][]byte
+	gets    int
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.gets++
+	value, ok := c.entries[key]
+	return value, ok
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	c.entries[key] = value
+}
+
+func TestFakeCache(t *testing.T) {
+	cache := &fakeCache{entries: map[string][]byte{}}
+	calls := 0
+	list := func(arguments map[string]string) (*mcp_golang.ToolResponse, error) {
+		calls++
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Dune")), nil
+	}
+	handler := cacheHandler("http://api", nil, "ListBooks", cache, time.Minute, list)
+
+	for range 2 {
+		result, err := handler(map[string]string{"NameFilter": "Dune"})
+		if err != nil || result.Content[0].TextContent.Text != "Dune" {
+			t.Fatalf("the handler returned %v, %v", result, err)
+		}
+	}
+	if _, err := handler(map[string]string{"NameFilter": "Emma"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || cache.gets != 3 || len(cache.entries) != 2 {
+		t.Errorf("the API was called %d times with %d lookups of %d entries, want 2, 3 and 2", calls, cache.gets, len(cache.entries))
+	}
+
+	// The results cached for some credentials are not given to others
+	calls = 0
+	for _, user := range []string{"user", "other", "user"} {
+		identity := credentialIdentity(user, "secret")
+		handler := cacheHandler("http://api", func() string { return identity }, "ListBooks", cache, time.Minute, list)
+		if _, err := handler(map[string]string{"NameFilter": "Dune"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("the API was called %d times for two credentials, want 2", calls)
+	}
+	if credentialIdentity("us", "ersecret") == credentialIdentity("user", "secret") {
+		t.Error("the identities of credentials differing in their split are the same")
+	}
+
+	if cache, err := newResultCache(""); err != nil || cache == nil {
+		t.Errorf("without Redis URL the cache is %T, %v", cache, err)
+	} else if _, ok := cache.(*memoryCache); !ok {
+		t.Errorf("without Redis URL the cache is %T, want the in-memory one", cache)
+	}
+	if cache, err := newResultCache("redis://localhost:6379/0"); err != nil {
+		t.Errorf("creating the Redis cache: %v", err)
+	} else if _, ok := cache.(*redisCache); !ok {
+		t.Errorf("with a Redis URL the cache is %T, want the Redis one", cache)
+	}
+	if _, err := newResultCache("localhost:6379"); err == nil {
+		t.Error("an invalid Redis URL was accepted")
+	}
+}
+`
+
+func TestRedisCacheBackend(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--cache-ttl", "1m", "--cache-backend", "redis")
+	dir := filepath.Dir(binary)
+	if err := os.WriteFile(filepath.Join(dir, "cache_test.go"), []byte(fakeCacheTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	test := exec.Command("go", "test", "-run", "TestFakeCache", ".")
+	test.Dir = dir
+	test.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if output, err := test.CombinedOutput(); err != nil {
+		t.Errorf("testing the generated cache: %v\n%s", err, output)
+	}
+
+	// Without Redis URL the results are cached in memory
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	for range 2 {
+		if result := session.callTool(t, "ListBooks", map[string]any{"NameFilter": "Dune"}); result.IsError {
+			t.Fatalf("ListBooks failed: %s", result.text())
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("the API was called %d times, want once", got)
+	}
+}
+
+func TestCacheWithoutGetOperations(t *testing.T) {
+	// Only the GET results are cached, a spec without GET has no cache
+	code := generate(t, "testdata/optional.yaml", "--auth-type", "none", "--cache-ttl", "1m")
+	assertNotContains(t, code, "resultCache", "CacheTTL")
+	assertWarning(t, "--cache-ttl is ignored as the spec has no GET operation")
+	buildServer(t, "testdata/optional.yaml", "--auth-type", "none", "--cache-ttl", "1m")
+}
+
+func TestCacheWithCredentialTool(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	// The results cached for the replaced credentials are not reused
+	binary := buildServer(t, booksSpec, "--cache-ttl", "1m", "--with-credential-tool")
+	session := startServer(t, binary, nil, "--host", upstream.URL, "--enable-credential-tool")
+	listBooks := func() {
+		t.Helper()
+		if result := session.callTool(t, "ListBooks", map[string]any{"NameFilter": "Dune"}); result.IsError {
+			t.Fatalf("ListBooks failed: %s", result.text())
+		}
+	}
+	listBooks()
+	listBooks()
+	if result := session.callTool(t, "UpdateCredentials", map[string]any{"username": "other", "password": "secret"}); result.IsError {
+		t.Fatalf("UpdateCredentials failed: %s", result.text())
+	}
+	listBooks()
+	if got := calls.Load(); got != 3 {
+		t.Errorf("the API was called %d times, want 3 with the check of the new credentials", got)
+	}
+}
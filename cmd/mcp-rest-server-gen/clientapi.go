@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// defaultClientMethodTemplate is the client method template of the
+// oapi-codegen clients, the only ones with known method signatures
+const defaultClientMethodTemplate = "{{.ID}}WithResponse"
+
+// clientAPIMethod returns the signature of the oapi-codegen client method
+// called for the operation
+func clientAPIMethod(op OperationInfo, name string) jen.Code {
+	params := []jen.Code{jen.Id("ctx").Qual("context", "Context")}
+	switch {
+	case op.HasRequestBody && (!op.BodyRequired || op.BodyTemplate != "" || op.FreeFormBody || op.BodyInlineUnion):
+		params = append(params, jen.Id("contentType").String(), jen.Id("body").Qual("io", "Reader"))
+	case op.HasRequestBody:
+		params = append(params, jen.Id("body").Qual(CLI.ClientImport, op.ParameterType))
+	default:
+		params = append(params, jen.Id("params").Op("*").Qual(CLI.ClientImport, op.ParameterType))
+	}
+	params = append(params, jen.Id("reqEditors").Op("...").Qual(CLI.ClientImport, "RequestEditorFn"))
+
+	return jen.Id(name).Params(params...).Params(
+		jen.Op("*").Qual(CLI.ClientImport, op.ID+"Response"),
+		jen.Error(),
+	)
+}
+
+// addClientAPI adds the interface covering the client methods called by the
+// tools, sorted by name
+func addClientAPI(f *jen.File, methods map[string]jen.Code) {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	signatures := make([]jen.Code, 0, len(names))
+	for _, name := range names {
+		signatures = append(signatures, methods[name])
+	}
+
+	f.Comment("restClientAPI is the part of the REST client called by the tools, implemented by")
+	f.Comment("the generated client and by mocks replacing it in tests")
+	f.Type().Id("restClientAPI").Interface(signatures...)
+}
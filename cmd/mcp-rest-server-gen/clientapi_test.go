@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCustomClientNaming(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("NameFilter") == "missing" {
+			respond(http.StatusNotFound, "text/plain", "no such book")(w, r)
+			return
+		}
+		respond(http.StatusOK, "application/json", `[{"Name":"Dune"}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	// The hand-written client is copied next to the server
+	dir, importPath := serverDir(t)
+	client, err := os.ReadFile("testdata/customclient/client.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "api", "client.go"), client, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parseFlags(t, "--spec", booksSpec, "--output", filepath.Join(dir, "main.go"), "--auth-type", "none",
+		"--client-import", importPath+"/api",
+		"--client-method-template", "Call{{.ID}}",
+		"--response-status-expr", "resp.Code",
+		"--response-status-text-expr", "resp.Status",
+		"--response-body-expr", "resp.Payload",
+	)
+	if err := generateMCPServer(); err != nil {
+		t.Fatal(err)
+	}
+	session := startServer(t, compileServer(t, dir), nil, "--host", upstream.URL)
+
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError || result.text() != `[{"Name":"Dune"}]` {
+		t.Errorf("ListBooks returned %+v", result)
+	}
+	result := session.callTool(t, "ListBooks", map[string]any{"NameFilter": "missing"})
+	if !result.IsError || !strings.Contains(result.text(), "404 Not Found: no such book") {
+		t.Errorf("the failed ListBooks returned %+v", result)
+	}
+}
+
+// mockClient is the mock of the REST client swapped into the server of
+// TestClientInterfaceMock, answering with the name filter of the call
+const mockClient = `package main
+
+import (
+	"context"
+	"net/http"
+
+	"%s/api"
+)
+
+type mockClient struct{}
+
+func (mockClient) AddBookWithResponse(ctx context.Context, body api.AddBookJSONRequestBody, reqEditors ...api.RequestEditorFn) (*api.AddBookResponse, error) {
+	return &api.AddBookResponse{HTTPResponse: &http.Response{StatusCode: http.StatusOK, Status: "200 OK"}}, nil
+}
+
+func (mockClient) ListBooksWithResponse(ctx context.Context, params *api.ListBooksParams, reqEditors ...api.RequestEditorFn) (*api.ListBooksResponse, error) {
+	return &api.ListBooksResponse{
+		Body:         []byte("mocked " + *params.NameFilter),
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK, Status: "200 OK"},
+	}, nil
+}
+`
+
+func TestClientInterfaceMock(t *testing.T) {
+	code := generate(t, booksSpec, "--with-client-interface")
+	assertContains(t, code, "var restAPI restClientAPI = restClient")
+	assertContains(t, code, "resp, err := restAPI.ListBooksWithResponse(serverCtx, &arguments)")
+
+	// The concrete client is replaced by the mock, no API is called
+	dir, importPath := serverDir(t)
+	parseFlags(t, "--spec", booksSpec, "--output", filepath.Join(dir, "main.go"), "--with-client-interface",
+		"--with-client", "--client-output-dir", filepath.Join(dir, "api"))
+	if err := generateMCPServer(); err != nil {
+		t.Fatal(err)
+	}
+	main, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	main = []byte(strings.Replace(string(main), "restClientAPI = restClient", "restClientAPI = mockClient{}\n\t_ = restClient", 1))
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), main, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mock.go"), []byte(fmt.Sprintf(mockClient, importPath)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := startServer(t, compileServer(t, dir), nil, "--host", "http://127.0.0.1:1")
+	if result := session.callTool(t, "ListBooks", map[string]any{"NameFilter": "Dune"}); result.IsError || result.text() != "mocked Dune" {
+		t.Errorf("the mocked ListBooks returned %+v", result)
+	}
+}
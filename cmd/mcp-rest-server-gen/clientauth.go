@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// clientAuth reports whether the generated server authenticates its MCP
+// clients, only the HTTP transports have requests to check
+func clientAuth() bool {
+	return CLI.ClientAuthToken && CLI.Transport != "stdio"
+}
+
+// clientAuthField returns the field of the generated server configuration
+// holding the token the MCP clients must send
+func clientAuthField() ConfigField {
+	return ConfigField{
+		Name: "ClientAuthToken", Type: jen.String(),
+		Tags: map[string]string{"help": "Bearer token the MCP clients must send in the Authorization header", "env": CLI.ClientAuthTokenEnv},
+	}
+}
+
+// clientAuthCheck returns the statements making the generated server exit at
+// startup without a client token, it would accept no client
+func clientAuthCheck() []jen.Code {
+	return []jen.Code{
+		jen.If(jen.Id("cli").Dot("ClientAuthToken").Op("==").Lit("")).Block(
+			jen.Qual("log", "Fatalf").Call(jen.Lit("missing MCP client auth token, set %s"), jen.Lit(CLI.ClientAuthTokenEnv)),
+		),
+	}
+}
+
+// addRequireClientToken adds the middleware rejecting the MCP requests that do
+// not carry the client token
+func addRequireClientToken(f *jen.File) {
+	f.Comment("requireClientToken rejects the MCP requests without the bearer token with a 401")
+	f.Comment("status, the tokens are compared in constant time")
+	f.Func().Id("requireClientToken").Params(
+		jen.Id("next").Qual("net/http", "Handler"),
+		jen.Id("token").String(),
+	).Qual("net/http", "Handler").Block(
+		jen.Return(jen.Qual("net/http", "HandlerFunc").Call(jen.Func().Params(
+			jen.Id("w").Qual("net/http", "ResponseWriter"),
+			jen.Id("r").Op("*").Qual("net/http", "Request"),
+		).Block(
+			jen.List(jen.Id("given"), jen.Id("ok")).Op(":=").Qual("strings", "CutPrefix").Call(
+				jen.Id("r").Dot("Header").Dot("Get").Call(jen.Lit("Authorization")), jen.Lit("Bearer "),
+			),
+			jen.If(jen.Op("!").Id("ok").Op("||").Qual("crypto/subtle", "ConstantTimeCompare").Call(
+				jen.Index().Byte().Call(jen.Id("given")), jen.Index().Byte().Call(jen.Id("token")),
+			).Op("!=").Lit(1)).Block(
+				jen.Id("w").Dot("Header").Call().Dot("Set").Call(jen.Lit("WWW-Authenticate"), jen.Lit("Bearer")),
+				jen.Qual("net/http", "Error").Call(jen.Id("w"), jen.Lit("unauthorized"), jen.Qual("net/http", "StatusUnauthorized")),
+				jen.Return(),
+			),
+			jen.Id("next").Dot("ServeHTTP").Call(jen.Id("w"), jen.Id("r")),
+		))),
+	)
+}
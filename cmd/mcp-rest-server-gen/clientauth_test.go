@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestClientAuthToken(t *testing.T) {
+	code := generate(t, booksSpec, "--mcp-library", "mark3labs", "--client-auth-token")
+	assertNotContains(t, code, "requireClientToken")
+	assertWarning(t, "--client-auth-token has no effect with the stdio transport")
+
+	binary := buildServer(t, booksSpec, "--mcp-library", "mark3labs", "--transport", "http", "--client-auth-token")
+
+	// The server accepts no client without a token
+	cmd := exec.Command(binary)
+	cmd.Env = append(os.Environ(), "API_USERNAME=user", "API_PASSWORD=secret", "MCP_CLIENT_AUTH_TOKEN=")
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "missing MCP client auth token, set MCP_CLIENT_AUTH_TOKEN") {
+		t.Errorf("the server without a client token exited with %v: %s", err, output)
+	}
+
+	session := startServer(t, binary, []string{"MCP_CLIENT_AUTH_TOKEN=s3cret"}, "--listen-addr", "127.0.0.1:0")
+	endpoint := "http://" + session.listenAddress(t) + "/mcp"
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+	for authorization, want := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Basic s3cret":  http.StatusUnauthorized,
+		"Bearer s3cret": http.StatusOK,
+	} {
+		req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(initialize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("the request with the authorization %q got %s, want %d", authorization, resp.Status, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/codegen"
+	"golang.org/x/mod/modfile"
+)
+
+// clientImportPath returns the import path of the client package generated in
+// the directory, its path relative to the module of the go.mod above it
+func clientImportPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := dir; ; root = filepath.Dir(root) {
+		content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			module := modfile.ModulePath(content)
+			if module == "" {
+				return "", fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+			}
+			relative, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", err
+			}
+			if relative == "." {
+				return module, nil
+			}
+			return module + "/" + filepath.ToSlash(relative), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("cannot derive the import path of %s, no go.mod found above it", dir)
+		}
+	}
+}
+
+// generateClient generates the REST client of the spec with the oapi-codegen
+// library as mcp-rest-client-gen does, from the spec loaded for the server
+// so the client methods match the tools. oapi-codegen prunes the spec it
+// generates from, so it is called once the server code is built.
+func generateClient(doc *openapi3.T, dir string) (string, error) {
+	config := codegen.Configuration{
+		PackageName: CLI.ClientPackage,
+		Generate: codegen.GenerateOptions{
+			Models: true,
+			Client: true,
+		},
+	}.UpdateDefaults()
+	if err := config.Validate(); err != nil {
+		return "", fmt.Errorf("invalid oapi-codegen configuration: %w", err)
+	}
+
+	// oapi-codegen takes the external references for other packages, the
+	// referenced schemas are moved into the components instead
+	doc.InternalizeRefs(context.Background(), nil)
+
+	code, err := codegen.Generate(doc, config)
+	if err != nil {
+		return "", fmt.Errorf("error generating the client: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating the client directory: %w", err)
+	}
+	path := filepath.Join(dir, "client.go")
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		return "", fmt.Errorf("error writing the client: %w", err)
+	}
+	return path, nil
+}
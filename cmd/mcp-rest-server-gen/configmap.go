@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// configMapScheme prefixes the spec paths referencing a ConfigMap key, as in
+// configmap://namespace/name/key
+const configMapScheme = "configmap://"
+
+// isConfigMapSpec reports whether the spec path references a ConfigMap key
+func isConfigMapSpec(specPath string) bool {
+	return strings.HasPrefix(specPath, configMapScheme)
+}
+
+// parseConfigMapRef returns the namespace, name and key of a ConfigMap spec path
+func parseConfigMapRef(specPath string) (string, string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(specPath, configMapScheme), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid ConfigMap reference %q, expected %snamespace/name/key", specPath, configMapScheme)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// newKubernetesClient creates a clientset from the kubeconfig, falling back to
+// the in-cluster config when there is none
+func newKubernetesClient() (kubernetes.Interface, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading Kubernetes config: %w", err)
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// readConfigMapSpec reads the spec content from the referenced ConfigMap key,
+// looking at the text data first and then at the binary data
+func readConfigMapSpec(ctx context.Context, client kubernetes.Interface, specPath string) ([]byte, error) {
+	namespace, name, key, err := parseConfigMapRef(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	if data, ok := configMap.Data[key]; ok {
+		return []byte(data), nil
+	}
+	if data, ok := configMap.BinaryData[key]; ok {
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("ConfigMap %s/%s has no key %s", namespace, name, key)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadConfigMapSpec(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apis", Name: "books"},
+			Data:       map[string]string{"openapi.yaml": "openapi: 3.0.1"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apis", Name: "archive"},
+			BinaryData: map[string][]byte{"openapi.json": []byte(`{"openapi":"3.0.1"}`)},
+		},
+	)
+
+	tests := []struct {
+		specPath, want, err string
+	}{
+		{"configmap://apis/books/openapi.yaml", "openapi: 3.0.1", ""},
+		{"configmap://apis/archive/openapi.json", `{"openapi":"3.0.1"}`, ""},
+		{"configmap://apis/books/swagger.yaml", "", "ConfigMap apis/books has no key swagger.yaml"},
+		{"configmap://apis/authors/openapi.yaml", "", "error getting ConfigMap apis/authors"},
+		{"configmap://apis/books", "", "expected configmap://namespace/name/key"},
+	}
+	for _, test := range tests {
+		content, err := readConfigMapSpec(context.Background(), client, test.specPath)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("reading %s failed with %v, want %q", test.specPath, err, test.err)
+			}
+			continue
+		}
+		if err != nil || string(content) != test.want {
+			t.Errorf("reading %s returned %q, %v, want %q", test.specPath, content, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// addConnectionType adds the type holding a REST client with its auth, and
+// the identity of its credentials when the results are cached
+func addConnectionType(f *jen.File, auth authScheme, cached bool) {
+	fields := []jen.Code{
+		jen.Id("restClient").Op("*").Qual(CLI.ClientImport, "ClientWithResponses"),
+		jen.Id(auth.Var).Op("*").Add(auth.providerType()),
+	}
+	if cached {
+		fields = append(fields, jen.Id("identity").String())
+	}
+	f.Comment("connection holds the REST client together with the auth it was built with")
+	f.Type().Id("connection").Struct(fields...)
+
+	arguments := make([]jen.Code, 0, len(auth.Credentials))
+	for _, field := range auth.Credentials {
+		arguments = append(arguments, jen.Id(field.Name).String().Tag(map[string]string{
+			"json":                   credentialParam(field),
+			"jsonschema":             "required",
+			"jsonschema_description": "New " + field.Tags["help"],
+		}))
+	}
+	f.Comment("CredentialToolArguments are the arguments of the credential tool")
+	f.Type().Id("CredentialToolArguments").Struct(arguments...)
+}
+
+// connectionCode returns the statements building the REST client through the
+// connect function, so it can be built again with other credentials, and
+// storing it as the active connection
+func connectionCode(auth authScheme, clientOptions []jen.Code, cached bool) []jen.Code {
+	params := auth.credentialArgs(func(field ConfigField) jen.Code { return jen.Id(credentialParam(field)) })
+	values := jen.Dict{
+		jen.Id("restClient"): jen.Id("restClient"),
+		jen.Id(auth.Var):     jen.Id(auth.Var),
+	}
+	if cached {
+		values[jen.Id("identity")] = jen.Id("credentialIdentity").Call(params...)
+	}
+	return []jen.Code{
+		jen.Id("connect").Op(":=").Func().Params(
+			jen.List(params...).String(),
+		).Params(jen.Op("*").Id("connection"), jen.Error()).Block(
+			jen.List(jen.Id(auth.Var), jen.Err()).Op(":=").Add(auth.providerCall(params)),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.List(jen.Id("restClient"), jen.Err()).Op(":=").Qual(CLI.ClientImport, "NewClientWithResponses").Call(clientOptions...),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.Return(jen.Op("&").Id("connection").Values(values), jen.Nil()),
+		),
+		jen.List(jen.Id("conn"), jen.Err()).Op(":=").Id("connect").Call(
+			auth.credentialArgs(func(field ConfigField) jen.Code { return jen.Id("cli").Dot(field.Name) })...,
+		),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("log", "Fatal").Call(jen.Err()),
+		),
+		jen.Var().Id("active").Qual("sync/atomic", "Pointer").Types(jen.Id("connection")),
+		jen.Id("active").Dot("Store").Call(jen.Id("conn")),
+	}
+}
+
+// connectionPrelude returns the statements loading the active connection at
+// the start of a handler, declaring only the variables used by the operation
+func connectionPrelude(op OperationInfo, auth authScheme, perOperationAuth bool) []jen.Code {
+	code := []jen.Code{
+		jen.Id("conn").Op(":=").Id("active").Dot("Load").Call(),
+		jen.Id("restClient").Op(":=").Id("conn").Dot("restClient"),
+	}
+	if perOperationAuth && op.RequiresAuth {
+		code = append(code, jen.Id(auth.Var).Op(":=").Id("conn").Dot(auth.Var))
+	}
+	if op.fetchesPages() {
+		code = append(code, jen.Id("baseClient").Op(":=").Id("restClient").Dot("ClientInterface").Assert(jen.Op("*").Qual(CLI.ClientImport, "Client")))
+	}
+	return code
+}
+
+// registerCredentialTool returns the statements registering the tool that
+// validates new credentials with a test call and swaps the active connection
+func registerCredentialTool(auth authScheme) []jen.Code {
+	return []jen.Code{
+		jen.If(jen.Id("cli").Dot("EnableCredentialTool")).Block(registerTool(
+			"server",
+			"UpdateCredentials",
+			"Replaces the credentials used to call the API, the new credentials are checked against the API before being used",
+			jen.Id("CredentialToolArguments"),
+			jen.Func().Params(
+				jen.Id("arguments").Id("CredentialToolArguments"),
+			).Params(toolResultType(), jen.Error()).Block(
+				jen.List(jen.Id("conn"), jen.Err()).Op(":=").Id("connect").Call(
+					auth.credentialArgs(func(field ConfigField) jen.Code { return jen.Id("arguments").Dot(field.Name) })...,
+				),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error building client: %v"), jen.Err())),
+				),
+
+				// Test the credentials against the API host before swapping
+				jen.List(jen.Id("req"), jen.Err()).Op(":=").Qual("net/http", "NewRequestWithContext").Call(
+					jen.Id("serverCtx"), jen.Qual("net/http", "MethodGet"), jen.Id("cli").Dot("Host"), jen.Nil(),
+				),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Err()),
+				),
+				jen.If(jen.Err().Op(":=").Id("conn").Dot(auth.Var).Dot("Intercept").Call(jen.Id("serverCtx"), jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Err()),
+				),
+				jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("conn").Dot("restClient").Dot("ClientInterface").Assert(jen.Op("*").Qual(CLI.ClientImport, "Client")).Dot("Client").Dot("Do").Call(jen.Id("req")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error checking credentials: %v"), jen.Err())),
+				),
+				jen.Id("resp").Dot("Body").Dot("Close").Call(),
+				jen.If(jen.Id("resp").Dot("StatusCode").Op("==").Qual("net/http", "StatusUnauthorized").Op("||").Id("resp").Dot("StatusCode").Op("==").Qual("net/http", "StatusForbidden")).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("credentials rejected by the API: %s"), jen.Id("resp").Dot("Status"))),
+				),
+
+				jen.Id("active").Dot("Store").Call(jen.Id("conn")),
+				jen.Return(toolResult(textContent(jen.Lit("Credentials updated"))), jen.Nil()),
+			),
+			nil,
+		)...),
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCredentialTool(t *testing.T) {
+	passwords := make(chan string, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		if password != "secret" && password != "rotated" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/ListBooks" {
+			passwords <- password
+		}
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--with-credential-tool")
+	session := startServer(t, binary, nil, "--host", upstream.URL, "--enable-credential-tool")
+	listBooks := func() string {
+		t.Helper()
+		if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+			t.Fatalf("ListBooks failed: %s", result.text())
+		}
+		return <-passwords
+	}
+	if got := listBooks(); got != "secret" {
+		t.Fatalf("the API was called with the password %q before the update", got)
+	}
+
+	// Rejected credentials leave the client unchanged
+	result := session.callTool(t, "UpdateCredentials", map[string]any{"username": "user", "password": "wrong"})
+	if !result.IsError || !strings.Contains(result.text(), "credentials rejected by the API: 401") {
+		t.Errorf("the rejected update returned %+v", result)
+	}
+	if got := listBooks(); got != "secret" {
+		t.Errorf("the API was called with the password %q after a rejected update", got)
+	}
+
+	result = session.callTool(t, "UpdateCredentials", map[string]any{"username": "user", "password": "rotated"})
+	if result.IsError || result.text() != "Credentials updated" {
+		t.Fatalf("the update returned %+v", result)
+	}
+	if got := listBooks(); got != "rotated" {
+		t.Errorf("the API was called with the password %q after the update, the client was not rebuilt", got)
+	}
+}
+
+func TestCredentialToolDisabled(t *testing.T) {
+	binary := buildServer(t, booksSpec, "--with-credential-tool")
+	if _, ok := startServer(t, binary, nil).listTools(t)["UpdateCredentials"]; ok {
+		t.Error("the credential tool is registered without being enabled")
+	}
+}
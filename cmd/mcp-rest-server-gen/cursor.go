@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// checkCursorParam reports a cursor parameter also adapted to offset and
+// limit, the two paginations cannot drive the same parameter
+func checkCursorParam() error {
+	if CLI.CursorParam != "" && CLI.CursorParam == CLI.PageTokenParam {
+		return fmt.Errorf("--cursor-param and --page-token-param both name the parameter %s, use one of them", CLI.CursorParam)
+	}
+	return nil
+}
+
+// cursorField returns the field of the 200 response holding the next page
+// cursor of a GET operation taking the cursor query parameter, the first of
+// the cursor fields declared by the response schema, empty when the
+// operation is not cursor paginated
+func cursorField(method string, operation *openapi3.Operation, parameters []ParameterInfo) string {
+	if CLI.CursorParam == "" || method != "GET" {
+		return ""
+	}
+
+	hasParam := false
+	for _, param := range parameters {
+		if param.In == openapi3.ParameterInQuery && param.Name == CLI.CursorParam {
+			hasParam = true
+		}
+	}
+	if !hasParam {
+		return ""
+	}
+
+	if hasJSONResponseSchema(operation) {
+		schema := operation.Responses.Status(200).Value.Content.Get("application/json").Schema.Value
+		for _, field := range CLI.CursorFields {
+			if _, ok := schema.Properties[field]; ok {
+				return field
+			}
+		}
+	}
+	warnf("the 200 response of %s declares none of the cursor fields, the tool takes no cursor", operation.OperationID)
+	return ""
+}
+
+// hasCursorArgument reports whether the tool takes a cursor argument set in
+// the cursor parameter, the parameter is itself the argument when it is named
+// cursor
+func (op OperationInfo) hasCursorArgument() bool {
+	return op.CursorField != "" && CLI.CursorParam != "cursor"
+}
+
+// cursorArgument returns the tool argument carrying the cursor of the page to
+// fetch, none when the tool takes no cursor argument
+func cursorArgument(op OperationInfo) []ConfigField {
+	if !op.hasCursorArgument() {
+		return nil
+	}
+	if op.hasParameter("cursor") {
+		warnf("parameter cursor of %s is shadowed by the cursor argument", op.ID)
+	}
+	return []ConfigField{{
+		Name: "Cursor", Type: jen.String(),
+		Tags: map[string]string{
+			"json":                   "cursor,omitempty",
+			"jsonschema_description": "Optional cursor of the page to fetch, as returned in the nextCursor of the previous page, the first page is fetched when empty",
+		},
+	}}
+}
+
+// addCursorParam adds the request editor sending the cursor argument in the
+// cursor query parameter
+func addCursorParam(f *jen.File) {
+	f.Comment("cursorParam returns a request editor setting the " + CLI.CursorParam + " query parameter to the")
+	f.Comment("cursor, the request is left unchanged when the cursor is empty")
+	f.Func().Id("cursorParam").Params(
+		jen.Id("cursor").String(),
+	).Qual(CLI.ClientImport, "RequestEditorFn").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).Error().Block(
+			jen.If(jen.Id("cursor").Op("!=").Lit("")).Block(
+				jen.Id("query").Op(":=").Id("req").Dot("URL").Dot("Query").Call(),
+				jen.Id("query").Dot("Set").Call(jen.Lit(CLI.CursorParam), jen.Id("cursor")),
+				jen.Id("req").Dot("URL").Dot("RawQuery").Op("=").Id("query").Dot("Encode").Call(),
+			),
+			jen.Return(jen.Nil()),
+		)),
+	)
+}
+
+// addCursorMeta adds the function surfacing the next page cursor of the
+// response as a separate content of the tool result
+func addCursorMeta(f *jen.File) {
+	f.Comment("cursorMeta returns a JSON object with the next page cursor read from the field of the")
+	f.Comment("response, empty on the last page, kept apart from the response body")
+	f.Func().Id("cursorMeta").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("field").String(),
+	).Add(contentType()).Block(
+		jen.Var().Id("envelope").Map(jen.String()).Qual("encoding/json", "RawMessage"),
+		jen.Var().Id("cursor").String(),
+		jen.If(jen.Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("envelope")).Op("==").Nil()).Block(
+			jen.Id("_").Op("=").Qual("encoding/json", "Unmarshal").Call(jen.Id("envelope").Index(jen.Id("field")), jen.Op("&").Id("cursor")),
+		),
+		jen.List(jen.Id("data"), jen.Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Map(jen.String()).Any().Values(jen.Dict{
+			jen.Lit("pagination"): jen.Map(jen.String()).Any().Values(jen.Dict{
+				jen.Lit("nextCursor"): jen.Id("cursor"),
+				jen.Lit("hasMore"):    jen.Id("cursor").Op("!=").Lit(""),
+			}),
+		})),
+		jen.Return(textContent(jen.String().Call(jen.Id("data")))),
+	)
+}
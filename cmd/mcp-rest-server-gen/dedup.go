@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// dedupField returns the runtime flag holding the deduplication window of the
+// mutating calls
+func dedupField() ConfigField {
+	return ConfigField{
+		Name: "DedupWindow", Type: jen.Qual("time", "Duration"),
+		Tags: map[string]string{"help": "Window within which a mutating tool call identical to a previous one returns the first result instead of calling the API again, 0 to disable", "default": CLI.DedupWindow.String()},
+	}
+}
+
+// dedupHandlerCode wraps the handler of a mutating operation so identical
+// calls within the window are answered with the first result
+func dedupHandlerCode(op OperationInfo, handler jen.Code) *jen.Statement {
+	return jen.Id("dedupHandler").Call(jen.Lit(op.ID), jen.Id("cli").Dot("DedupWindow"), handler)
+}
+
+// addDedupHandler adds the function deduplicating the tool calls, calls are
+// identical when their arguments, idempotency key parameters included, have
+// the same JSON encoding. Failed calls are forgotten once answered so they can
+// be retried.
+func addDedupHandler(f *jen.File) {
+	handlerType := handlerSignature(jen.Id("T"))
+
+	f.Comment("dedupCall is a tool call remembered for the deduplication window")
+	f.Type().Id("dedupCall").Struct(
+		jen.Id("done").Chan().Struct(),
+		jen.Id("finished").Qual("time", "Time"),
+		jen.Id("result").Add(toolResultType()),
+		jen.Err().Error(),
+	)
+
+	f.Comment("dedupHandler returns a handler answering the calls with the same arguments as a call")
+	f.Comment("in flight, or finished within the window, with the result of that call instead of")
+	f.Comment("calling the API again")
+	f.Func().Id("dedupHandler").Types(jen.Id("T").Any()).Params(
+		jen.Id("operation").String(),
+		jen.Id("window").Qual("time", "Duration"),
+		jen.Id("handler").Add(handlerType.Clone()),
+	).Add(handlerType.Clone()).Block(
+		jen.Var().Id("mu").Qual("sync", "Mutex"),
+		jen.Id("calls").Op(":=").Map(jen.String()).Op("*").Id("dedupCall").Values(),
+		jen.Return(jen.Func().Params(jen.Id("arguments").Id("T")).Params(toolResultType(), jen.Error()).Block(
+			jen.If(jen.Id("window").Op("<=").Lit(0)).Block(
+				jen.Return(jen.Id("handler").Call(jen.Id("arguments"))),
+			),
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("arguments")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Id("handler").Call(jen.Id("arguments"))),
+			),
+			jen.Id("sum").Op(":=").Qual("crypto/sha256", "Sum256").Call(jen.Id("data")),
+			jen.Id("key").Op(":=").Qual("encoding/hex", "EncodeToString").Call(jen.Id("sum").Index(jen.Empty(), jen.Empty())),
+
+			jen.Id("mu").Dot("Lock").Call(),
+			jen.Id("now").Op(":=").Qual("time", "Now").Call(),
+			jen.For(jen.List(jen.Id("k"), jen.Id("call")).Op(":=").Range().Id("calls")).Block(
+				jen.If(jen.Op("!").Id("call").Dot("finished").Dot("IsZero").Call().Op("&&").Id("now").Dot("Sub").Call(jen.Id("call").Dot("finished")).Op(">").Id("window")).Block(
+					jen.Delete(jen.Id("calls"), jen.Id("k")),
+				),
+			),
+			jen.If(jen.List(jen.Id("call"), jen.Id("ok")).Op(":=").Id("calls").Index(jen.Id("key")), jen.Id("ok")).Block(
+				jen.Id("mu").Dot("Unlock").Call(),
+				jen.Op("<-").Id("call").Dot("done"),
+				jen.Qual("log/slog", "Info").Call(jen.Lit("Duplicate call answered with the first result"), jen.Lit("operation"), jen.Id("operation")),
+				jen.Return(jen.Id("call").Dot("result"), jen.Id("call").Dot("err")),
+			),
+			jen.Id("call").Op(":=").Op("&").Id("dedupCall").Values(jen.Dict{
+				jen.Id("done"): jen.Make(jen.Chan().Struct()),
+			}),
+			jen.Id("calls").Index(jen.Id("key")).Op("=").Id("call"),
+			jen.Id("mu").Dot("Unlock").Call(),
+
+			jen.List(jen.Id("call").Dot("result"), jen.Id("call").Dot("err")).Op("=").Id("handler").Call(jen.Id("arguments")),
+			jen.Id("mu").Dot("Lock").Call(),
+			jen.Id("call").Dot("finished").Op("=").Qual("time", "Now").Call(),
+			jen.If(jen.Id("call").Dot("err").Op("!=").Nil()).Block(
+				jen.Delete(jen.Id("calls"), jen.Id("key")),
+			),
+			jen.Id("mu").Dot("Unlock").Call(),
+			jen.Close(jen.Id("call").Dot("done")),
+			jen.Return(jen.Id("call").Dot("result"), jen.Id("call").Dot("err")),
+		)),
+	)
+}
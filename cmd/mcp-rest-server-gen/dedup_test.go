@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDedupWindow(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		respond(http.StatusOK, "application/json", `{"Id":1,"Name":"Dune"}`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--dedup-window", "1m")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	for range 2 {
+		if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); result.IsError {
+			t.Fatalf("AddBook failed: %s", result.text())
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("two identical calls within the window called the API %d times, want once", got)
+	}
+
+	// Other arguments and reads are not deduplicated
+	session.callTool(t, "AddBook", map[string]any{"Name": "Emma"})
+	session.callTool(t, "ListBooks", map[string]any{})
+	session.callTool(t, "ListBooks", map[string]any{})
+	if got := calls.Load(); got != 4 {
+		t.Errorf("the API was called %d times, want 4", got)
+	}
+
+	session = startServer(t, binary, nil, "--host", upstream.URL, "--dedup-window", "0")
+	session.callTool(t, "AddBook", map[string]any{"Name": "Dune"})
+	session.callTool(t, "AddBook", map[string]any{"Name": "Dune"})
+	if got := calls.Load(); got != 6 {
+		t.Errorf("without window the API was called %d times, want 6", got)
+	}
+}
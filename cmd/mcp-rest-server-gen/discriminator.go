@@ -0,0 +1,157 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/codegen"
+)
+
+// BodyVariant holds a concrete type of a discriminated request body
+type BodyVariant struct {
+	// Value is the discriminator value selecting the variant
+	Value string
+	// Type is the name of the client type of the variant
+	Type string
+}
+
+// bodyDiscriminator returns the discriminator property and the variants of
+// the JSON request body when it is a oneOf or anyOf of referenced schemas
+// with a discriminator, the variants are named and mapped as oapi-codegen does
+func bodyDiscriminator(operation *openapi3.Operation) (string, []BodyVariant) {
+	schema := bodySchema(operation)
+	if schema == nil {
+		return "", nil
+	}
+
+	elements := schema.OneOf
+	if len(elements) == 0 {
+		elements = schema.AnyOf
+	}
+	if schema.Discriminator == nil || len(elements) == 0 {
+		return "", nil
+	}
+
+	// Explicit mappings are looked up in order so the values are stable
+	mappingValues := make([]string, 0, len(schema.Discriminator.Mapping))
+	for value := range schema.Discriminator.Mapping {
+		mappingValues = append(mappingValues, value)
+	}
+	sort.Strings(mappingValues)
+
+	variants := make([]BodyVariant, 0, len(elements))
+	for _, element := range elements {
+		if !strings.HasPrefix(element.Ref, "#/components/schemas/") {
+			warnf("discriminated body of %s has a variant that is not a component schema, the variants are not selected by %s", operation.OperationID, schema.Discriminator.PropertyName)
+			return "", nil
+		}
+		typeName := codegen.SchemaNameToTypeName(codegen.RefPathToObjName(element.Ref))
+
+		value := codegen.RefPathToObjName(element.Ref)
+		for _, mappingValue := range mappingValues {
+			if schema.Discriminator.Mapping[mappingValue] == element.Ref {
+				value = mappingValue
+				break
+			}
+		}
+		variants = append(variants, BodyVariant{Value: value, Type: typeName})
+	}
+
+	return schema.Discriminator.PropertyName, variants
+}
+
+// isInlineUnion reports whether the discriminated body of the operation is an
+// inline union. oapi-codegen gives the request body type of an inline union
+// no way to hold a variant, unlike the component unions it aliases, so the
+// selected variant is sent raw.
+func isInlineUnion(operation *openapi3.Operation) bool {
+	return operation.RequestBody.Value.Content.Get("application/json").Schema.Ref == ""
+}
+
+// variantArgument returns the name of the tool argument holding a variant
+func variantArgument(variant BodyVariant) string {
+	return strings.ToLower(variant.Type[:1]) + variant.Type[1:]
+}
+
+// variantFields returns the tool arguments of a discriminated body, the
+// discriminator value and an optional argument per variant
+func variantFields(op OperationInfo) []jen.Code {
+	values := make([]string, 0, len(op.BodyVariants))
+	for _, variant := range op.BodyVariants {
+		values = append(values, variant.Value)
+	}
+
+	json, schema := op.BodyDiscriminator, "required,enum="+strings.Join(values, ",enum=")
+	if !op.BodyRequired {
+		json, schema = op.BodyDiscriminator+",omitempty", "enum="+strings.Join(values, ",enum=")
+	}
+	fields := []jen.Code{
+		jen.Id("Discriminator").String().Tag(map[string]string{
+			"json":                   json,
+			"jsonschema":             schema,
+			"jsonschema_description": "Selects the body variant sent, the argument of the variant must be set",
+		}),
+	}
+	for _, variant := range op.BodyVariants {
+		fields = append(fields, jen.Id(variant.Type).Op("*").Qual(CLI.ClientImport, variant.Type).Tag(map[string]string{
+			"json":                   variantArgument(variant) + ",omitempty",
+			"jsonschema_description": "Body sent when " + op.BodyDiscriminator + " is " + variant.Value,
+		}))
+	}
+	return fields
+}
+
+// addUnionBody adds the method building the discriminated body of the
+// operation from the variant selected in the tool arguments
+func addUnionBody(f *jen.File, op OperationInfo) {
+	values := make([]string, 0, len(op.BodyVariants))
+	cases := make([]jen.Code, 0, len(op.BodyVariants))
+	for _, variant := range op.BodyVariants {
+		values = append(values, variant.Value)
+		argument := jen.Id("a").Dot(variant.Type)
+		set := []jen.Code{
+			jen.Err().Op(":=").Id("body").Dot("From" + variant.Type).Call(jen.Op("*").Add(argument)),
+			jen.Return(jen.Id("body"), jen.Err()),
+		}
+		if op.BodyInlineUnion {
+			set = []jen.Code{jen.Return(jen.Op("*").Add(argument), jen.Nil())}
+		}
+		cases = append(cases, jen.Case(jen.Lit(variant.Value)).Block(append([]jen.Code{
+			jen.If(argument.Clone().Op("==").Nil()).Block(
+				jen.Return(jen.Id("body"), jen.Qual("errors", "New").Call(jen.Lit(variantArgument(variant)+" is required when "+op.BodyDiscriminator+" is "+variant.Value))),
+			),
+		}, set...)...))
+	}
+
+	// The variant of an inline union is the body itself
+	bodyType := jen.Qual(CLI.ClientImport, op.ParameterType)
+	if op.BodyInlineUnion {
+		bodyType = jen.Any()
+	}
+
+	f.Comment("unionBody returns the " + op.ID + " body holding the variant selected by " + op.BodyDiscriminator)
+	f.Func().Params(jen.Id("a").Id(argumentsTypeName(op))).Id("unionBody").Params().Params(
+		bodyType.Clone(),
+		jen.Error(),
+	).Block(
+		jen.Var().Id("body").Add(bodyType),
+		jen.Switch(jen.Id("a").Dot("Discriminator")).Block(cases...),
+		jen.Return(jen.Id("body"), jen.Qual("fmt", "Errorf").Call(
+			jen.Lit("unknown "+op.BodyDiscriminator+" %q, expected one of "+strings.Join(values, ", ")),
+			jen.Id("a").Dot("Discriminator"),
+		)),
+	)
+}
+
+// unionBodyCode returns the statements building the discriminated body of
+// the operation into the unionBody variable
+func unionBodyCode(op OperationInfo) []jen.Code {
+	return []jen.Code{
+		jen.List(jen.Id("unionBody"), jen.Err()).Op(":=").Id("arguments").Dot("unionBody").Call(),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid "+op.ID+" body: %v"), jen.Err())),
+		),
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscriminatedBody(t *testing.T) {
+	bodies := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- r.Method + " " + string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/pets.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The inline union of AddPet and the component union of ReplacePet both
+	// send the variant selected by the discriminator
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"AddPet", map[string]any{"petType": "cat", "cat": map[string]any{"petType": "cat", "name": "Tom", "indoor": true}}, `POST {"indoor":true,"name":"Tom","petType":"cat"}`},
+		{"AddPet", map[string]any{"petType": "dog", "dog": map[string]any{"petType": "dog", "name": "Rex", "breed": "collie"}}, `POST {"breed":"collie","name":"Rex","petType":"dog"}`},
+		{"ReplacePet", map[string]any{"petType": "cat", "cat": map[string]any{"petType": "cat", "name": "Tom"}}, `PUT {"name":"Tom","petType":"cat"}`},
+		{"ReplacePet", map[string]any{"petType": "dog", "dog": map[string]any{"petType": "dog", "breed": "collie"}}, `PUT {"breed":"collie","petType":"dog"}`},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, test.tool, test.args); result.IsError {
+			t.Fatalf("%s with %v failed: %s", test.tool, test.args, result.text())
+		}
+		if got := <-bodies; got != test.want {
+			t.Errorf("%s with %v sent %s, want %s", test.tool, test.args, got, test.want)
+		}
+	}
+
+	// The argument of the selected variant must be set
+	if result := session.callTool(t, "AddPet", map[string]any{"petType": "dog", "cat": map[string]any{"petType": "cat"}}); !result.IsError || !strings.Contains(result.text(), "invalid AddPet body: dog is required when petType is dog") {
+		t.Errorf("AddPet without the dog argument returned %+v", result)
+	}
+}
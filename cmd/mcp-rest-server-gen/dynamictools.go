@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// addToolSet adds the type registering the operation tools on the server and
+// letting them be disabled and enabled again at runtime, the server notifies
+// the clients of each change of the tool list
+func addToolSet(f *jen.File) {
+	f.Comment("toolSet registers the operation tools on the server, keeping them so they can be")
+	f.Comment("disabled and enabled again at runtime")
+	f.Type().Id("toolSet").Struct(
+		jen.Id("mu").Qual("sync", "Mutex"),
+		jen.Id("server").Op("*").Qual("github.com/metoro-io/mcp-golang", "Server"),
+		jen.Id("descriptions").Map(jen.String()).String(),
+		jen.Id("handlers").Map(jen.String()).Any(),
+		jen.Id("disabled").Map(jen.String()).Bool(),
+	)
+
+	f.Comment("newToolSet returns a tool set registering the tools on the server but the disabled ones")
+	f.Func().Id("newToolSet").Params(
+		jen.Id("server").Op("*").Qual("github.com/metoro-io/mcp-golang", "Server"),
+		jen.Id("disabled").Index().String(),
+	).Op("*").Id("toolSet").Block(
+		jen.Id("tools").Op(":=").Op("&").Id("toolSet").Values(jen.Dict{
+			jen.Id("server"):       jen.Id("server"),
+			jen.Id("descriptions"): jen.Map(jen.String()).String().Values(),
+			jen.Id("handlers"):     jen.Map(jen.String()).Any().Values(),
+			jen.Id("disabled"):     jen.Map(jen.String()).Bool().Values(),
+		}),
+		jen.For(jen.List(jen.Id("_"), jen.Id("name")).Op(":=").Range().Id("disabled")).Block(
+			jen.Id("tools").Dot("disabled").Index(jen.Id("name")).Op("=").True(),
+		),
+		jen.Return(jen.Id("tools")),
+	)
+
+	f.Comment("RegisterTool keeps the tool and registers it on the server unless it is disabled,")
+	f.Comment("registering a known tool again replaces its description and handler")
+	f.Func().Params(jen.Id("t").Op("*").Id("toolSet")).Id("RegisterTool").Params(
+		jen.List(jen.Id("name"), jen.Id("description")).String(),
+		jen.Id("handler").Any(),
+	).Error().Block(
+		jen.Id("t").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("t").Dot("mu").Dot("Unlock").Call(),
+		jen.Id("t").Dot("descriptions").Index(jen.Id("name")).Op("=").Id("description"),
+		jen.Id("t").Dot("handlers").Index(jen.Id("name")).Op("=").Id("handler"),
+		jen.If(jen.Id("t").Dot("disabled").Index(jen.Id("name"))).Block(
+			jen.Return(jen.Nil()),
+		),
+		jen.Return(jen.Id("t").Dot("server").Dot("RegisterTool").Call(jen.Id("name"), jen.Id("description"), jen.Id("handler"))),
+	)
+
+	f.Comment("isDisabled reports whether the tool is disabled")
+	f.Func().Params(jen.Id("t").Op("*").Id("toolSet")).Id("isDisabled").Params(
+		jen.Id("name").String(),
+	).Bool().Block(
+		jen.Id("t").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("t").Dot("mu").Dot("Unlock").Call(),
+		jen.Return(jen.Id("t").Dot("disabled").Index(jen.Id("name"))),
+	)
+
+	f.Comment("setEnabled registers or deregisters a known tool, the server notifies the clients")
+	f.Comment("that the tool list changed")
+	f.Func().Params(jen.Id("t").Op("*").Id("toolSet")).Id("setEnabled").Params(
+		jen.Id("name").String(),
+		jen.Id("enabled").Bool(),
+	).Error().Block(
+		jen.Id("t").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("t").Dot("mu").Dot("Unlock").Call(),
+		jen.List(jen.Id("handler"), jen.Id("ok")).Op(":=").Id("t").Dot("handlers").Index(jen.Id("name")),
+		jen.If(jen.Op("!").Id("ok")).Block(
+			jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("unknown tool %s"), jen.Id("name"))),
+		),
+		jen.If(jen.Id("enabled").Op("!=").Id("t").Dot("disabled").Index(jen.Id("name"))).Block(
+			jen.Return(jen.Nil()),
+		),
+		jen.If(jen.Op("!").Id("enabled")).Block(
+			jen.Id("t").Dot("disabled").Index(jen.Id("name")).Op("=").True(),
+			jen.Return(jen.Id("t").Dot("server").Dot("DeregisterTool").Call(jen.Id("name"))),
+		),
+		jen.Delete(jen.Id("t").Dot("disabled"), jen.Id("name")),
+		jen.Return(jen.Id("t").Dot("server").Dot("RegisterTool").Call(jen.Id("name"), jen.Id("t").Dot("descriptions").Index(jen.Id("name")), jen.Id("handler"))),
+	)
+
+	f.Comment("ToolEnabledArguments are the arguments of the tool admin tool")
+	f.Type().Id("ToolEnabledArguments").Struct(
+		jen.Id("Tool").String().Tag(map[string]string{"json": "tool", "jsonschema": "required", "jsonschema_description": "Name of the tool to enable or disable"}),
+		jen.Id("Enabled").Bool().Tag(map[string]string{"json": "enabled", "jsonschema_description": "True to expose the tool, false to hide it"}),
+	)
+}
+
+// registerToolAdmin returns the statements registering the tool that enables
+// and disables the operation tools, when enabled at startup
+func registerToolAdmin() []jen.Code {
+	return []jen.Code{
+		jen.If(jen.Id("cli").Dot("EnableToolAdmin")).Block(
+			jen.Err().Op("=").Id("server").Dot("RegisterTool").Call(
+				jen.Lit("SetToolEnabled"),
+				jen.Lit("Enables or disables one of the API tools, the clients are notified that the tool list changed"),
+				jen.Func().Params(
+					jen.Id("arguments").Id("ToolEnabledArguments"),
+				).Params(
+					jen.Op("*").Qual("github.com/metoro-io/mcp-golang", "ToolResponse"),
+					jen.Error(),
+				).Block(
+					jen.If(jen.Err().Op(":=").Id("tools").Dot("setEnabled").Call(jen.Id("arguments").Dot("Tool"), jen.Id("arguments").Dot("Enabled")), jen.Err().Op("!=").Nil()).Block(
+						jen.Return(jen.Nil(), jen.Err()),
+					),
+					jen.Id("state").Op(":=").Lit("disabled"),
+					jen.If(jen.Id("arguments").Dot("Enabled")).Block(
+						jen.Id("state").Op("=").Lit("enabled"),
+					),
+					jen.Qual("log/slog", "Info").Call(jen.Lit("Tool availability changed"), jen.Lit("tool"), jen.Id("arguments").Dot("Tool"), jen.Lit("state"), jen.Id("state")),
+					jen.Return(
+						jen.Qual("github.com/metoro-io/mcp-golang", "NewToolResponse").Call(
+							jen.Qual("github.com/metoro-io/mcp-golang", "NewTextContent").Call(jen.Id("arguments").Dot("Tool").Op("+").Lit(" ").Op("+").Id("state")),
+						),
+						jen.Nil(),
+					),
+				),
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Panic(jen.Err()),
+			),
+		),
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// listChanged counts the notifications that the tool list changed
+func listChanged(notifications []map[string]json.RawMessage) int {
+	count := 0
+	for _, notification := range notifications {
+		if string(notification["method"]) == `"notifications/tools/list_changed"` {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDynamicTools(t *testing.T) {
+	code := generate(t, booksSpec)
+	assertNotContains(t, code, "toolSet", "SetToolEnabled")
+
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", `[]`))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--dynamic-tools")
+	session := startServer(t, binary, nil, "--host", upstream.URL, "--disabled-tools", "AddBook", "--enable-tool-admin")
+	tools := session.listTools(t)
+	if _, ok := tools["AddBook"]; ok {
+		t.Error("the disabled AddBook tool is listed")
+	}
+	if _, ok := tools["SetToolEnabled"]; !ok {
+		t.Fatal("the tool admin tool is not listed")
+	}
+
+	// Disabling a tool deregisters it and notifies the client
+	data, notifications := session.request(t, "tools/call", map[string]any{
+		"name": "SetToolEnabled", "arguments": map[string]any{"tool": "ListBooks", "enabled": false},
+	})
+	var result callResult
+	if err := json.Unmarshal(data, &result); err != nil || result.IsError || result.text() != "ListBooks disabled" {
+		t.Fatalf("disabling ListBooks returned %s: %v", data, err)
+	}
+	if got := listChanged(notifications); got != 1 {
+		t.Errorf("disabling ListBooks sent %d list_changed notifications, want 1", got)
+	}
+	if _, ok := session.listTools(t)["ListBooks"]; ok {
+		t.Error("the disabled ListBooks tool is listed")
+	}
+
+	_, notifications = session.request(t, "tools/call", map[string]any{
+		"name": "SetToolEnabled", "arguments": map[string]any{"tool": "AddBook", "enabled": true},
+	})
+	if got := listChanged(notifications); got != 1 {
+		t.Errorf("enabling AddBook sent %d list_changed notifications, want 1", got)
+	}
+	if _, ok := session.listTools(t)["AddBook"]; !ok {
+		t.Error("the enabled AddBook tool is not listed")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// argumentEnums returns the allowed values of the enum-constrained tool
+// arguments, by argument name: the top-level properties of object bodies for
+// operations with a body, the query, header and cookie parameters otherwise
+func argumentEnums(operation *openapi3.Operation, parameters []ParameterInfo) map[string][]string {
+	enums := make(map[string][]string)
+
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+		mediaType := operation.RequestBody.Value.Content.Get("application/json")
+		if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+			return nil
+		}
+		for name, property := range mediaType.Schema.Value.Properties {
+			if property == nil {
+				continue
+			}
+			if values := enumValues(property.Value); len(values) > 0 {
+				enums[name] = values
+			}
+		}
+	} else {
+		for _, param := range parameters {
+			if param.In == openapi3.ParameterInPath || param.ContentType != "" {
+				continue
+			}
+			if values := enumValues(param.Schema); len(values) > 0 {
+				enums[param.Name] = values
+			}
+		}
+	}
+
+	if len(enums) == 0 {
+		return nil
+	}
+	return enums
+}
+
+// enumValues returns the enum values of the schema, or of its items for
+// arrays, formatted as the JSON values decoded from the arguments are
+func enumValues(schema *openapi3.Schema) []string {
+	if schema == nil {
+		return nil
+	}
+	if schema.Type.Is(openapi3.TypeArray) && schema.Items != nil {
+		schema = schema.Items.Value
+		if schema == nil {
+			return nil
+		}
+	}
+
+	values := make([]string, 0, len(schema.Enum))
+	for _, value := range schema.Enum {
+		if value != nil {
+			values = append(values, fmt.Sprint(value))
+		}
+	}
+	return values
+}
+
+// enumsCode returns the map literal of the allowed values of the operation
+// arguments, by argument name
+func enumsCode(op OperationInfo) jen.Code {
+	names := make([]string, 0, len(op.Enums))
+	for name := range op.Enums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := jen.Dict{}
+	for _, name := range names {
+		items := make([]jen.Code, 0, len(op.Enums[name]))
+		for _, value := range op.Enums[name] {
+			items = append(items, jen.Lit(value))
+		}
+		values[jen.Lit(name)] = jen.Values(items...)
+	}
+	return jen.Map(jen.String()).Index().String().Values(values)
+}
+
+// enumCheckCode returns the statements rejecting the call when an argument
+// holds a value outside its enum, before anything is sent
+func enumCheckCode(op OperationInfo) []jen.Code {
+	return []jen.Code{
+		jen.If(jen.Err().Op(":=").Id("checkEnums").Call(jen.Id("arguments"), enumsCode(op)), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid "+op.ID+" arguments: %v"), jen.Err())),
+		),
+	}
+}
+
+// addCheckEnums adds the function checking the arguments against the enums of
+// their schema
+func addCheckEnums(f *jen.File) {
+	f.Comment("checkEnums returns an error listing the valid values of the first argument, by name, holding")
+	f.Comment("a value outside its enum, array arguments are checked item by item")
+	f.Func().Id("checkEnums").Params(
+		jen.Id("arguments").Any(),
+		jen.Id("enums").Map(jen.String()).Index().String(),
+	).Error().Block(
+		jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("arguments")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Err()),
+		),
+		jen.Var().Id("values").Map(jen.String()).Any(),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("values")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Err()),
+		),
+		jen.Id("names").Op(":=").Make(jen.Index().String(), jen.Lit(0), jen.Len(jen.Id("enums"))),
+		jen.For(jen.Id("name").Op(":=").Range().Id("enums")).Block(
+			jen.Id("names").Op("=").Append(jen.Id("names"), jen.Id("name")),
+		),
+		jen.Qual("sort", "Strings").Call(jen.Id("names")),
+		jen.For(jen.List(jen.Id("_"), jen.Id("name")).Op(":=").Range().Id("names")).Block(
+			jen.List(jen.Id("items"), jen.Id("isArray")).Op(":=").Id("values").Index(jen.Id("name")).Assert(jen.Index().Any()),
+			jen.If(jen.Op("!").Id("isArray")).Block(
+				jen.Id("items").Op("=").Index().Any().Values(jen.Id("values").Index(jen.Id("name"))),
+			),
+			jen.For(jen.List(jen.Id("_"), jen.Id("item")).Op(":=").Range().Id("items")).Block(
+				jen.If(jen.Id("item").Op("!=").Nil().Op("&&").Op("!").Qual("slices", "Contains").Call(jen.Id("enums").Index(jen.Id("name")), jen.Qual("fmt", "Sprint").Call(jen.Id("item")))).Block(
+					jen.Return(jen.Qual("fmt", "Errorf").Call(
+						jen.Lit("invalid value %v for %s, valid values are %s"),
+						jen.Id("item"), jen.Id("name"), jen.Qual("strings", "Join").Call(jen.Id("enums").Index(jen.Id("name")), jen.Lit(", ")),
+					)),
+				),
+			),
+		),
+		jen.Return(jen.Nil()),
+	)
+}
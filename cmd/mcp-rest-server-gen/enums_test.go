@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnforceEnums(t *testing.T) {
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/params.yaml", "--auth-type", "none", "--enforce-enums")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		arguments map[string]any
+		want      string
+	}{
+		{map[string]any{"q": "dune", "status": "sold"}, "invalid ListBooks arguments: invalid value sold for status, valid values are available, lent"},
+		{map[string]any{"q": "dune", "tags": []string{"novel", "essay"}}, "invalid ListBooks arguments: invalid value essay for tags, valid values are novel, poetry"},
+	}
+	for _, test := range tests {
+		result := session.callTool(t, "ListBooks", test.arguments)
+		if !result.IsError || !strings.HasSuffix(result.text(), test.want) {
+			t.Errorf("ListBooks with %v returned %+v, want the error %q", test.arguments, result, test.want)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("the invalid calls reached the API %d times", calls)
+	}
+
+	if result := session.callTool(t, "ListBooks", map[string]any{"q": "dune", "status": "lent", "tags": []string{"poetry"}}); result.IsError {
+		t.Errorf("ListBooks with valid values failed: %s", result.text())
+	}
+}
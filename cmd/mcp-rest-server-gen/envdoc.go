@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// writeEnvDoc writes a markdown reference of the environment variables read
+// by the generated server, derived from the fields of its configuration
+func writeEnvDoc(path string, fields []ConfigField) error {
+	var b strings.Builder
+	b.WriteString("# Environment\n\n")
+	b.WriteString("Environment variables read by the generated MCP server.\n\n")
+	b.WriteString("| Variable | Flag | Default | Description |\n")
+	b.WriteString("|----------|------|---------|-------------|\n")
+
+	for _, field := range fields {
+		env := field.Tags["env"]
+		if env == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "| `%s` | `--%s` | %s | %s |\n",
+			env, flagName(field.Name), markdownCode(field.Tags["default"]), field.Tags["help"])
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// flagName returns the flag kong derives from a configuration field name
+func flagName(fieldName string) string {
+	var b strings.Builder
+	for i, r := range fieldName {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// markdownCode wraps a value in backticks, leaving empty values blank
+func markdownCode(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "`" + value + "`"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestEnvDocMatchesConfigFields(t *testing.T) {
+	dir := t.TempDir()
+	parseFlags(t, "--spec", booksSpec, "--output", filepath.Join(dir, "main.go"), "--emit-env-doc", "--accept-language", "en")
+	if err := generateMCPServer(); err != nil {
+		t.Fatal(err)
+	}
+	code, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := os.ReadFile(filepath.Join(dir, "ENVIRONMENT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fieldEnvs := findAll(t, `env:"([A-Z_]+)"`, string(code))
+	docEnvs := findAll(t, "(?m)^\\| `([A-Z_]+)` \\|", string(doc))
+	if len(fieldEnvs) == 0 {
+		t.Fatal("the generated configuration has no environment variables")
+	}
+	if !slices.Equal(fieldEnvs, docEnvs) {
+		t.Errorf("the documented variables are %v, the configuration reads %v", docEnvs, fieldEnvs)
+	}
+	assertContains(t, string(doc), "| `API_ACCEPT_LANGUAGE` | `--accept-language` | `en` |")
+}
+
+func TestFlagName(t *testing.T) {
+	for field, want := range map[string]string{"Host": "host", "AcceptLanguage": "accept-language", "SpecFile": "spec-file"} {
+		if got := flagName(field); got != want {
+			t.Errorf("flagName(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+// findAll returns the sorted first groups of the matches of the pattern
+func findAll(t *testing.T, pattern, text string) []string {
+	t.Helper()
+	var values []string
+	for _, match := range regexp.MustCompile(pattern).FindAllStringSubmatch(text, -1) {
+		values = append(values, match[1])
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// isFreeFormBody reports whether the JSON request body of the operation has
+// no schema, in which case the client types it as an empty interface
+func isFreeFormBody(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+
+	mediaType := operation.RequestBody.Value.Content.Get("application/json")
+	if mediaType == nil {
+		return false
+	}
+	return mediaType.Schema == nil || mediaType.Schema.Value == nil || mediaType.Schema.Value.IsEmpty()
+}
+
+// bodyExample returns the JSON of the example of the JSON request body, its
+// example or else the first of its named examples by name, empty when none
+func bodyExample(operation *openapi3.Operation) string {
+	mediaType := operation.RequestBody.Value.Content.Get("application/json")
+	example := mediaType.Example
+	if example == nil {
+		names := make([]string, 0, len(mediaType.Examples))
+		for name := range mediaType.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ref := mediaType.Examples[name]; ref != nil && ref.Value != nil && ref.Value.Value != nil {
+				example = ref.Value.Value
+				break
+			}
+		}
+	}
+	if example == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(example)
+	if err != nil {
+		warnf("example body of %s is not valid JSON: %v", operation.OperationID, err)
+		return ""
+	}
+	return string(data)
+}
+
+// freeFormBodyField returns the tool argument holding a free-form body as
+// raw JSON, documented with the example of the body when there is one
+func freeFormBodyField(op OperationInfo) jen.Code {
+	description := "Request body, any JSON value"
+	if op.BodyExample != "" {
+		description += ", for example " + op.BodyExample
+	}
+
+	tags := map[string]string{"json": "body", "jsonschema": "required", "jsonschema_description": description}
+	if !op.BodyRequired {
+		tags = map[string]string{"json": "body,omitempty", "jsonschema_description": description}
+	}
+	return jen.Id("Body").Qual("encoding/json", "RawMessage").Tag(tags)
+}
+
+// freeFormBodyCode returns the statements sending the raw JSON of a free-form
+// body through the requestBody bytes, left empty when an optional body is
+// absent
+func freeFormBodyCode(op OperationInfo) []jen.Code {
+	body := jen.Id("arguments").Dot("Body")
+	if op.BodyRequired {
+		return []jen.Code{
+			jen.If(jen.Len(body.Clone()).Op("==").Lit(0)).Block(
+				jen.Return(jen.Nil(), jen.Qual("errors", "New").Call(jen.Lit("the body argument is required"))),
+			),
+			jen.Id("requestBody").Op(":=").Index().Byte().Call(body),
+		}
+	}
+	return []jen.Code{
+		jen.Var().Id("requestBody").Index().Byte(),
+		jen.If(jen.Len(body.Clone()).Op(">").Lit(0)).Block(
+			jen.Id("requestBody").Op("=").Add(body.Clone()),
+		),
+	}
+}
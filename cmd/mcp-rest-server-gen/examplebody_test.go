@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFreeFormBody(t *testing.T) {
+	code := generate(t, "testdata/freeform.yaml", "--auth-type", "none")
+	assertContains(t, code,
+		`Body json.RawMessage `+"`"+`json:"body" jsonschema:"required" jsonschema_description:"Request body, any JSON value, for example {\"tags\":[\"books\"],\"text\":\"Read Dune\"}"`,
+		`Body json.RawMessage `+"`"+`json:"body,omitempty" jsonschema_description:"Request body, any JSON value, for example [{\"text\":\"Read Dune\"}]"`,
+	)
+
+	bodies := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- r.Method + " " + string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/freeform.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The body is sent as given, large integers included
+	body := map[string]any{"text": "Read Dune", "pages": map[string]any{"count": 9007199254740993}}
+	if result := session.callTool(t, "AddNote", map[string]any{"body": body}); result.IsError {
+		t.Fatalf("AddNote failed: %s", result.text())
+	}
+	if got, want := <-bodies, `POST {"pages":{"count":9007199254740993},"text":"Read Dune"}`; got != want {
+		t.Errorf("AddNote sent %s, want %s", got, want)
+	}
+
+	// An absent optional body sends nothing
+	if result := session.callTool(t, "ReplaceNotes", map[string]any{}); result.IsError {
+		t.Fatalf("ReplaceNotes failed: %s", result.text())
+	}
+	if got, want := <-bodies, "PUT "; got != want {
+		t.Errorf("ReplaceNotes without a body sent %q, want %q", got, want)
+	}
+}
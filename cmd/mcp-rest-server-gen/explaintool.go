@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// secretHeaders returns the canonical names of the headers whose values are
+// not shown by the explain tool, the standard credential headers and the
+// API key headers of the auth and of the spec security schemes
+func secretHeaders(doc *openapi3.T) []string {
+	names := map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+		"Cookie":              true,
+	}
+	if CLI.AuthType == "apikey" {
+		names[http.CanonicalHeaderKey(CLI.APIKeyName)] = true
+	}
+	if doc.Components != nil {
+		for _, scheme := range doc.Components.SecuritySchemes {
+			if scheme != nil && scheme.Value != nil && scheme.Value.Type == "apiKey" && scheme.Value.In == openapi3.ParameterInHeader {
+				names[http.CanonicalHeaderKey(scheme.Value.Name)] = true
+			}
+		}
+	}
+
+	headers := make([]string, 0, len(names))
+	for name := range names {
+		headers = append(headers, name)
+	}
+	sort.Strings(headers)
+	return headers
+}
+
+// explainerCode returns the function building the request of the operation
+// from the JSON arguments of its tool, as the tool handler would send it.
+// The steps are the statements preparing the builder arguments.
+func explainerCode(op OperationInfo, argsType jen.Code, steps []jen.Code, builder string, builderArgs, reqEditors []jen.Code) jen.Code {
+	body := []jen.Code{
+		jen.Var().Id("arguments").Add(argsType),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("arguments")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid "+op.ID+" arguments: %v"), jen.Err())),
+		),
+	}
+	body = append(body, steps...)
+	body = append(body,
+		jen.List(jen.Id("req"), jen.Err()).Op(":=").Qual(CLI.ClientImport, builder).Call(
+			append([]jen.Code{jen.Id("baseClient").Dot("Server")}, builderArgs...)...,
+		),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.Return(jen.Id("req"), jen.Id("editRequest").Call(
+			append([]jen.Code{jen.Id("serverCtx"), jen.Id("req"), jen.Id("baseClient").Dot("RequestEditors")}, reqEditors...)...,
+		)),
+	)
+
+	return jen.Id("explainers").Index(jen.Lit(op.ID)).Op("=").Func().Params(
+		jen.Id("data").Index().Byte(),
+	).Params(jen.Op("*").Qual("net/http", "Request"), jen.Error()).Block(body...)
+}
+
+// addExplainTool adds the types and functions of the tool describing the
+// request an operation would send
+func addExplainTool(f *jen.File, doc *openapi3.T) {
+	f.Comment("ExplainToolArguments are the arguments of the explain tool")
+	f.Type().Id("ExplainToolArguments").Struct(
+		jen.Id("Operation").String().Tag(map[string]string{
+			"json":                   "operation",
+			"jsonschema":             "required",
+			"jsonschema_description": "ID of the operation, the name of its tool",
+		}),
+		jen.Id("Arguments").Map(jen.String()).Any().Tag(map[string]string{
+			"json":                   "arguments,omitempty",
+			"jsonschema_description": "Arguments of the operation tool, as they would be passed to it",
+		}),
+	)
+
+	f.Comment("explainer builds the request of an operation from the JSON arguments of its tool")
+	f.Type().Id("explainer").Func().Params(jen.Id("data").Index().Byte()).Params(jen.Op("*").Qual("net/http", "Request"), jen.Error())
+
+	secrets := jen.Dict{}
+	for _, name := range secretHeaders(doc) {
+		secrets[jen.Lit(name)] = jen.True()
+	}
+	f.Comment("secretHeaders are the headers whose values are not shown by the explain tool")
+	f.Var().Id("secretHeaders").Op("=").Map(jen.String()).Bool().Values(secrets)
+
+	f.Comment("editRequest applies the request editors of the client, then those of the operation")
+	f.Func().Id("editRequest").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+		jen.Id("clientEditors").Index().Qual(CLI.ClientImport, "RequestEditorFn"),
+		jen.Id("editors").Op("...").Qual(CLI.ClientImport, "RequestEditorFn"),
+	).Error().Block(
+		jen.For(jen.List(jen.Id("_"), jen.Id("editor")).Op(":=").Range().Append(jen.Id("clientEditors").Index(jen.Empty(), jen.Len(jen.Id("clientEditors")), jen.Len(jen.Id("clientEditors"))), jen.Id("editors").Op("..."))).Block(
+			jen.If(jen.Err().Op(":=").Id("editor").Call(jen.Id("ctx"), jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+		),
+		jen.Return(jen.Nil()),
+	)
+
+	f.Comment("explainRequest describes the method, URL, headers and body of the request, the")
+	f.Comment("values of the secret headers are redacted")
+	f.Func().Id("explainRequest").Params(
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Params(jen.String(), jen.Error()).Block(
+		jen.Var().Id("body").Index().Byte(),
+		jen.If(jen.Id("req").Dot("Body").Op("!=").Nil()).Block(
+			jen.Var().Err().Error(),
+			jen.If(jen.List(jen.Id("body"), jen.Err()).Op("=").Qual("io", "ReadAll").Call(jen.Id("req").Dot("Body")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Lit(""), jen.Err()),
+			),
+		),
+		jen.Id("headers").Op(":=").Make(jen.Map(jen.String()).String(), jen.Len(jen.Id("req").Dot("Header"))),
+		jen.For(jen.List(jen.Id("name"), jen.Id("values")).Op(":=").Range().Id("req").Dot("Header")).Block(
+			jen.Id("headers").Index(jen.Id("name")).Op("=").Qual("strings", "Join").Call(jen.Id("values"), jen.Lit(", ")),
+			jen.If(jen.Id("secretHeaders").Index(jen.Qual("net/http", "CanonicalHeaderKey").Call(jen.Id("name")))).Block(
+				jen.Id("headers").Index(jen.Id("name")).Op("=").Lit("[redacted]"),
+			),
+		),
+		jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "MarshalIndent").Call(jen.Map(jen.String()).Any().Values(jen.Dict{
+			jen.Lit("method"):  jen.Id("req").Dot("Method"),
+			jen.Lit("url"):     jen.Id("req").Dot("URL").Dot("String").Call(),
+			jen.Lit("headers"): jen.Id("headers"),
+			jen.Lit("body"):    jen.String().Call(jen.Id("body")),
+		}), jen.Lit(""), jen.Lit("  ")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Lit(""), jen.Err()),
+		),
+		jen.Return(jen.String().Call(jen.Id("data")), jen.Nil()),
+	)
+}
+
+// registerExplainTool returns the statements registering the tool that
+// returns the request an operation would send, without sending it
+func registerExplainTool() []jen.Code {
+	return registerTool(
+		"server",
+		"explain",
+		"Returns the HTTP request (method, URL, headers and body) an operation tool would send for the given arguments, without sending it",
+		jen.Id("ExplainToolArguments"),
+		jen.Func().Params(
+			jen.Id("arguments").Id("ExplainToolArguments"),
+		).Params(toolResultType(), jen.Error()).Block(
+			jen.List(jen.Id("build"), jen.Id("ok")).Op(":=").Id("explainers").Index(jen.Id("arguments").Dot("Operation")),
+			jen.If(jen.Op("!").Id("ok")).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("unknown operation %q"), jen.Id("arguments").Dot("Operation"))),
+			),
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("arguments").Dot("Arguments")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.List(jen.Id("req"), jen.Err()).Op(":=").Id("build").Call(jen.Id("data")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error building the %s request: %v"), jen.Id("arguments").Dot("Operation"), jen.Err())),
+			),
+			jen.List(jen.Id("explanation"), jen.Err()).Op(":=").Id("explainRequest").Call(jen.Id("req")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.Return(toolResult(textContent(jen.Id("explanation"))), jen.Nil()),
+		),
+		nil,
+	)
+}
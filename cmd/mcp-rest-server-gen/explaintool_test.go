@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExplainTool(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the explain tool sent %s %s", r.Method, r.URL)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--explain-tool")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "explain", map[string]any{"operation": "AddBook", "arguments": map[string]any{"Name": "Dune"}})
+	if result.IsError {
+		t.Fatalf("explaining AddBook failed: %s", result.text())
+	}
+	var request struct {
+		Method  string            `json:"method"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(result.text()), &request); err != nil {
+		t.Fatalf("decoding the explanation %s: %v", result.text(), err)
+	}
+	if request.Method != "PUT" || request.URL != upstream.URL+"/AddBook" || !strings.Contains(request.Body, `"Name":"Dune"`) {
+		t.Errorf("the explained request is %+v", request)
+	}
+
+	// The credentials are not shown
+	if got := request.Headers["Authorization"]; got != "[redacted]" {
+		t.Errorf("the explained Authorization header is %q, want it redacted", got)
+	}
+
+	if result := session.callTool(t, "explain", map[string]any{"operation": "RemoveBook"}); !result.IsError || !strings.Contains(result.text(), `unknown operation "RemoveBook"`) {
+		t.Errorf("explaining an unknown operation returned %+v", result)
+	}
+}
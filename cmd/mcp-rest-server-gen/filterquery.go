@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+	"sigs.k8s.io/yaml"
+)
+
+// filterParameters returns the query parameters of a list operation the
+// filter query argument is translated into, none when the operation takes a
+// body or has fewer than two of them
+func filterParameters(op OperationInfo) []ParameterInfo {
+	if !CLI.FilterQuery || op.HasRequestBody {
+		return nil
+	}
+
+	var params []ParameterInfo
+	for _, param := range op.Parameters {
+		if param.In == openapi3.ParameterInQuery && param.ContentType == "" && param.Schema != nil {
+			params = append(params, param)
+		}
+	}
+	if len(params) < 2 {
+		return nil
+	}
+	return params
+}
+
+// filterQueryArgument returns the tool argument holding the filters of the
+// operation in plain words
+func filterQueryArgument(op OperationInfo, params []ParameterInfo) ConfigField {
+	if op.hasParameter("query") {
+		warnf("parameter query of %s is shadowed by the filter query argument", op.ID)
+	}
+
+	names := make([]string, 0, len(params))
+	example := params[0].Name + " is value"
+	for i := len(params) - 1; i >= 0; i-- {
+		names = append([]string{params[i].Name}, names...)
+		if enum := enumValues(params[i].Schema); len(enum) > 0 {
+			example = params[i].Name + " is " + enum[0]
+		}
+	}
+	return ConfigField{
+		Name: "Query", Type: jen.String(),
+		Tags: map[string]string{
+			"json": "query,omitempty",
+			"jsonschema_description": fmt.Sprintf("Optional filters in plain words translated into the %s parameters, "+
+				"as clauses separated by commas or and, each either name is value, name=value or a value of an enum alone, "+
+				"such as %s, the parameters given explicitly take precedence", strings.Join(names, ", "), example),
+		},
+	}
+}
+
+// readFilterRules reads the filter rules file, YAML or JSON mapping phrases of
+// the filter queries to the parameter values they stand for
+func readFilterRules(path string) (map[string]map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading filter rules file: %w", err)
+	}
+
+	var file map[string]map[string]any
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("error parsing filter rules file: %w", err)
+	}
+
+	rules := make(map[string]map[string]string, len(file))
+	for phrase, values := range file {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("filter rule %q sets no parameter", phrase)
+		}
+		rule := make(map[string]string, len(values))
+		for name, value := range values {
+			rule[name] = fmt.Sprint(value)
+		}
+		rules[strings.ToLower(strings.TrimSpace(phrase))] = rule
+	}
+	return rules, nil
+}
+
+// operationFilterRules returns the filter rules setting only filter
+// parameters of the operation
+func operationFilterRules(params []ParameterInfo, rules map[string]map[string]string) map[string]map[string]string {
+	names := make(map[string]bool, len(params))
+	for _, param := range params {
+		names[param.Name] = true
+	}
+
+	matching := make(map[string]map[string]string)
+	for phrase, rule := range rules {
+		applies := true
+		for name := range rule {
+			applies = applies && names[name]
+		}
+		if applies {
+			matching[phrase] = rule
+		}
+	}
+	return matching
+}
+
+// filtersCode returns the map literal describing the filter parameters, by
+// name, for the translation of the filter queries
+func filtersCode(params []ParameterInfo) jen.Code {
+	values := jen.Dict{}
+	for _, param := range params {
+		schema, array := param.Schema, false
+		if schema.Type.Is(openapi3.TypeArray) && schema.Items != nil && schema.Items.Value != nil {
+			schema, array = schema.Items.Value, true
+		}
+
+		fields := jen.Dict{}
+		switch {
+		case schema.Type.Is(openapi3.TypeInteger), schema.Type.Is(openapi3.TypeNumber):
+			fields[jen.Id("Kind")] = jen.Lit("number")
+		case schema.Type.Is(openapi3.TypeBoolean):
+			fields[jen.Id("Kind")] = jen.Lit("boolean")
+		}
+		if array {
+			fields[jen.Id("Array")] = jen.True()
+		}
+		if enum := enumValues(param.Schema); len(enum) > 0 {
+			items := make([]jen.Code, 0, len(enum))
+			for _, value := range enum {
+				items = append(items, jen.Lit(value))
+			}
+			fields[jen.Id("Enum")] = jen.Index().String().Values(items...)
+		}
+		values[jen.Lit(param.Name)] = jen.Values(fields)
+	}
+	return jen.Map(jen.String()).Id("filterParameter").Values(values)
+}
+
+// filterRulesCode returns the map literal of the filter rules of the
+// operation
+func filterRulesCode(rules map[string]map[string]string) jen.Code {
+	phrases := make([]string, 0, len(rules))
+	for phrase := range rules {
+		phrases = append(phrases, phrase)
+	}
+	sort.Strings(phrases)
+
+	values := jen.Dict{}
+	for _, phrase := range phrases {
+		rule := jen.Dict{}
+		for name, value := range rules[phrase] {
+			rule[jen.Lit(name)] = jen.Lit(value)
+		}
+		values[jen.Lit(phrase)] = jen.Values(rule)
+	}
+	return jen.Map(jen.String()).Map(jen.String()).String().Values(values)
+}
+
+// addTranslateFilterQuery adds the functions translating the filter query
+// argument into the query parameters, following the parameter names, the enum
+// values and the filter rules, no model is involved
+func addTranslateFilterQuery(f *jen.File) {
+	f.Comment("filterParameter describes a parameter the filter queries are translated into")
+	f.Type().Id("filterParameter").Struct(
+		jen.Comment("Kind is number or boolean for the values decoded as such, empty for strings"),
+		jen.Id("Kind").String(),
+		jen.Comment("Array is set when the parameter takes a list of values, one per clause"),
+		jen.Id("Array").Bool(),
+		jen.Id("Enum").Index().String(),
+	)
+
+	f.Comment("filterClauseSeparator splits the filter queries into clauses")
+	f.Var().Id("filterClauseSeparator").Op("=").Qual("regexp", "MustCompile").Call(jen.Lit(`(?i)\s*(?:[,;]|\band\b)\s*`))
+
+	f.Comment("filterClausePattern matches the name is value, name=value and name: value clauses")
+	f.Var().Id("filterClausePattern").Op("=").Qual("regexp", "MustCompile").Call(jen.Lit(`(?i)^(.+?)\s*(?:=|:|\s(?:is|equals)\s)\s*(.+)$`))
+
+	f.Comment("translateFilterQuery sets the query parameters given in plain words by the query argument")
+	f.Comment("of the JSON arguments, keeping the ones given explicitly")
+	f.Func().Id("translateFilterQuery").Params(
+		jen.Id("data").Index().Byte(),
+		jen.Id("filters").Map(jen.String()).Id("filterParameter"),
+		jen.Id("rules").Map(jen.String()).Map(jen.String()).String(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Var().Id("arguments").Map(jen.String()).Any(),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("arguments")), jen.Err().Op("!=").Nil()).Block(
+			// Left to the regular decoding to report
+			jen.Return(jen.Id("data"), jen.Nil()),
+		),
+		jen.List(jen.Id("query"), jen.Id("_")).Op(":=").Id("arguments").Index(jen.Lit("query")).Assert(jen.String()),
+		jen.If(jen.Qual("strings", "TrimSpace").Call(jen.Id("query")).Op("==").Lit("")).Block(
+			jen.Return(jen.Id("data"), jen.Nil()),
+		),
+
+		jen.List(jen.Id("values"), jen.Err()).Op(":=").Id("parseFilterQuery").Call(jen.Id("query"), jen.Id("filters"), jen.Id("rules")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.For(jen.List(jen.Id("name"), jen.Id("value")).Op(":=").Range().Id("values")).Block(
+			jen.If(jen.List(jen.Id("_"), jen.Id("set")).Op(":=").Id("arguments").Index(jen.Id("name")), jen.Op("!").Id("set")).Block(
+				jen.Id("arguments").Index(jen.Id("name")).Op("=").Id("value"),
+			),
+		),
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id("arguments"))),
+	)
+
+	f.Comment("parseFilterQuery returns the parameter values of the filter query clauses: the phrase of")
+	f.Comment("a rule, a parameter name followed by its value, or a value of a single parameter enum")
+	f.Func().Id("parseFilterQuery").Params(
+		jen.Id("query").String(),
+		jen.Id("filters").Map(jen.String()).Id("filterParameter"),
+		jen.Id("rules").Map(jen.String()).Map(jen.String()).String(),
+	).Params(jen.Map(jen.String()).Any(), jen.Error()).Block(
+		jen.Id("names").Op(":=").Make(jen.Index().String(), jen.Lit(0), jen.Len(jen.Id("filters"))),
+		jen.For(jen.Id("name").Op(":=").Range().Id("filters")).Block(
+			jen.Id("names").Op("=").Append(jen.Id("names"), jen.Id("name")),
+		),
+		jen.Qual("sort", "Strings").Call(jen.Id("names")),
+
+		jen.Id("values").Op(":=").Make(jen.Map(jen.String()).Any()),
+		jen.For(jen.List(jen.Id("_"), jen.Id("clause")).Op(":=").Range().Id("filterClauseSeparator").Dot("Split").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("query")), jen.Lit(-1))).Block(
+			jen.If(jen.Id("clause").Op("==").Lit("")).Block(
+				jen.Continue(),
+			),
+
+			jen.If(jen.List(jen.Id("rule"), jen.Id("ok")).Op(":=").Id("rules").Index(jen.Qual("strings", "ToLower").Call(jen.Id("clause"))), jen.Id("ok")).Block(
+				jen.For(jen.List(jen.Id("name"), jen.Id("value")).Op(":=").Range().Id("rule")).Block(
+					jen.If(jen.Err().Op(":=").Id("setFilter").Call(jen.Id("values"), jen.Id("name"), jen.Id("filters").Index(jen.Id("name")), jen.Id("value")), jen.Err().Op("!=").Nil()).Block(
+						jen.Return(jen.Nil(), jen.Err()),
+					),
+				),
+				jen.Continue(),
+			),
+
+			jen.List(jen.Id("name"), jen.Id("value")).Op(":=").Id("filterClause").Call(jen.Id("clause"), jen.Id("names")),
+			jen.If(jen.Id("name").Op("==").Lit("")).Block(
+				jen.Comment("A value alone is one of the enum of a single parameter"),
+				jen.For(jen.List(jen.Id("_"), jen.Id("candidate")).Op(":=").Range().Id("names")).Block(
+					jen.For(jen.List(jen.Id("_"), jen.Id("allowed")).Op(":=").Range().Id("filters").Index(jen.Id("candidate")).Dot("Enum")).Block(
+						jen.If(jen.Qual("strings", "EqualFold").Call(jen.Id("allowed"), jen.Id("clause"))).Block(
+							jen.If(jen.Id("name").Op("!=").Lit("")).Block(
+								jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("filter %q is a value of both %s and %s, give the parameter name"), jen.Id("clause"), jen.Id("name"), jen.Id("candidate"))),
+							),
+							jen.List(jen.Id("name"), jen.Id("value")).Op("=").List(jen.Id("candidate"), jen.Id("clause")),
+						),
+					),
+				),
+			),
+			jen.If(jen.Id("name").Op("==").Lit("")).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("cannot translate filter %q, use name is value with one of the parameters %s"), jen.Id("clause"), jen.Qual("strings", "Join").Call(jen.Id("names"), jen.Lit(", ")))),
+			),
+			jen.If(jen.Err().Op(":=").Id("setFilter").Call(jen.Id("values"), jen.Id("name"), jen.Id("filters").Index(jen.Id("name")), jen.Id("value")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+		),
+		jen.Return(jen.Id("values"), jen.Nil()),
+	)
+
+	f.Comment("filterClause returns the parameter named by the clause with its value, the names are")
+	f.Comment("compared ignoring case and separators so due date names dueDate")
+	f.Func().Id("filterClause").Params(
+		jen.Id("clause").String(),
+		jen.Id("names").Index().String(),
+	).Params(jen.String(), jen.String()).Block(
+		jen.Id("normalize").Op(":=").Func().Params(jen.Id("text").String()).String().Block(
+			jen.Return(jen.Qual("strings", "Map").Call(jen.Func().Params(jen.Id("r").Rune()).Rune().Block(
+				jen.If(jen.Qual("unicode", "IsLetter").Call(jen.Id("r")).Op("||").Qual("unicode", "IsDigit").Call(jen.Id("r"))).Block(
+					jen.Return(jen.Qual("unicode", "ToLower").Call(jen.Id("r"))),
+				),
+				jen.Return(jen.Lit(-1)),
+			), jen.Id("text"))),
+		),
+		jen.Id("lookup").Op(":=").Func().Params(jen.Id("text").String()).String().Block(
+			jen.For(jen.List(jen.Id("_"), jen.Id("name")).Op(":=").Range().Id("names")).Block(
+				jen.If(jen.Id("normalize").Call(jen.Id("name")).Op("==").Id("normalize").Call(jen.Id("text"))).Block(
+					jen.Return(jen.Id("name")),
+				),
+			),
+			jen.Return(jen.Lit("")),
+		),
+
+		jen.If(jen.Id("match").Op(":=").Id("filterClausePattern").Dot("FindStringSubmatch").Call(jen.Id("clause")), jen.Id("match").Op("!=").Nil()).Block(
+			jen.If(jen.Id("name").Op(":=").Id("lookup").Call(jen.Id("match").Index(jen.Lit(1))), jen.Id("name").Op("!=").Lit("")).Block(
+				jen.Return(jen.Id("name"), jen.Id("match").Index(jen.Lit(2))),
+			),
+		),
+		jen.Comment("The name followed by the value, such as limit 10"),
+		jen.If(jen.List(jen.Id("text"), jen.Id("value"), jen.Id("ok")).Op(":=").Qual("strings", "Cut").Call(jen.Id("clause"), jen.Lit(" ")), jen.Id("ok")).Block(
+			jen.If(jen.Id("name").Op(":=").Id("lookup").Call(jen.Id("text")), jen.Id("name").Op("!=").Lit("")).Block(
+				jen.Return(jen.Id("name"), jen.Qual("strings", "TrimSpace").Call(jen.Id("value"))),
+			),
+		),
+		jen.Return(jen.Lit(""), jen.Lit("")),
+	)
+
+	f.Comment("setFilter sets the value of the parameter decoded as its kind, the enum values with their")
+	f.Comment("declared case, appending it to the previous ones for the lists")
+	f.Func().Id("setFilter").Params(
+		jen.Id("values").Map(jen.String()).Any(),
+		jen.Id("name").String(),
+		jen.Id("filter").Id("filterParameter"),
+		jen.Id("text").String(),
+	).Error().Block(
+		jen.Id("text").Op("=").Qual("strings", "Trim").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("text")), jen.Lit(`"'`)),
+		jen.For(jen.List(jen.Id("_"), jen.Id("allowed")).Op(":=").Range().Id("filter").Dot("Enum")).Block(
+			jen.If(jen.Qual("strings", "EqualFold").Call(jen.Id("allowed"), jen.Id("text"))).Block(
+				jen.Id("text").Op("=").Id("allowed"),
+			),
+		),
+
+		jen.Var().Id("value").Any().Op("=").Id("text"),
+		jen.Switch(jen.Id("filter").Dot("Kind")).Block(
+			jen.Case(jen.Lit("number")).Block(
+				jen.List(jen.Id("number"), jen.Err()).Op(":=").Qual("strconv", "ParseFloat").Call(jen.Id("text"), jen.Lit(64)),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("filter %s must be a number, got %q"), jen.Id("name"), jen.Id("text"))),
+				),
+				jen.Id("value").Op("=").Id("number"),
+			),
+			jen.Case(jen.Lit("boolean")).Block(
+				jen.Switch(jen.Qual("strings", "ToLower").Call(jen.Id("text"))).Block(
+					jen.Case(jen.Lit("true"), jen.Lit("yes"), jen.Lit("y"), jen.Lit("on"), jen.Lit("1")).Block(
+						jen.Id("value").Op("=").True(),
+					),
+					jen.Case(jen.Lit("false"), jen.Lit("no"), jen.Lit("n"), jen.Lit("off"), jen.Lit("0")).Block(
+						jen.Id("value").Op("=").False(),
+					),
+					jen.Default().Block(
+						jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("filter %s must be true or false, got %q"), jen.Id("name"), jen.Id("text"))),
+					),
+				),
+			),
+		),
+
+		jen.If(jen.Id("filter").Dot("Array")).Block(
+			jen.List(jen.Id("items"), jen.Id("_")).Op(":=").Id("values").Index(jen.Id("name")).Assert(jen.Index().Any()),
+			jen.Id("values").Index(jen.Id("name")).Op("=").Append(jen.Id("items"), jen.Id("value")),
+			jen.Return(jen.Nil()),
+		),
+		jen.Id("values").Index(jen.Id("name")).Op("=").Id("value"),
+		jen.Return(jen.Nil()),
+	)
+}
+
+// filterQueryCode returns the statement translating the filter query of the
+// JSON arguments data of the operation
+func filterQueryCode(params []ParameterInfo, rules map[string]map[string]string) jen.Code {
+	return jen.List(jen.Id("data"), jen.Err()).Op("=").Id("translateFilterQuery").Call(jen.Id("data"), filtersCode(params), filterRulesCode(operationFilterRules(params, rules)))
+}
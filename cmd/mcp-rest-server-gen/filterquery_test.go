@@ -0,0 +1,57 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilterQuery(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	rules := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(rules, []byte("poems:\n  tags: poetry\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binary := buildServer(t, "testdata/params.yaml", "--auth-type", "none", "--filter-query", "--filter-rules", rules)
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The enum values alone, the separated names and the rules name the parameters
+	tests := []struct {
+		arguments map[string]any
+		want      url.Values
+	}{
+		{
+			map[string]any{"q": "dune", "query": "lent, min score is 4.5 and limit=10; poems"},
+			url.Values{"q": {"dune"}, "status": {"lent"}, "minScore": {"4.5"}, "limit": {"10"}, "tags": {"poetry"}},
+		},
+		{
+			map[string]any{"q": "dune", "query": "status is lent", "status": "available"},
+			url.Values{"q": {"dune"}, "status": {"available"}},
+		},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, "ListBooks", test.arguments); result.IsError {
+			t.Fatalf("ListBooks with %v failed: %s", test.arguments, result.text())
+		}
+		if got := <-queries; !maps.EqualFunc(got, test.want, slices.Equal) {
+			t.Errorf("ListBooks with %v sent the query %v, want %v", test.arguments, got, test.want)
+		}
+	}
+
+	result := session.callTool(t, "ListBooks", map[string]any{"q": "dune", "query": "cheap"})
+	if !result.IsError || !strings.Contains(result.text(), `cannot translate filter "cheap", use name is value with one of the parameters`) {
+		t.Errorf("ListBooks with an unknown filter returned %+v", result)
+	}
+}
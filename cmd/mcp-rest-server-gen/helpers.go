@@ -0,0 +1,465 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// helperSet tracks the helper functions used by the generated code, so each
+// one is emitted only once and in a stable order
+type helperSet struct {
+	names []string
+	funcs map[string]func(f *jen.File)
+}
+
+// newHelperSet creates an empty helper set
+func newHelperSet() *helperSet {
+	return &helperSet{funcs: make(map[string]func(f *jen.File))}
+}
+
+// use marks the helper as used, add is called once when emitting
+func (h *helperSet) use(name string, add func(f *jen.File)) {
+	if _, ok := h.funcs[name]; ok {
+		return
+	}
+	h.names = append(h.names, name)
+	h.funcs[name] = add
+}
+
+// emit adds the used helpers to the file in the order they were first used
+func (h *helperSet) emit(f *jen.File) {
+	for _, name := range h.names {
+		h.funcs[name](f)
+	}
+}
+
+// addIsProblemResponse adds the function detecting problem details responses
+func addIsProblemResponse(f *jen.File) {
+	f.Comment("isProblemResponse reports whether the response carries RFC 7807 problem details")
+	f.Func().Id("isProblemResponse").Params(
+		jen.Id("resp").Op("*").Qual("net/http", "Response"),
+	).Bool().Block(
+		jen.If(jen.Id("resp").Op("==").Nil()).Block(
+			jen.Return(jen.False()),
+		),
+		jen.List(jen.Id("mediaType"), jen.Id("_"), jen.Id("_")).Op(":=").Qual("mime", "ParseMediaType").Call(
+			jen.Id("resp").Dot("Header").Dot("Get").Call(jen.Lit("Content-Type")),
+		),
+		jen.Return(jen.Id("mediaType").Op("==").Lit(problemContentType)),
+	)
+}
+
+// addFormatProblem adds the function formatting RFC 7807 problem details
+func addFormatProblem(f *jen.File) {
+	f.Comment("formatProblem formats RFC 7807 problem details, falling back to the raw body")
+	f.Func().Id("formatProblem").Params(
+		jen.Id("body").Index().Byte(),
+	).String().Block(
+		jen.Var().Id("problem").Struct(
+			jen.Id("Title").String().Tag(map[string]string{"json": "title"}),
+			jen.Id("Detail").String().Tag(map[string]string{"json": "detail"}),
+			jen.Id("Errors").Qual("encoding/json", "RawMessage").Tag(map[string]string{"json": "errors"}),
+		),
+		jen.If(
+			jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("problem")),
+			jen.Err().Op("!=").Nil().Op("||").Parens(jen.Id("problem").Dot("Title").Op("==").Lit("").Op("&&").Id("problem").Dot("Detail").Op("==").Lit("")),
+		).Block(
+			jen.Return(jen.String().Call(jen.Id("body"))),
+		),
+		jen.Id("message").Op(":=").Id("problem").Dot("Title"),
+		jen.If(jen.Id("problem").Dot("Detail").Op("!=").Lit("")).Block(
+			jen.If(jen.Id("message").Op("!=").Lit("")).Block(
+				jen.Id("message").Op("+=").Lit(": "),
+			),
+			jen.Id("message").Op("+=").Id("problem").Dot("Detail"),
+		),
+		jen.If(jen.Len(jen.Id("problem").Dot("Errors")).Op(">").Lit(0).Op("&&").String().Call(jen.Id("problem").Dot("Errors")).Op("!=").Lit("null")).Block(
+			jen.Id("message").Op("+=").Lit(" (errors: ").Op("+").String().Call(jen.Id("problem").Dot("Errors")).Op("+").Lit(")"),
+		),
+		jen.Return(jen.Id("message")),
+	)
+}
+
+// addRateLimitNote adds the function summarizing the rate-limit headers of a
+// response, with the header names to look for
+func addRateLimitNote(f *jen.File) {
+	names := make([]jen.Code, 0, len(CLI.RateLimitHeaders))
+	for _, name := range CLI.RateLimitHeaders {
+		names = append(names, jen.Lit(name))
+	}
+
+	f.Comment("rateLimitHeaders are the response headers reported in rate-limit notes")
+	f.Var().Id("rateLimitHeaders").Op("=").Index().String().Values(names...)
+
+	f.Comment("rateLimitNote returns a note with the rate-limit headers present in the response,")
+	f.Comment("empty when there are none")
+	f.Func().Id("rateLimitNote").Params(
+		jen.Id("header").Qual("net/http", "Header"),
+	).String().Block(
+		jen.Var().Id("values").Index().String(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("name")).Op(":=").Range().Id("rateLimitHeaders")).Block(
+			jen.If(jen.Id("value").Op(":=").Id("header").Dot("Get").Call(jen.Id("name")), jen.Id("value").Op("!=").Lit("")).Block(
+				jen.Id("values").Op("=").Append(jen.Id("values"), jen.Id("name").Op("+").Lit("=").Op("+").Id("value")),
+			),
+		),
+		jen.If(jen.Len(jen.Id("values")).Op("==").Lit(0)).Block(
+			jen.Return(jen.Lit("")),
+		),
+		jen.Return(jen.Lit("\n[rate limit: ").Op("+").Qual("strings", "Join").Call(jen.Id("values"), jen.Lit(", ")).Op("+").Lit("]")),
+	)
+}
+
+// handlerSignature returns the type of the tool handlers taking the arguments
+func handlerSignature(argsType jen.Code) *jen.Statement {
+	return jen.Func().Params(argsType).Params(toolResultType(), jen.Error())
+}
+
+// addLazyHandler adds the function deferring the construction of a tool
+// handler to its first call
+func addLazyHandler(f *jen.File) {
+	handlerType := handlerSignature(jen.Id("T"))
+
+	f.Comment("lazyHandler returns a handler building the actual one on its first call, the")
+	f.Comment("arguments type is kept so the tool input schema is unchanged")
+	f.Func().Id("lazyHandler").Types(jen.Id("T").Any()).Params(
+		jen.Id("build").Func().Params().Add(handlerType.Clone()),
+	).Add(handlerType.Clone()).Block(
+		jen.Var().Id("once").Qual("sync", "Once"),
+		jen.Var().Id("handler").Add(handlerType.Clone()),
+		jen.Return(jen.Func().Params(jen.Id("arguments").Id("T")).Params(toolResultType(), jen.Error()).Block(
+			jen.Id("once").Dot("Do").Call(jen.Func().Params().Block(
+				jen.Id("handler").Op("=").Id("build").Call(),
+			)),
+			jen.Return(jen.Id("handler").Call(jen.Id("arguments"))),
+		)),
+	)
+}
+
+// addTransformResponse adds the hook transforming the response bodies, a
+// variable so it can be replaced without editing the generated file
+func addTransformResponse(f *jen.File) {
+	f.Comment("transformResponse transforms the response body of the operation before it is returned,")
+	f.Comment("replace it from another file of the package, e.g. in an init function, to trim,")
+	f.Comment("reformat or enrich the responses")
+	f.Var().Id("transformResponse").Op("=").Func().Params(
+		jen.Id("op").String(),
+		jen.Id("body").Index().Byte(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Return(jen.Id("body"), jen.Nil()),
+	)
+}
+
+// addStripResponseKeys adds the function dropping the configured top-level
+// keys from the JSON object responses
+func addStripResponseKeys(f *jen.File) {
+	keys := make([]jen.Code, 0, len(CLI.StripResponseKeys))
+	for _, key := range CLI.StripResponseKeys {
+		keys = append(keys, jen.Lit(key))
+	}
+
+	f.Comment("strippedResponseKeys are the top-level keys dropped from the JSON object responses")
+	f.Var().Id("strippedResponseKeys").Op("=").Index().String().Values(keys...)
+
+	f.Comment("stripResponseKeys drops the stripped keys from a JSON object body, other bodies are")
+	f.Comment("returned unchanged")
+	f.Func().Id("stripResponseKeys").Params(
+		jen.Id("body").Index().Byte(),
+	).Index().Byte().Block(
+		jen.Var().Id("object").Map(jen.String()).Qual("encoding/json", "RawMessage"),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("object")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.Id("stripped").Op(":=").False(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("key")).Op(":=").Range().Id("strippedResponseKeys")).Block(
+			jen.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Id("object").Index(jen.Id("key")), jen.Id("ok")).Block(
+				jen.Delete(jen.Id("object"), jen.Id("key")),
+				jen.Id("stripped").Op("=").True(),
+			),
+		),
+		jen.If(jen.Op("!").Id("stripped")).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("object")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.Return(jen.Id("data")),
+	)
+}
+
+// addConvertResponseKeys adds the functions rewriting the keys of the JSON
+// responses to the configured case
+func addConvertResponseKeys(f *jen.File) {
+	f.Comment("convertResponseKeys rewrites the object keys of a JSON body recursively, other bodies")
+	f.Comment("are returned unchanged")
+	f.Func().Id("convertResponseKeys").Params(
+		jen.Id("body").Index().Byte(),
+	).Index().Byte().Block(
+		// Numbers are kept as written so large integers are not rounded
+		jen.Var().Id("value").Any(),
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("body"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("value")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("convertKeys").Call(jen.Id("value"))),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.Return(jen.Id("data")),
+	)
+
+	f.Comment("convertKeys rewrites the object keys of the JSON value recursively")
+	f.Func().Id("convertKeys").Params(jen.Id("value").Any()).Any().Block(
+		jen.Switch(jen.Id("v").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Index().Any()).Block(
+				jen.For(jen.List(jen.Id("i"), jen.Id("item")).Op(":=").Range().Id("v")).Block(
+					jen.Id("v").Index(jen.Id("i")).Op("=").Id("convertKeys").Call(jen.Id("item")),
+				),
+			),
+			jen.Case(jen.Map(jen.String()).Any()).Block(
+				jen.Id("converted").Op(":=").Make(jen.Map(jen.String()).Any(), jen.Len(jen.Id("v"))),
+				jen.For(jen.List(jen.Id("key"), jen.Id("child")).Op(":=").Range().Id("v")).Block(
+					jen.Id("converted").Index(jen.Id("convertKey").Call(jen.Id("key"))).Op("=").Id("convertKeys").Call(jen.Id("child")),
+				),
+				jen.Return(jen.Id("converted")),
+			),
+		),
+		jen.Return(jen.Id("value")),
+	)
+
+	isSeparator := jen.Id("r").Op("==").LitRune('_').Op("||").Id("r").Op("==").LitRune('-').Op("||").Id("r").Op("==").LitRune(' ')
+	f.Comment("snakeCase returns the key in snake_case, acronyms are kept as a single word")
+	f.Func().Id("snakeCase").Params(jen.Id("key").String()).String().Block(
+		jen.Id("runes").Op(":=").Index().Rune().Call(jen.Id("key")),
+		jen.Var().Id("b").Qual("strings", "Builder"),
+		jen.For(jen.List(jen.Id("i"), jen.Id("r")).Op(":=").Range().Id("runes")).Block(
+			jen.If(isSeparator).Block(
+				jen.Id("b").Dot("WriteRune").Call(jen.LitRune('_')),
+				jen.Continue(),
+			),
+			jen.If(jen.Qual("unicode", "IsUpper").Call(jen.Id("r")).Op("&&").Id("i").Op(">").Lit(0)).Block(
+				jen.Id("prev").Op(":=").Id("runes").Index(jen.Id("i").Op("-").Lit(1)),
+				jen.Id("nextLower").Op(":=").Id("i").Op("+").Lit(1).Op("<").Len(jen.Id("runes")).Op("&&").Qual("unicode", "IsLower").Call(jen.Id("runes").Index(jen.Id("i").Op("+").Lit(1))),
+				jen.If(jen.Qual("unicode", "IsLower").Call(jen.Id("prev")).Op("||").Qual("unicode", "IsDigit").Call(jen.Id("prev")).Op("||").Parens(jen.Qual("unicode", "IsUpper").Call(jen.Id("prev")).Op("&&").Id("nextLower"))).Block(
+					jen.Id("b").Dot("WriteRune").Call(jen.LitRune('_')),
+				),
+			),
+			jen.Id("b").Dot("WriteRune").Call(jen.Qual("unicode", "ToLower").Call(jen.Id("r"))),
+		),
+		jen.Return(jen.Id("b").Dot("String").Call()),
+	)
+
+	if CLI.ResponseKeyCase == "snake" {
+		f.Comment("convertKey returns the key in the response key case")
+		f.Func().Id("convertKey").Params(jen.Id("key").String()).String().Block(
+			jen.Return(jen.Id("snakeCase").Call(jen.Id("key"))),
+		)
+		return
+	}
+
+	f.Comment("convertKey returns the key in camelCase from its snake_case words, leading")
+	f.Comment("underscores are kept")
+	f.Func().Id("convertKey").Params(jen.Id("key").String()).String().Block(
+		jen.Id("snake").Op(":=").Id("snakeCase").Call(jen.Id("key")),
+		jen.Id("words").Op(":=").Qual("strings", "TrimLeft").Call(jen.Id("snake"), jen.Lit("_")),
+		jen.Var().Id("b").Qual("strings", "Builder"),
+		jen.Id("b").Dot("WriteString").Call(jen.Id("snake").Index(jen.Op(":").Len(jen.Id("snake")).Op("-").Len(jen.Id("words")))),
+		jen.For(jen.List(jen.Id("i"), jen.Id("word")).Op(":=").Range().Qual("strings", "Split").Call(jen.Id("words"), jen.Lit("_"))).Block(
+			jen.If(jen.Id("i").Op(">").Lit(0).Op("&&").Id("word").Op("!=").Lit("")).Block(
+				jen.List(jen.Id("r"), jen.Id("size")).Op(":=").Qual("unicode/utf8", "DecodeRuneInString").Call(jen.Id("word")),
+				jen.Id("word").Op("=").String().Call(jen.Qual("unicode", "ToUpper").Call(jen.Id("r"))).Op("+").Id("word").Index(jen.Id("size").Op(":")),
+			),
+			jen.Id("b").Dot("WriteString").Call(jen.Id("word")),
+		),
+		jen.Return(jen.Id("b").Dot("String").Call()),
+	)
+}
+
+// addOperationMeta adds the function describing the operation behind a call,
+// as a separate text content since the SDK serializes neither embedded
+// resources nor annotations as the MCP schema expects
+func addOperationMeta(f *jen.File) {
+	f.Comment("operationMeta returns a JSON object with the operation and the resolved path of the")
+	f.Comment("call, kept apart from the response body")
+	f.Func().Id("operationMeta").Params(
+		jen.List(jen.Id("operationID"), jen.Id("method")).String(),
+		jen.Id("resp").Op("*").Qual("net/http", "Response"),
+	).Add(contentType()).Block(
+		jen.Id("meta").Op(":=").Map(jen.String()).String().Values(jen.Dict{
+			jen.Lit("operationId"): jen.Id("operationID"),
+			jen.Lit("method"):      jen.Id("method"),
+		}),
+		jen.If(jen.Id("resp").Op("!=").Nil().Op("&&").Id("resp").Dot("Request").Op("!=").Nil()).Block(
+			jen.Id("meta").Index(jen.Lit("path")).Op("=").Id("resp").Dot("Request").Dot("URL").Dot("Path"),
+		),
+		jen.List(jen.Id("data"), jen.Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Map(jen.String()).Any().Values(jen.Dict{
+			jen.Lit("operation"): jen.Id("meta"),
+		})),
+		jen.Return(textContent(jen.String().Call(jen.Id("data")))),
+	)
+}
+
+// addMimeAnnotation adds the function describing the media type of a response,
+// as a separate text content since the SDK annotations have no media type
+func addMimeAnnotation(f *jen.File) {
+	f.Comment("mimeAnnotation returns a JSON object with the media type of the upstream response,")
+	f.Comment("sniffed from the body when the response declares none")
+	f.Func().Id("mimeAnnotation").Params(
+		jen.Id("resp").Op("*").Qual("net/http", "Response"),
+		jen.Id("body").Index().Byte(),
+	).Add(contentType()).Block(
+		jen.Id("contentType").Op(":=").Lit(""),
+		jen.If(jen.Id("resp").Op("!=").Nil()).Block(
+			jen.Id("contentType").Op("=").Id("resp").Dot("Header").Dot("Get").Call(jen.Lit("Content-Type")),
+		),
+		jen.If(jen.Id("contentType").Op("==").Lit("")).Block(
+			jen.Id("contentType").Op("=").Qual("net/http", "DetectContentType").Call(jen.Id("body")),
+		),
+		jen.If(jen.List(jen.Id("mediaType"), jen.Id("_"), jen.Err()).Op(":=").Qual("mime", "ParseMediaType").Call(jen.Id("contentType")), jen.Err().Op("==").Nil()).Block(
+			jen.Id("contentType").Op("=").Id("mediaType"),
+		),
+		jen.List(jen.Id("data"), jen.Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Map(jen.String()).String().Values(jen.Dict{
+			jen.Lit("mimeType"): jen.Id("contentType"),
+		})),
+		jen.Return(textContent(jen.String().Call(jen.Id("data")))),
+	)
+}
+
+// addCallContext adds the function deriving the context of a tool call
+func addCallContext(f *jen.File) {
+	f.Comment("callContext returns the context of a tool call derived from the server one, bounded by")
+	f.Comment("the requested timeout or else the default one, requested timeouts are limited to the")
+	f.Comment("maximum when set")
+	f.Func().Id("callContext").Params(
+		jen.Id("parent").Qual("context", "Context"),
+		jen.Id("timeoutSeconds").Int(),
+		jen.List(jen.Id("defaultTimeout"), jen.Id("maxTimeout")).Qual("time", "Duration"),
+	).Params(jen.Qual("context", "Context"), jen.Qual("context", "CancelFunc")).Block(
+		jen.Id("timeout").Op(":=").Id("defaultTimeout"),
+		jen.If(jen.Id("timeoutSeconds").Op(">").Lit(0)).Block(
+			jen.Id("timeout").Op("=").Qual("time", "Duration").Call(jen.Id("timeoutSeconds")).Op("*").Qual("time", "Second"),
+			jen.If(jen.Id("maxTimeout").Op(">").Lit(0)).Block(
+				jen.Id("timeout").Op("=").Min(jen.Id("timeout"), jen.Id("maxTimeout")),
+			),
+		),
+		jen.If(jen.Id("timeout").Op("<=").Lit(0)).Block(
+			jen.Return(jen.Qual("context", "WithCancel").Call(jen.Id("parent"))),
+		),
+		jen.Return(jen.Qual("context", "WithTimeout").Call(jen.Id("parent"), jen.Id("timeout"))),
+	)
+}
+
+// addErrorDetail adds the function presenting the body of an error response
+func addErrorDetail(f *jen.File) {
+	f.Comment("errorDetail returns the response body appended to the error of a failed call, trimmed")
+	f.Comment("to the limit, empty when the response has no body")
+	f.Func().Id("errorDetail").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("limit").Int(),
+	).String().Block(
+		jen.Id("body").Op("=").Qual("bytes", "TrimSpace").Call(jen.Id("body")),
+		jen.If(jen.Len(jen.Id("body")).Op("==").Lit(0)).Block(
+			jen.Return(jen.Lit("")),
+		),
+		jen.If(jen.Len(jen.Id("body")).Op(">").Id("limit")).Block(
+			jen.Return(jen.Lit(": ").Op("+").Qual("strings", "ToValidUTF8").Call(jen.String().Call(jen.Id("body").Index(jen.Empty(), jen.Id("limit"))), jen.Lit("")).Op("+").Lit(" [truncated]")),
+		),
+		jen.Return(jen.Lit(": ").Op("+").String().Call(jen.Id("body"))),
+	)
+}
+
+// addErrorCode adds the function mapping the response statuses to stable
+// error codes
+func addErrorCode(f *jen.File) {
+	f.Comment("errorCode returns the stable code of an error status, so agents can branch on the")
+	f.Comment("error category whatever the operation")
+	f.Func().Id("errorCode").Params(jen.Id("status").Int()).String().Block(
+		jen.Switch().Block(
+			jen.Case(jen.Id("status").Op("==").Qual("net/http", "StatusBadRequest")).Block(jen.Return(jen.Lit("BAD_REQUEST"))),
+			jen.Case(jen.Id("status").Op("==").Qual("net/http", "StatusUnauthorized")).Block(jen.Return(jen.Lit("UNAUTHORIZED"))),
+			jen.Case(jen.Id("status").Op("==").Qual("net/http", "StatusForbidden")).Block(jen.Return(jen.Lit("FORBIDDEN"))),
+			jen.Case(jen.Id("status").Op("==").Qual("net/http", "StatusNotFound")).Block(jen.Return(jen.Lit("NOT_FOUND"))),
+			jen.Case(jen.Id("status").Op("==").Qual("net/http", "StatusConflict")).Block(jen.Return(jen.Lit("CONFLICT"))),
+			jen.Case(jen.Id("status").Op("==").Qual("net/http", "StatusTooManyRequests")).Block(jen.Return(jen.Lit("RATE_LIMITED"))),
+			jen.Case(jen.Id("status").Op(">=").Lit(400).Op("&&").Id("status").Op("<").Lit(500)).Block(jen.Return(jen.Lit("CLIENT_ERROR"))),
+			jen.Case(jen.Id("status").Op(">=").Lit(500)).Block(jen.Return(jen.Lit("UPSTREAM_ERROR"))),
+		),
+		jen.Return(jen.Lit("UNEXPECTED_STATUS")),
+	)
+}
+
+// addOverrideMethod adds the request editor sending the requests as POST with
+// the real method in the method override header
+func addOverrideMethod(f *jen.File) {
+	f.Comment("overrideMethod sends the request as POST with its method in the " + CLI.MethodOverrideHeader + " header")
+	f.Func().Id("overrideMethod").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Error().Block(
+		jen.If(jen.Id("req").Dot("Method").Op("!=").Qual("net/http", "MethodPost")).Block(
+			jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit(CLI.MethodOverrideHeader), jen.Id("req").Dot("Method")),
+			jen.Id("req").Dot("Method").Op("=").Qual("net/http", "MethodPost"),
+		),
+		jen.Return(jen.Nil()),
+	)
+}
+
+// addCustomHeaders adds the request editor setting the headers given to a tool call
+func addCustomHeaders(f *jen.File) {
+	f.Comment("customHeaders returns a request editor setting the given headers on the request")
+	f.Func().Id("customHeaders").Params(
+		jen.Id("headers").Map(jen.String()).String(),
+	).Qual(CLI.ClientImport, "RequestEditorFn").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).Error().Block(
+			jen.For(jen.List(jen.Id("name"), jen.Id("value")).Op(":=").Range().Id("headers")).Block(
+				jen.Id("req").Dot("Header").Dot("Set").Call(jen.Id("name"), jen.Id("value")),
+			),
+			jen.Return(jen.Nil()),
+		)),
+	)
+}
+
+// addPreviewRequest adds the request editor stopping the requests of dry runs
+func addPreviewRequest(f *jen.File) {
+	f.Comment("errDryRun stops the requests of dry runs before they are sent")
+	f.Var().Id("errDryRun").Op("=").Qual("errors", "New").Call(jen.Lit("dry run"))
+
+	f.Comment("previewRequest returns a request editor that, in dry runs, describes the request")
+	f.Comment("into preview and stops it with errDryRun")
+	f.Func().Id("previewRequest").Params(
+		jen.Id("dryRun").Bool(),
+		jen.Id("preview").Op("*").String(),
+	).Qual(CLI.ClientImport, "RequestEditorFn").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).Error().Block(
+			jen.If(jen.Op("!").Id("dryRun")).Block(
+				jen.Return(jen.Nil()),
+			),
+			jen.Var().Id("body").Index().Byte(),
+			jen.If(jen.Id("req").Dot("Body").Op("!=").Nil()).Block(
+				jen.Var().Err().Error(),
+				jen.If(jen.List(jen.Id("body"), jen.Err()).Op("=").Qual("io", "ReadAll").Call(jen.Id("req").Dot("Body")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Err()),
+				),
+			),
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "MarshalIndent").Call(jen.Map(jen.String()).String().Values(jen.Dict{
+				jen.Lit("method"): jen.Id("req").Dot("Method"),
+				jen.Lit("url"):    jen.Id("req").Dot("URL").Dot("String").Call(),
+				jen.Lit("body"):   jen.String().Call(jen.Id("body")),
+			}), jen.Lit(""), jen.Lit("  ")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+			jen.Op("*").Id("preview").Op("=").String().Call(jen.Id("data")),
+			jen.Return(jen.Id("errDryRun")),
+		)),
+	)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitNote(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.Header().Set("X-RateLimit-Reset", "1760000000")
+		w.Header().Set("X-Quota-Left", "7")
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--surface-rate-limits")
+	result := startServer(t, binary, nil, "--host", upstream.URL).callTool(t, "ListBooks", map[string]any{})
+	if want := "[]\n[rate limit: X-RateLimit-Remaining=42, X-RateLimit-Reset=1760000000]"; result.text() != want {
+		t.Errorf("the result is %q, want %q", result.text(), want)
+	}
+
+	// The configured headers replace the common ones
+	binary = buildServer(t, booksSpec, "--surface-rate-limits", "--rate-limit-headers", "X-Quota-Left")
+	result = startServer(t, binary, nil, "--host", upstream.URL).callTool(t, "ListBooks", map[string]any{})
+	if want := "[]\n[rate limit: X-Quota-Left=7]"; result.text() != want {
+		t.Errorf("the result with configured headers is %q, want %q", result.text(), want)
+	}
+}
+
+func TestOperationMeta(t *testing.T) {
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", `[{"Id":1}]`))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--attach-operation-meta")
+	result := startServer(t, binary, nil, "--host", upstream.URL+"/v2").callTool(t, "ListBooks", map[string]any{})
+	if result.IsError || len(result.Content) != 2 {
+		t.Fatalf("ListBooks returned %+v", result)
+	}
+
+	// The body is left untouched, the metadata has its own content with the
+	// path resolved against the host
+	if got := result.Content[0].Text; got != `[{"Id":1}]` {
+		t.Errorf("the body is %q", got)
+	}
+	if got, want := result.Content[1].Text, `{"operation":{"method":"GET","operationId":"ListBooks","path":"/v2/ListBooks"}}`; got != want {
+		t.Errorf("the metadata is %q, want %q", got, want)
+	}
+}
+
+func TestMimeAnnotation(t *testing.T) {
+	var handler http.HandlerFunc
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/envelope.yaml", "--auth-type", "none", "--annotate-mime")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The parameters of the media type are dropped
+	tests := []struct {
+		contentType, body, want string
+	}{
+		{"application/json; charset=utf-8", `{"items":[]}`, `{"mimeType":"application/json"}`},
+		{"application/xml", `<books/>`, `{"mimeType":"application/xml"}`},
+		{"text/plain", `no books`, `{"mimeType":"text/plain"}`},
+	}
+	for _, test := range tests {
+		handler = respond(http.StatusOK, test.contentType, test.body)
+		result := session.callTool(t, "ListBooks", map[string]any{})
+		if result.IsError || len(result.Content) != 2 {
+			t.Errorf("ListBooks returned %+v for %s", result, test.contentType)
+			continue
+		}
+		if got := result.Content[0].Text; got != test.body {
+			t.Errorf("the body is %q, want %q", got, test.body)
+		}
+		if got := result.Content[1].Text; got != test.want {
+			t.Errorf("the annotation of %s is %q, want %q", test.contentType, got, test.want)
+		}
+	}
+}
+
+func TestTimeoutOverride(t *testing.T) {
+	// The API answers after the delay given as name filter
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, _ := time.ParseDuration(r.URL.Query().Get("NameFilter"))
+		select {
+		case <-time.After(delay):
+			respond(http.StatusOK, "application/json", `[]`)(w, r)
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--default-timeout", "300ms", "--max-timeout", "1s")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		name      string
+		arguments map[string]any
+		fails     bool
+		maxTime   time.Duration
+	}{
+		{"default timeout", map[string]any{"NameFilter": "800ms"}, true, 700 * time.Millisecond},
+		{"requested timeout", map[string]any{"NameFilter": "800ms", "timeoutSeconds": 1}, false, 0},
+		{"clamped timeout", map[string]any{"NameFilter": "3s", "timeoutSeconds": 10}, true, 2 * time.Second},
+	}
+	for _, test := range tests {
+		start := time.Now()
+		result := session.callTool(t, "ListBooks", test.arguments)
+		elapsed := time.Since(start)
+		if result.IsError != test.fails {
+			t.Errorf("%s: the call returned %+v", test.name, result)
+		}
+		if test.fails && elapsed > test.maxTime {
+			t.Errorf("%s: the call failed after %v, want less than %v", test.name, elapsed, test.maxTime)
+		}
+	}
+}
+
+func TestPreviewMutations(t *testing.T) {
+	calls := make(chan string, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- r.Method + " " + r.URL.Path
+		respond(http.StatusOK, "application/json", `{"Id":1}`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--preview-mutations")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune", "dryRun": true})
+	if result.IsError {
+		t.Fatalf("the dry run failed: %s", result.text())
+	}
+	var preview struct{ Method, URL, Body string }
+	if err := json.Unmarshal([]byte(result.text()), &preview); err != nil {
+		t.Fatalf("the preview %q is not JSON: %v", result.text(), err)
+	}
+	if preview.Method != "PUT" || preview.URL != upstream.URL+"/AddBook" || preview.Body != `{"Name":"Dune"}` {
+		t.Errorf("the preview is %+v", preview)
+	}
+	select {
+	case call := <-calls:
+		t.Fatalf("the dry run called the API with %s", call)
+	default:
+	}
+
+	// Without dryRun the request is sent
+	if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); result.IsError {
+		t.Fatalf("AddBook failed: %s", result.text())
+	}
+	if got := <-calls; got != "PUT /AddBook" {
+		t.Errorf("the API was called with %s", got)
+	}
+}
+
+func TestStripResponseKeys(t *testing.T) {
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json",
+		`{"items":[{"id":1,"_links":{"self":"/books/1"}}],"_links":{"self":"/books"},"meta":{"total":1}}`))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/envelope.yaml", "--auth-type", "none", "--strip-response-keys", "_links,meta")
+	result := startServer(t, binary, nil, "--host", upstream.URL).callTool(t, "ListBooks", map[string]any{})
+
+	// Only the top-level keys are dropped
+	if want := `{"items":[{"id":1,"_links":{"self":"/books/1"}}]}`; result.IsError || result.text() != want {
+		t.Errorf("the stripped response is %q, want %q", result.text(), want)
+	}
+}
+
+func TestResponseKeyCase(t *testing.T) {
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json",
+		`{"bookId":12345678901234567890,"Book Title":"Dune","author_name":"Herbert","ISBNCode":"x","pages":[{"pageCount":412}]}`))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/envelope.yaml", "--auth-type", "none", "--response-key-case", "snake")
+	result := startServer(t, binary, nil, "--host", upstream.URL).callTool(t, "ListBooks", map[string]any{})
+
+	// The nested keys are converted too and the large number is not rounded
+	want := `{"author_name":"Herbert","book_id":12345678901234567890,"book_title":"Dune","isbn_code":"x","pages":[{"page_count":412}]}`
+	if result.IsError || result.text() != want {
+		t.Errorf("the converted response is %q, want %q", result.text(), want)
+	}
+}
+
+func TestLazyHandlers(t *testing.T) {
+	code := generate(t, booksSpec, "--lazy-handlers")
+	assertContains(t, code, `lazyHandler(func() func(api.ListBooksParams) (*mcp_golang.ToolResponse, error) {`)
+
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", `[{"Id":1}]`))
+	defer upstream.Close()
+	binary := buildServer(t, booksSpec, "--lazy-handlers")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The tools are advertised before any handler is built
+	if tools := session.listTools(t); len(tools) != 2 {
+		t.Errorf("the lazy server advertises %v", tools)
+	}
+	for range 2 {
+		if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError || result.text() != `[{"Id":1}]` {
+			t.Errorf("the lazy ListBooks returned %+v", result)
+		}
+	}
+}
+
+func TestResponseTransform(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `[{"Id":1,"Name":"Dune"}]`
+		if r.URL.Path == "/AddBook" {
+			body = `{"Id":1}`
+		}
+		respond(http.StatusOK, "application/json", body)(w, r)
+	}))
+	defer upstream.Close()
+
+	// The hook is replaced from another file of the package
+	binary := buildServer(t, booksSpec, "--with-response-transform")
+	dir := filepath.Dir(binary)
+	hook := `package main
+
+import (
+	"errors"
+	"strings"
+)
+
+func init() {
+	transformResponse = func(op string, body []byte) ([]byte, error) {
+		if op != "ListBooks" {
+			return nil, errors.New("unexpected operation")
+		}
+		return []byte(strings.ToUpper(string(body))), nil
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "transform.go"), []byte(hook), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binary = compileServer(t, dir)
+
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError || result.text() != `[{"ID":1,"NAME":"DUNE"}]` {
+		t.Errorf("the transformed ListBooks returned %+v", result)
+	}
+	if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); !result.IsError || !strings.Contains(result.text(), "error transforming AddBook response: unexpected operation") {
+		t.Errorf("the failed transform returned %+v", result)
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	requests := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.Header.Get("X-HTTP-Method-Override")
+		body := `[]`
+		if r.URL.Path == "/AddBook" {
+			body = `{"Id":1}`
+		}
+		respond(http.StatusOK, "application/json", body)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--method-override-header", "X-HTTP-Method-Override")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		tool string
+		want string
+	}{
+		{"ListBooks", "POST GET"},
+		{"AddBook", "POST PUT"},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, test.tool, map[string]any{"Name": "Dune"}); result.IsError {
+			t.Fatalf("%s failed: %s", test.tool, result.text())
+		}
+		if got := <-requests; got != test.want {
+			t.Errorf("%s was sent as %q, want %q", test.tool, got, test.want)
+		}
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	var handler http.HandlerFunc
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--error-codes")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusNotFound, "error on ListBooks: [NOT_FOUND] 404 Not Found"},
+		{http.StatusUnauthorized, "error on ListBooks: [UNAUTHORIZED] 401 Unauthorized"},
+		{http.StatusTooManyRequests, "error on ListBooks: [RATE_LIMITED] 429 Too Many Requests"},
+		{http.StatusInternalServerError, "error on ListBooks: [UPSTREAM_ERROR] 500 Internal Server Error"},
+	}
+	for _, test := range tests {
+		handler = respond(test.status, "text/plain", "failed")
+		result := session.callTool(t, "ListBooks", map[string]any{})
+		if !result.IsError || !strings.Contains(result.text(), test.want) {
+			t.Errorf("the %d status returned %+v, want %q", test.status, result, test.want)
+		}
+	}
+}
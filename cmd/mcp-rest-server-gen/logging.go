@@ -0,0 +1,170 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// mcpTransport is the import path of the MCP transport package
+const mcpTransport = "github.com/metoro-io/mcp-golang/transport"
+
+// mcpLog returns the statement sending a MCP logging notification with the
+// formatted message, or nothing when MCP logging is disabled
+func mcpLog(level string, format string, args ...jen.Code) []jen.Code {
+	if !CLI.MCPLogging {
+		return nil
+	}
+	message := jen.Lit(format)
+	if len(args) > 0 {
+		message = jen.Qual("fmt", "Sprintf").Call(append([]jen.Code{jen.Lit(format)}, args...)...)
+	}
+	return []jen.Code{jen.Id("mcpLogger").Dot("log").Call(jen.Lit(level), message)}
+}
+
+// addLoggingTransport adds the transport wrapper sending MCP logging
+// notifications, the SDK does not support them so the wrapper handles the
+// logging/setLevel requests and advertises the logging capability itself
+func addLoggingTransport(f *jen.File) {
+	levels := []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+	levelsCode := make([]jen.Code, 0, len(levels))
+	for _, level := range levels {
+		levelsCode = append(levelsCode, jen.Lit(level))
+	}
+
+	f.Comment("mcpLogLevels are the MCP log levels by increasing severity")
+	f.Var().Id("mcpLogLevels").Op("=").Index().String().Values(levelsCode...)
+
+	f.Comment("loggingTransport wraps a transport to send MCP logging notifications at or above")
+	f.Comment("the level requested by the client")
+	f.Type().Id("loggingTransport").Struct(
+		jen.Qual(mcpTransport, "Transport"),
+		jen.Id("mu").Qual("sync", "Mutex"),
+		jen.Id("level").Int(),
+		jen.Id("initializeID").Op("*").Qual(mcpTransport, "RequestId"),
+	)
+
+	f.Comment("newLoggingTransport wraps the transport, logging at info level until the client sets it")
+	f.Func().Id("newLoggingTransport").Params(
+		jen.Id("t").Qual(mcpTransport, "Transport"),
+	).Op("*").Id("loggingTransport").Block(
+		jen.Return(jen.Op("&").Id("loggingTransport").Values(jen.Dict{
+			jen.Id("Transport"): jen.Id("t"),
+			jen.Id("level"):     jen.Qual("slices", "Index").Call(jen.Id("mcpLogLevels"), jen.Lit("info")),
+		})),
+	)
+
+	f.Comment("SetMessageHandler handles the logging/setLevel requests and passes the other messages")
+	f.Comment("to the handler, remembering the initialize request to advertise logging in its response")
+	f.Func().Params(jen.Id("t").Op("*").Id("loggingTransport")).Id("SetMessageHandler").Params(
+		jen.Id("handler").Func().Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("message").Op("*").Qual(mcpTransport, "BaseJsonRpcMessage")),
+	).Block(
+		jen.Id("t").Dot("Transport").Dot("SetMessageHandler").Call(
+			jen.Func().Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("message").Op("*").Qual(mcpTransport, "BaseJsonRpcMessage")).Block(
+				jen.If(jen.Id("message").Dot("Type").Op("==").Qual(mcpTransport, "BaseMessageTypeJSONRPCRequestType")).Block(
+					jen.Switch(jen.Id("message").Dot("JsonRpcRequest").Dot("Method")).Block(
+						jen.Case(jen.Lit("initialize")).Block(
+							jen.Id("id").Op(":=").Id("message").Dot("JsonRpcRequest").Dot("Id"),
+							jen.Id("t").Dot("mu").Dot("Lock").Call(),
+							jen.Id("t").Dot("initializeID").Op("=").Op("&").Id("id"),
+							jen.Id("t").Dot("mu").Dot("Unlock").Call(),
+						),
+						jen.Case(jen.Lit("logging/setLevel")).Block(
+							jen.Id("t").Dot("setLevel").Call(jen.Id("ctx"), jen.Id("message").Dot("JsonRpcRequest")),
+							jen.Return(),
+						),
+					),
+				),
+				jen.Id("handler").Call(jen.Id("ctx"), jen.Id("message")),
+			),
+		),
+	)
+
+	f.Comment("setLevel sets the minimum level of the notifications and answers the request")
+	f.Func().Params(jen.Id("t").Op("*").Id("loggingTransport")).Id("setLevel").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("request").Op("*").Qual(mcpTransport, "BaseJSONRPCRequest"),
+	).Block(
+		jen.Var().Id("params").Struct(
+			jen.Id("Level").String().Tag(map[string]string{"json": "level"}),
+		),
+		jen.Id("level").Op(":=").Lit(-1),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("request").Dot("Params"), jen.Op("&").Id("params")), jen.Err().Op("==").Nil()).Block(
+			jen.Id("level").Op("=").Qual("slices", "Index").Call(jen.Id("mcpLogLevels"), jen.Id("params").Dot("Level")),
+		),
+		jen.If(jen.Id("level").Op("<").Lit(0)).Block(
+			jen.Id("_").Op("=").Id("t").Dot("Transport").Dot("Send").Call(jen.Id("ctx"), jen.Qual(mcpTransport, "NewBaseMessageError").Call(
+				jen.Op("&").Qual(mcpTransport, "BaseJSONRPCError").Values(jen.Dict{
+					jen.Id("Id"):      jen.Id("request").Dot("Id"),
+					jen.Id("Jsonrpc"): jen.Lit("2.0"),
+					jen.Id("Error"): jen.Qual(mcpTransport, "BaseJSONRPCErrorInner").Values(jen.Dict{
+						jen.Id("Code"):    jen.Lit(-32602),
+						jen.Id("Message"): jen.Qual("fmt", "Sprintf").Call(jen.Lit("invalid log level %q"), jen.Id("params").Dot("Level")),
+					}),
+				}),
+			)),
+			jen.Return(),
+		),
+
+		jen.Id("t").Dot("mu").Dot("Lock").Call(),
+		jen.Id("t").Dot("level").Op("=").Id("level"),
+		jen.Id("t").Dot("mu").Dot("Unlock").Call(),
+
+		jen.Id("_").Op("=").Id("t").Dot("Transport").Dot("Send").Call(jen.Id("ctx"), jen.Qual(mcpTransport, "NewBaseMessageResponse").Call(
+			jen.Op("&").Qual(mcpTransport, "BaseJSONRPCResponse").Values(jen.Dict{
+				jen.Id("Id"):      jen.Id("request").Dot("Id"),
+				jen.Id("Jsonrpc"): jen.Lit("2.0"),
+				jen.Id("Result"):  jen.Qual("encoding/json", "RawMessage").Call(jen.Lit("{}")),
+			}),
+		)),
+	)
+
+	f.Comment("Send sends the message, adding the logging capability to the initialize response")
+	f.Func().Params(jen.Id("t").Op("*").Id("loggingTransport")).Id("Send").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("message").Op("*").Qual(mcpTransport, "BaseJsonRpcMessage"),
+	).Error().Block(
+		jen.If(jen.Id("message").Dot("Type").Op("==").Qual(mcpTransport, "BaseMessageTypeJSONRPCResponseType")).Block(
+			jen.Id("t").Dot("mu").Dot("Lock").Call(),
+			jen.Id("initialize").Op(":=").Id("t").Dot("initializeID").Op("!=").Nil().Op("&&").Op("*").Id("t").Dot("initializeID").Op("==").Id("message").Dot("JsonRpcResponse").Dot("Id"),
+			jen.Id("t").Dot("mu").Dot("Unlock").Call(),
+
+			jen.Var().Id("result").Map(jen.String()).Any(),
+			jen.If(jen.Id("initialize").Op("&&").Qual("encoding/json", "Unmarshal").Call(jen.Id("message").Dot("JsonRpcResponse").Dot("Result"), jen.Op("&").Id("result")).Op("==").Nil()).Block(
+				jen.If(jen.List(jen.Id("capabilities"), jen.Id("ok")).Op(":=").Id("result").Index(jen.Lit("capabilities")).Assert(jen.Map(jen.String()).Any()), jen.Id("ok")).Block(
+					jen.Id("capabilities").Index(jen.Lit("logging")).Op("=").Map(jen.String()).Any().Values(),
+					jen.If(jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("result")), jen.Err().Op("==").Nil()).Block(
+						jen.Id("message").Dot("JsonRpcResponse").Dot("Result").Op("=").Id("data"),
+					),
+				),
+			),
+		),
+		jen.Return(jen.Id("t").Dot("Transport").Dot("Send").Call(jen.Id("ctx"), jen.Id("message"))),
+	)
+
+	f.Comment("log sends a logging notification when the level is at or above the requested one")
+	f.Func().Params(jen.Id("t").Op("*").Id("loggingTransport")).Id("log").Params(
+		jen.List(jen.Id("level"), jen.Id("data")).String(),
+	).Block(
+		jen.Id("t").Dot("mu").Dot("Lock").Call(),
+		jen.Id("enabled").Op(":=").Qual("slices", "Index").Call(jen.Id("mcpLogLevels"), jen.Id("level")).Op(">=").Id("t").Dot("level"),
+		jen.Id("t").Dot("mu").Dot("Unlock").Call(),
+		jen.If(jen.Op("!").Id("enabled")).Block(
+			jen.Return(),
+		),
+
+		jen.List(jen.Id("params"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Map(jen.String()).String().Values(jen.Dict{
+			jen.Lit("level"):  jen.Id("level"),
+			jen.Lit("logger"): jen.Lit(CLI.ClientPackage),
+			jen.Lit("data"):   jen.Id("data"),
+		})),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(),
+		),
+		jen.Id("_").Op("=").Id("t").Dot("Transport").Dot("Send").Call(jen.Qual("context", "TODO").Call(), jen.Qual(mcpTransport, "NewBaseMessageNotification").Call(
+			jen.Op("&").Qual(mcpTransport, "BaseJSONRPCNotification").Values(jen.Dict{
+				jen.Id("Jsonrpc"): jen.Lit("2.0"),
+				jen.Id("Method"):  jen.Lit("notifications/message"),
+				jen.Id("Params"):  jen.Id("params"),
+			}),
+		)),
+	)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// logMessages returns the level and data of the logging notifications
+func logMessages(t *testing.T, notifications []map[string]json.RawMessage) []string {
+	t.Helper()
+	var messages []string
+	for _, notification := range notifications {
+		if string(notification["method"]) != `"notifications/message"` {
+			continue
+		}
+		var params struct{ Level, Data string }
+		if err := json.Unmarshal(notification["params"], &params); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, params.Level+": "+params.Data)
+	}
+	return messages
+}
+
+func TestMCPLogging(t *testing.T) {
+	code := generate(t, booksSpec, "--mcp-logging")
+	assertContains(t, code,
+		`Method:  "notifications/message"`,
+		`mcpLogger.log("debug", "calling ListBooks")`,
+		`case "logging/setLevel":`,
+	)
+	code = generate(t, booksSpec)
+	assertNotContains(t, code, "notifications/message", "mcpLogger")
+
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", `[]`))
+	defer upstream.Close()
+	binary := buildServer(t, booksSpec, "--mcp-logging")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	result, _ := session.request(t, "initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "test", "version": "1.0"},
+	})
+	if !strings.Contains(string(result), `"logging":{}`) {
+		t.Errorf("the initialize result does not advertise logging: %s", result)
+	}
+
+	// The server logs at info level until the client sets the level
+	_, notifications := session.request(t, "tools/call", map[string]any{"name": "ListBooks", "arguments": map[string]any{}})
+	if got, want := logMessages(t, notifications), []string{"info: ListBooks returned 200 OK"}; !slices.Equal(got, want) {
+		t.Errorf("the notifications at info level are %q, want %q", got, want)
+	}
+
+	session.request(t, "logging/setLevel", map[string]any{"level": "debug"})
+	_, notifications = session.request(t, "tools/call", map[string]any{"name": "ListBooks", "arguments": map[string]any{}})
+	if got, want := logMessages(t, notifications), []string{"debug: calling ListBooks", "info: ListBooks returned 200 OK"}; !slices.Equal(got, want) {
+		t.Errorf("the notifications at debug level are %q, want %q", got, want)
+	}
+}
@@ -1,42 +1,249 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/dave/jennifer/jen"
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/renato0307/go-mcp-rest/internal/specload"
+	"github.com/renato0307/go-mcp-rest/tooldesc"
 )
 
 // CLI represents the command-line interface configuration
 var CLI struct {
-	Spec          string `help:"Path or URL to the OpenAPI specification" default:"https://converter.swagger.io/api/convert?url=https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend/swagger.json"`
-	Output        string `help:"Output file for the generated code" default:"./generated/main.go"`
-	Package       string `help:"Package name for the generated code" default:"main"`
-	ClientPackage string `help:"Name of the client package" default:"api"`
-	ClientImport  string `help:"Import path for the client package" default:"github.com/renato0307/go-mcp-rest/generated/api"`
-	ServerURL     string `help:"URL of the API server" default:"https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend"`
-	UsernameEnv   string `help:"Environment variable name for username" default:"API_USERNAME"`
-	PasswordEnv   string `help:"Environment variable name for password" default:"API_PASSWORD"`
+	Spec                   string            `help:"Path or URL to the OpenAPI specification, zip and tarball bundles are extracted, configmap://namespace/name/key reads a ConfigMap key, - reads standard input" default:"https://converter.swagger.io/api/convert?url=https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend/swagger.json"`
+	SpecEntry              string            `help:"Path of the root spec inside a spec bundle, found by name when empty"`
+	SpecHeaders            []string          `name:"spec-header" help:"Header sent when fetching a remote spec, as Name: Value, repeatable" sep:"none"`
+	SpecBearer             string            `help:"Bearer token sent when fetching a remote spec"`
+	SpecCacheDir           string            `help:"Directory caching the fetched remote specs by URL, shared with mcp-rest-client-gen, no cache when empty"`
+	SpecCacheTTL           time.Duration     `name:"spec-cache-ttl" help:"Time a spec cached in --spec-cache-dir is reused before being fetched again" default:"1h"`
+	SynthesizeIDs          bool              `name:"synthesize-ids" help:"Give the operations without an operationId one made of their method and path, such as GetBooksById for GET /books/{id}, instead of skipping them"`
+	DedupeStrategy         string            `help:"What to do with the operations sharing an operationId, rename suffixes their IDs with the path segment telling them apart" aliases:"on-duplicate" enum:"error,rename" default:"error"`
+	PathFilter             []string          `help:"Only generate tools for the paths matching one of the patterns, such as /admin or /admin/*, * matches within a path segment and ** across segments"`
+	Output                 string            `help:"Output file for the generated code" default:"./generated/main.go"`
+	Package                string            `help:"Package name for the generated code" default:"main"`
+	ClientPackage          string            `help:"Name of the client package" default:"api"`
+	ClientImport           string            `help:"Import path for the client package" default:"github.com/renato0307/go-mcp-rest/generated/api"`
+	WithClient             bool              `help:"Also generate the REST client into --client-output-dir with oapi-codegen, the import path of the client package is then derived from the go.mod above it"`
+	ClientOutputDir        string            `help:"Directory of the REST client generated with --with-client" default:"./generated/api"`
+	ServerURL              string            `help:"URL of the API server, the first server of the spec is used when left at its default" default:"${defaultServerURL}"`
+	ServerVars             map[string]string `name:"server-var" help:"Values of the variables of the server URLs of the spec, replacing their defaults (name=value, repeatable)"`
+	UsernameEnv            string            `help:"Environment variable name for username" default:"API_USERNAME"`
+	PasswordEnv            string            `help:"Environment variable name for password" default:"API_PASSWORD"`
+	AuthType               string            `help:"Auth of the generated server, basic sends a username and password, bearer a token, apikey a key header, oauth2 the tokens of a client credentials flow, auto detects it from the security schemes of the spec" enum:"auto,basic,bearer,apikey,oauth2,none" default:"basic"`
+	SecurityAlternative    string            `help:"Security requirement of the spec to satisfy when it accepts several, as the names of its schemes joined by +, such as apiKey+tenantKey, the credentials of all its schemes are sent, with --auth-type=auto"`
+	TokenEnv               string            `help:"Environment variable name for the bearer token" default:"API_TOKEN"`
+	APIKeyEnv              string            `name:"apikey-env" help:"Environment variable name for the API key" default:"API_KEY"`
+	APIKeyName             string            `name:"apikey-name" help:"Header carrying the API key with --auth-type=apikey" default:"X-API-Key"`
+	TokenURL               string            `help:"Default token endpoint of the oauth2 client credentials flow, the one of the spec when empty"`
+	TokenURLEnv            string            `help:"Environment variable name for the oauth2 token endpoint" default:"API_TOKEN_URL"`
+	ClientIDEnv            string            `help:"Environment variable name for the oauth2 client ID" default:"API_CLIENT_ID"`
+	ClientSecretEnv        string            `help:"Environment variable name for the oauth2 client secret" default:"API_CLIENT_SECRET"`
+	DescribeLinks          bool              `help:"Experimental: document OpenAPI response links in the tool descriptions"`
+	AcceptLanguage         string            `help:"Default Accept-Language header sent to the API (empty to disable)"`
+	AcceptLanguageEnv      string            `help:"Environment variable name for overriding the Accept-Language header" default:"API_ACCEPT_LANGUAGE"`
+	ReloadOnSighup         bool              `help:"Generate a server that reloads the tool descriptions from the spec file on SIGHUP"`
+	SpecFileEnv            string            `help:"Environment variable name for the spec file reloaded on SIGHUP" default:"API_SPEC_FILE"`
+	DynamicTools           bool              `help:"Generate a server whose tools can be disabled and enabled at runtime, notifying the clients of the tool list changes"`
+	FilterQuery            bool              `help:"Add a query argument to the list operations with several query parameters, translating filters given in plain words into these parameters"`
+	FilterRules            string            `help:"YAML or JSON file of the filter rules of --filter-query, mapping phrases to the query parameter values they stand for"`
+	ProfilesFile           string            `help:"YAML or JSON file of config profiles embedded in the server, mapping profile names to the server flags they set, selected with --profile"`
+	EmitEnvDoc             bool              `help:"Write an ENVIRONMENT.md next to the output listing the environment variables read by the server"`
+	WithSpecTool           bool              `help:"Embed the OpenAPI spec in the server and register a tool returning it"`
+	BatchTool              bool              `help:"Register a batch tool calling several operation tools at once, reporting the result or the error of each call"`
+	ExplainTool            bool              `help:"Register an explain tool returning the HTTP request an operation would send for given arguments, without sending it"`
+	ValidateResponses      bool              `help:"Embed the OpenAPI spec in the server and flag the responses not matching the schema declared for their success status"`
+	ProblemDetails         bool              `help:"Parse RFC 7807 problem details responses of all operations into readable tool errors"`
+	AutoPaginate           bool              `help:"Follow RFC 5988 Link header pagination on operations declaring a Link response header"`
+	MaxPages               int               `help:"Default maximum number of pages fetched when auto paginating" default:"10"`
+	PageTokenParam         string            `help:"Query parameter carrying the page token, adds offset and limit arguments to the GET tools declaring it"`
+	NextPageTokenField     string            `help:"Field of the JSON responses holding the next page token" default:"nextPageToken"`
+	PageItemsField         string            `help:"Field of the JSON responses holding the page items" default:"items"`
+	CursorParam            string            `help:"Query parameter carrying the page cursor, the GET tools declaring it take a cursor argument and return the next cursor"`
+	CursorFields           []string          `help:"Fields of the JSON responses holding the next page cursor, the first one declared by the response schema is used" default:"nextCursor,next_cursor,nextPageToken,next_page_token"`
+	OutputFormat           string            `help:"Format of the generation report printed to stdout" enum:"text,json" default:"text"`
+	MaxReadBytes           int64             `help:"Default maximum number of response body bytes read from the API, or returned with the tail and smart truncation strategies, 0 for no limit"`
+	TruncationStrategy     string            `help:"How the response bodies over --max-read-bytes are truncated, head keeps their start, tail their end and smart shortens their JSON arrays" enum:"head,tail,smart" default:"head"`
+	TLSServerName          string            `help:"Default TLS server name (SNI) used when connecting to the API"`
+	FallbackHost           string            `help:"Default API server host called once more when a call to the primary one fails with a network error or a 5xx status"`
+	CacheTTL               time.Duration     `help:"Default time the successful GET tool results are cached, 0 to disable caching"`
+	CacheBackend           string            `help:"Cache of the GET tool results, redis shares them between replicas through the Redis server given at runtime, falling back to memory without one" enum:"memory,redis" default:"memory"`
+	RedisURLEnv            string            `help:"Environment variable name for the Redis server URL of the redis cache backend" default:"API_REDIS_URL"`
+	Retry                  int               `help:"Default maximum number of attempts of the REST calls failing with a network error or a --retry-statuses status, 0 or 1 to not retry"`
+	RetryBackoff           time.Duration     `help:"Default wait before the first retry of a REST call, doubled at each retry" default:"500ms"`
+	RetryStatuses          []string          `help:"Status codes of the API responses retried with --retry, single codes or ranges such as 500-599" default:"502,503,504"`
+	RetryUnsafe            bool              `help:"Retry the POST and PATCH calls too with --retry, even without --idempotency-key-header, which may repeat their side effects"`
+	IdempotencyKeyHeader   string            `help:"Header carrying a random key, kept across the retries, on the POST and PATCH calls, which --retry then retries too, e.g. Idempotency-Key"`
+	DedupWindow            time.Duration     `help:"Default window within which a mutating tool call identical to a previous one returns the first result instead of calling the API again, 0 to disable"`
+	DescriptionSource      string            `help:"Text of the operations used as tool description, the other one is used when it is missing" enum:"description,summary,both" default:"description"`
+	DescriptionSuffixMap   map[string]string `help:"Text appended to the description of the given operations (operationId=suffix, separated by ;)"`
+	BodyTemplates          map[string]string `help:"Go template files building the request body of the given operations from the tool arguments (operationId=file, separated by ;)"`
+	ClientMethodTemplate   string            `help:"Go template of the client method called for each operation, executed with the operation info" default:"{{.ID}}WithResponse"`
+	WithClientInterface    bool              `help:"Call the REST client through an interface covering the methods used by the tools, so it can be replaced by a mock"`
+	ResponseStatusExpr     string            `help:"Expression returning the HTTP status code of the client response" default:"resp.StatusCode()"`
+	ResponseStatusTextExpr string            `help:"Expression returning the HTTP status text of the client response" default:"resp.Status()"`
+	ResponseBodyExpr       string            `help:"Expression returning the body of the client response" default:"resp.Body"`
+	MethodOverrideHeader   string            `help:"Send all the API requests as POST with the real method in this header, e.g. X-HTTP-Method-Override"`
+	SuccessCodes           []string          `help:"Status codes of the API responses returned as tool results, single codes or ranges such as 200-299" default:"200-299"`
+	ErrorCodes             bool              `help:"Prefix the tool errors on API error statuses with a stable code such as NOT_FOUND or RATE_LIMITED"`
+	MaxErrorBody           int               `help:"Default maximum number of response body bytes included in the tool errors on API error statuses, 0 to leave the body out" default:"2048"`
+	AllowCustomHeaders     bool              `help:"Add a headers argument to the tools setting extra headers on the API requests of the call"`
+	AllowFieldProjection   bool              `help:"Add an optional fields tool argument projecting the JSON response down to the requested fields"`
+	AllowResponseFormat    bool              `help:"Add an optional format tool argument rendering the JSON response as sent, indented or as a text table of an array of objects"`
+	WithCredentialTool     bool              `help:"Add a tool replacing the API credentials at runtime, it must also be enabled when running the server"`
+	DefaultTimeout         time.Duration     `aliases:"request-timeout" help:"Default timeout of the API calls, 0 for no timeout"`
+	MaxTimeout             time.Duration     `help:"Maximum timeout the tool calls can request through a timeoutSeconds argument, 0 to not add the argument"`
+	SLABudgets             map[string]string `name:"sla-budget" help:"Latency budgets of the given operations (operationId=duration, separated by ;), the results of slower calls are flagged as over the budget"`
+	Strict                 bool              `help:"Fail instead of warning when the spec uses features that cannot be mapped to tools"`
+	PreviewMutations       bool              `help:"Add a dryRun argument to the mutating tools returning the request that would be sent instead of sending it"`
+	RecordSpecSource       bool              `help:"Embed the spec source and content hash in the server and log them at startup"`
+	MCPLibrary             string            `name:"mcp-library" help:"MCP library used by the generated server" enum:"metoro,mark3labs" default:"metoro"`
+	Transport              string            `help:"MCP transport of the generated server, sse and http (streamable HTTP) serve the MCP clients over HTTP and need --mcp-library=mark3labs" enum:"stdio,sse,http" default:"stdio"`
+	ListenAddr             string            `help:"Default address the generated server listens on with the sse and http transports" default:":8080"`
+	ClientAuthToken        bool              `help:"Reject the MCP requests of the sse and http transports without the bearer token given to the generated server"`
+	ClientAuthTokenEnv     string            `help:"Environment variable name for the bearer token of the MCP clients" default:"MCP_CLIENT_AUTH_TOKEN"`
+	MCPLogging             bool              `name:"mcp-logging" help:"Send MCP logging notifications about the tool calls to the client, at the level it requests"`
+	LogRequests            bool              `help:"Log the start and the end of each tool call with slog on standard error, with the operation, its method and path, the status code of the API response and the elapsed time"`
+	AnnotateMime           bool              `help:"Attach the media type of the API response as a separate JSON content to the tool results"`
+	AttachOperationMeta    bool              `help:"Attach the operationId, method and resolved path of the call as a separate JSON content to the tool results"`
+	SurfaceRateLimits      bool              `help:"Append the rate-limit headers of the API responses to the tool results"`
+	MaxTools               int               `help:"Maximum number of operation tools generated, 0 for no limit"`
+	MaxToolsPolicy         string            `help:"What to do when the spec yields more tools than the maximum, truncate keeps the first operations by ID" enum:"error,truncate" default:"error"`
+	CoerceBooleanParams    bool              `help:"Accept loose values such as yes, 1 or off for the boolean query parameters"`
+	HideWriteOnly          bool              `help:"Remove the writeOnly properties of the response schemas from the JSON responses returned by the tools"`
+	EnforceEnums           bool              `help:"Reject the tool calls whose arguments hold a value outside the enum of their schema, listing the valid values"`
+	LazyHandlers           bool              `help:"Build the handler of each tool on its first call instead of at startup, the tools are still all advertised"`
+	ResponseKeyCase        string            `help:"Rewrite the keys of the JSON responses recursively to the case" enum:",camel,snake" default:""`
+	WithResponseTransform  bool              `help:"Pass the response bodies through a transformResponse hook, a no-op replaceable from another file of the package"`
+	HandlerPlugins         []string          `help:"Plugins compiled into the generator injecting statements into the tool handlers, applied in order"`
+	StripResponseKeys      []string          `help:"Top-level keys dropped from the JSON object responses, e.g. _links,meta"`
+	RateLimitHeaders       []string          `help:"Names of the rate-limit headers appended to the tool results" default:"X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"`
 }
 
 // OperationInfo holds information about an API operation
 type OperationInfo struct {
 	ID             string
+	Method         string
+	Path           string
 	Summary        string
 	Description    string
 	ParameterType  string
 	HasRequestBody bool
+	// BodyIsObject is set when the JSON request body is an object
+	BodyIsObject bool
+	// BodyRequired is set when the request body must be sent
+	BodyRequired bool
+	Parameters   []ParameterInfo
+	// ResponseContentTypes lists the media types of all declared responses
+	ResponseContentTypes []string
+	// HasLinkPagination is set for GET operations with a Link response header
+	HasLinkPagination bool
+	// HasResponseSchema is set when a success response declares a JSON schema
+	HasResponseSchema bool
+	// HasPageToken is set for GET operations with the page token query parameter
+	HasPageToken bool
+	// CursorField is the response field holding the next page cursor of GET
+	// operations with the cursor query parameter, empty when there is none
+	CursorField string
+	// RequiresAuth is set when the effective security of the operation is not empty
+	RequiresAuth bool
+	// Callbacks lists the sorted names of the callbacks declared by the operation
+	Callbacks []string
+	// BodyDiscriminator is the property selecting the variant of a discriminated body
+	BodyDiscriminator string
+	// BodyVariants lists the variants of a discriminated body
+	BodyVariants []BodyVariant
+	// BodyInlineUnion is set when the discriminated body is an inline union,
+	// sent raw as its request body type cannot hold the variants
+	BodyInlineUnion bool
+	// Category is the tool category given with the x-mcp-category extension
+	Category string
+	// BodyTemplate is the Go template building the request body, if any
+	BodyTemplate string
+	// BodyTemplateFields are the tool arguments used by the body template
+	BodyTemplateFields []ConfigField
+	// FreeFormBody is set when the JSON request body has no schema, the tool
+	// takes it as raw JSON
+	FreeFormBody bool
+	// BodyExample is the JSON of the example of a free-form body, if any
+	BodyExample string
+	// SLABudget is the latency budget of the calls, 0 when there is none
+	SLABudget time.Duration
+	// ResponseVariants lists the success responses when they declare different
+	// body schemas, the tool result names the one received
+	ResponseVariants []ResponseVariant
+	// Enums holds the allowed values of the enum-constrained tool arguments,
+	// by argument name
+	Enums map[string][]string
+	// ReadOnlyProperties are the dotted paths of the readOnly properties of
+	// the JSON request body, removed before it is sent
+	ReadOnlyProperties []string
+	// WriteOnlyProperties are the dotted paths of the writeOnly properties of
+	// the JSON success response bodies
+	WriteOnlyProperties []string
+}
+
+// IsMutating reports whether the operation may have side effects, all methods
+// but the safe GET, HEAD and OPTIONS ones
+func (op OperationInfo) IsMutating() bool {
+	return op.Method != "GET" && op.Method != "HEAD" && op.Method != "OPTIONS"
+}
+
+// IsIdempotent reports whether making the operation twice has the effect of
+// making it once, the safe methods and PUT and DELETE
+func (op OperationInfo) IsIdempotent() bool {
+	return !op.IsMutating() || op.Method == "PUT" || op.Method == "DELETE"
+}
+
+// HasResponseContentType reports whether any response declares the media type
+func (op OperationInfo) HasResponseContentType(mediaType string) bool {
+	for _, contentType := range op.ResponseContentTypes {
+		if contentType == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigField holds a field of the generated server configuration
+type ConfigField struct {
+	Name string
+	Type jen.Code
+	Tags map[string]string
+}
+
+// ParameterInfo holds information about an operation parameter
+type ParameterInfo struct {
+	Name    string
+	In      string
+	Style   string
+	Explode bool
+	// ContentType is the media type of parameters declaring content instead
+	// of schema, the client sends them encoded as a whole
+	ContentType string
+	// IsBoolean is set when the parameter schema is a boolean
+	IsBoolean bool
+	// Required and Description are the ones declared by the parameter
+	Required    bool
+	Description string
+	// Schema is the schema of the parameter value, the one of its content
+	// when declared with content, nil when there is none
+	Schema *openapi3.Schema
 }
 
 func main() {
-	ctx := kong.Parse(&CLI, kong.Name("mcp-rest-server-gen"), kong.Description("Generate a MCP server from an OpenAPI spec"))
+	ctx := kong.Parse(&CLI, kong.Name("mcp-rest-server-gen"), kong.Description("Generate a MCP server from an OpenAPI spec"), kong.Vars{"defaultServerURL": defaultServerURL})
 
 	// If output path is empty, generate it from the URL
 	if CLI.Output == "" {
@@ -50,13 +257,20 @@ func main() {
 		}
 
 		CLI.Output = filepath.Join(outputDir, "main.go")
-		fmt.Printf("Output file not specified, using: %s\n", CLI.Output)
+		logf("Output file not specified, using: %s\n", CLI.Output)
 	}
 
 	// Generate MCP server code
+	start := time.Now()
 	if err := generateMCPServer(); err != nil {
 		ctx.FatalIfErrorf(err)
 	}
+	report.Timings["totalMs"] = time.Since(start).Milliseconds()
+
+	if CLI.OutputFormat == "json" {
+		ctx.FatalIfErrorf(report.print(os.Stdout))
+		return
+	}
 
 	fmt.Printf("MCP server generated successfully: %s\n", CLI.Output)
 }
@@ -81,64 +295,178 @@ func extractAppNameFromURL(urlStr string) string {
 	return "app"
 }
 
-// loadOpenAPISpec loads an OpenAPI specification from either a file or URL
-func loadOpenAPISpec(specPath string) (*openapi3.T, error) {
+// stdinSpec is the spec path reading the spec from standard input
+const stdinSpec = "-"
+
+// loadOpenAPISpec loads an OpenAPI specification from either a file or URL,
+// returning it with the raw content it was loaded from
+func loadOpenAPISpec(specPath string) (*openapi3.T, []byte, error) {
 	var doc *openapi3.T
+	var content []byte
 	loader := openapi3.NewLoader()
 
 	// Check if the path is a URL
 	parsedURL, parseErr := url.Parse(specPath)
-	if parseErr == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") {
-		// It's a URL, load from URL
-		fmt.Printf("Loading OpenAPI spec from URL: %s\n", specPath)
+	if specPath == stdinSpec {
+		// It's piped, read it whole from standard input
+		logf("Loading OpenAPI spec from standard input\n")
 
-		// Fetch the content
-		resp, err := http.Get(specPath)
+		var err error
+		content, err = io.ReadAll(os.Stdin)
 		if err != nil {
-			return nil, fmt.Errorf("error fetching from URL: %w", err)
+			return nil, nil, fmt.Errorf("error reading OpenAPI spec from standard input: %w", err)
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+		// The piped spec has no location, its relative references are
+		// resolved against the working directory
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error getting working directory: %w", err)
 		}
+		loader.IsExternalRefsAllowed = true
+		doc, err = loader.LoadFromDataWithPath(content, &url.URL{Path: filepath.ToSlash(filepath.Join(dir, "openapi.yaml"))})
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
+		}
+	} else if isConfigMapSpec(specPath) {
+		// It's a ConfigMap key, read it from the cluster
+		logf("Loading OpenAPI spec from ConfigMap: %s\n", specPath)
 
-		// Read the content
-		content, err := io.ReadAll(resp.Body)
+		client, err := newKubernetesClient()
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err = readConfigMapSpec(context.Background(), client, specPath)
 		if err != nil {
-			return nil, fmt.Errorf("error reading response body: %w", err)
+			return nil, nil, err
 		}
 
-		// Parse the document
-		// LoadFromData automatically handles both JSON and YAML formats
 		doc, err = loader.LoadFromData(content)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
+			return nil, nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
+		}
+	} else if isSpecBundle(specPath) {
+		// It's a bundle, load the root spec with its referenced files
+		var err error
+		content, err = readSpecBundle(specPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading spec bundle: %w", err)
+		}
+
+		dir, err := extractSpecBundle(content)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		entry, err := findSpecEntry(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		logf("Loading OpenAPI spec %s from bundle: %s\n", strings.TrimPrefix(entry, dir+string(os.PathSeparator)), specPath)
+		loader.IsExternalRefsAllowed = true
+		doc, err = loader.LoadFromFile(entry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading OpenAPI spec from bundle: %w", err)
+		}
+	} else if parseErr == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") {
+		// It's a URL, load from URL
+		logf("Loading OpenAPI spec from URL: %s\n", specPath)
+
+		// Fetch the content, with the spec headers
+		var readFromURI openapi3.ReadFromURIFunc
+		var err error
+		content, readFromURI, err = fetchSpec(parsedURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Parse the document, relative references are resolved against the URL
+		// LoadFromDataWithPath automatically handles both JSON and YAML formats
+		loader.IsExternalRefsAllowed = true
+		loader.ReadFromURIFunc = readFromURI
+		doc, err = loader.LoadFromDataWithPath(content, parsedURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
 		}
 	} else {
-		// It's a file path, load from file
-		fmt.Printf("Loading OpenAPI spec from file: %s\n", specPath)
+		// It's a file path, load from file along with the files it references
+		logf("Loading OpenAPI spec from file: %s\n", specPath)
 		var err error
+		loader.IsExternalRefsAllowed = true
 		doc, err = loader.LoadFromFile(specPath)
 		if err != nil {
-			return nil, fmt.Errorf("error loading OpenAPI spec from file: %w", err)
+			return nil, nil, fmt.Errorf("error loading OpenAPI spec from file: %w", err)
+		}
+		content, err = os.ReadFile(specPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading OpenAPI spec file: %w", err)
+		}
+	}
+
+	// Operations without an operationId are skipped unless they are given one
+	if CLI.SynthesizeIDs {
+		for _, id := range specload.SynthesizeIDs(doc) {
+			logf("Synthesized operation ID %s\n", id)
+		}
+	} else {
+		for _, op := range specload.Operations(doc) {
+			if op.Operation.OperationID == "" {
+				warnf("operation %s %s has no operationId, skipping it, generate with --synthesize-ids to expose it", op.Method, op.Path)
+			}
 		}
 	}
 
+	// Operations sharing an operationId would otherwise fail the validation,
+	// or overwrite each other as tools
+	if CLI.DedupeStrategy == "rename" {
+		for _, rename := range specload.RenameDuplicates(doc) {
+			logf("Renamed duplicate operation %s\n", rename)
+		}
+	} else if err := specload.CheckDuplicates(doc); err != nil {
+		return nil, nil, err
+	}
+
 	// Validate the spec
 	if err := doc.Validate(loader.Context); err != nil {
-		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+		return nil, nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
 	}
 
-	return doc, nil
+	return doc, content, nil
 }
 
 func generateMCPServer() error {
+	if err := checkMCPLibrary(); err != nil {
+		return err
+	}
+	if err := checkCursorParam(); err != nil {
+		return err
+	}
+
 	// Load and parse OpenAPI spec
-	doc, err := loadOpenAPISpec(CLI.Spec)
+	loadStart := time.Now()
+	doc, specContent, err := loadOpenAPISpec(CLI.Spec)
+	if err != nil {
+		return err
+	}
+	report.Timings["loadMs"] = time.Since(loadStart).Milliseconds()
+
+	// The client generated along is imported from where it is written
+	if CLI.WithClient {
+		CLI.ClientImport, err = clientImportPath(CLI.ClientOutputDir)
+		if err != nil {
+			return err
+		}
+		logf("Importing the generated client as %s\n", CLI.ClientImport)
+	}
+
+	// Only the operations of the paths matching the filter become tools
+	pathFilter, err := newPathFilter(CLI.PathFilter)
 	if err != nil {
 		return err
 	}
+	pathFilter.warnUnmatched(doc.Paths.InMatchingOrder())
 
 	// Extract operations from the spec
 	operations := make(map[string]OperationInfo)
@@ -146,25 +474,49 @@ func generateMCPServer() error {
 	// Correctly iterate through paths
 	for path := range doc.Paths.Map() {
 		pathItem := doc.Paths.Find(path)
-		if pathItem == nil {
+		if pathItem == nil || !pathFilter.match(path) {
 			continue
 		}
 
 		// Process all operations for this path (GET, POST, etc.)
-		processOperation(path, "GET", pathItem.Get, operations)
-		processOperation(path, "POST", pathItem.Post, operations)
-		processOperation(path, "PUT", pathItem.Put, operations)
-		processOperation(path, "DELETE", pathItem.Delete, operations)
-		processOperation(path, "PATCH", pathItem.Patch, operations)
-		processOperation(path, "HEAD", pathItem.Head, operations)
-		processOperation(path, "OPTIONS", pathItem.Options, operations)
+		processOperation(path, "GET", pathItem.Get, pathItem.Parameters, doc.Security, operations)
+		processOperation(path, "POST", pathItem.Post, pathItem.Parameters, doc.Security, operations)
+		processOperation(path, "PUT", pathItem.Put, pathItem.Parameters, doc.Security, operations)
+		processOperation(path, "DELETE", pathItem.Delete, pathItem.Parameters, doc.Security, operations)
+		processOperation(path, "PATCH", pathItem.Patch, pathItem.Parameters, doc.Security, operations)
+		processOperation(path, "HEAD", pathItem.Head, pathItem.Parameters, doc.Security, operations)
+		processOperation(path, "OPTIONS", pathItem.Options, pathItem.Parameters, doc.Security, operations)
 	}
 
 	if len(operations) == 0 {
 		return fmt.Errorf("no valid operations found in the OpenAPI spec")
 	}
 
-	fmt.Printf("Found %d operations in the OpenAPI spec\n", len(operations))
+	logf("Found %d operations in the OpenAPI spec\n", len(operations))
+
+	for id := range CLI.DescriptionSuffixMap {
+		if _, ok := operations[id]; !ok {
+			warnf("description suffix given for unknown operation %s", id)
+		}
+	}
+
+	if err := checkCallbacks(operations); err != nil {
+		return err
+	}
+
+	if err := limitOperations(operations); err != nil {
+		return err
+	}
+
+	if err := loadBodyTemplates(doc, operations); err != nil {
+		return err
+	}
+
+	if err := loadSLABudgets(operations); err != nil {
+		return err
+	}
+
+	report.Operations = sortedOperationIDs(operations)
 
 	// Generate code using jennifer
 	f := jen.NewFile(CLI.Package)
@@ -177,139 +529,1218 @@ func generateMCPServer() error {
 	f.ImportName("os", "os")
 	f.ImportName("github.com/metoro-io/mcp-golang", "mcp_golang")
 	f.ImportName("github.com/metoro-io/mcp-golang/transport/stdio", "stdio")
+	f.ImportName(mark3labsMCP, "mcp")
+	f.ImportAlias(mark3labsServer, "mcpserver")
+	f.ImportName(redisPackage, "redis")
 	f.ImportName("github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider", "securityprovider")
 	f.ImportName("github.com/alecthomas/kong", "kong")
 	f.ImportName(CLI.ClientImport, CLI.ClientPackage)
 
+	auth, err := detectAuthScheme(doc)
+	if err != nil {
+		return err
+	}
+
+	// Fields of the generated server command-line interface
+	host, hostHelp, err := defaultHost(doc)
+	if err != nil {
+		return err
+	}
+	cliFields := []ConfigField{
+		{Name: "Host", Type: jen.String(), Tags: map[string]string{"help": hostHelp, "default": host}},
+	}
+	cliFields = append(cliFields, auth.Credentials...)
+	cliFields = append(cliFields, auth.Settings...)
+
+	// Options passed to the REST client, request editors are applied in order
+	clientOptions := []jen.Code{jen.Id("cli").Dot("Host")}
+
+	// The auth is applied by the client unless some operations do not need it
+	withAuth := auth.enabled() && anyRequiresAuth(operations)
+	perOperationAuth := authPerOperation(operations)
+
+	// Without auth there are no credentials to replace
+	credentialTool := CLI.WithCredentialTool && withAuth && len(auth.Parts) == 0
+	if CLI.WithCredentialTool && !withAuth {
+		warnf("credential tool not generated as no operation requires auth")
+	} else if CLI.WithCredentialTool && !credentialTool {
+		warnf("credential tool not generated as the credentials of combined security schemes cannot be replaced")
+	}
+	if withAuth && !perOperationAuth {
+		clientOptions = append(clientOptions, jen.Qual(CLI.ClientImport, "WithRequestEditorFn").Call(auth.intercept()))
+	}
+
+	// Every request goes through the override, including pagination ones
+	if CLI.MethodOverrideHeader != "" {
+		addOverrideMethod(f)
+		clientOptions = append(clientOptions, jen.Qual(CLI.ClientImport, "WithRequestEditorFn").Call(jen.Id("overrideMethod")))
+	}
+
+	if CLI.ReloadOnSighup {
+		cliFields = append(cliFields, ConfigField{
+			Name: "SpecFile", Type: jen.String(),
+			Tags: map[string]string{"help": "OpenAPI spec file reloaded on SIGHUP", "default": defaultSpecFile(CLI.Spec), "env": CLI.SpecFileEnv},
+		})
+	}
+
+	if CLI.Transport != "stdio" {
+		cliFields = append(cliFields, listenAddrField())
+	}
+	if clientAuth() {
+		cliFields = append(cliFields, clientAuthField())
+	} else if CLI.ClientAuthToken {
+		warnf("--client-auth-token has no effect with the stdio transport, whose client started the server")
+	}
+
+	if CLI.DynamicTools {
+		cliFields = append(cliFields,
+			ConfigField{
+				Name: "DisabledTools", Type: jen.Index().String(),
+				Tags: map[string]string{"help": "Tools not exposed at startup, they can be enabled at runtime"},
+			},
+			ConfigField{
+				Name: "EnableToolAdmin", Type: jen.Bool(),
+				Tags: map[string]string{"help": "Register the tool enabling and disabling the other tools at runtime"},
+			},
+		)
+	}
+
+	if CLI.AutoPaginate || CLI.PageTokenParam != "" {
+		cliFields = append(cliFields, ConfigField{
+			Name: "MaxPages", Type: jen.Int(),
+			Tags: map[string]string{"help": "Maximum number of pages fetched when following Link headers or page tokens", "default": strconv.Itoa(CLI.MaxPages)},
+		})
+	}
+
+	if CLI.MaxReadBytes > 0 {
+		maxReadBytesHelp := "Maximum number of response body bytes read from the API"
+		if readsWholeBody() {
+			maxReadBytesHelp = "Maximum number of response body bytes returned in the tool results, keeping the " + map[string]string{"tail": "end of the bodies", "smart": "structure of the JSON bodies"}[CLI.TruncationStrategy]
+		}
+		cliFields = append(cliFields, ConfigField{
+			Name: "MaxReadBytes", Type: jen.Int64(),
+			Tags: map[string]string{"help": maxReadBytesHelp, "default": strconv.FormatInt(CLI.MaxReadBytes, 10)},
+		})
+	}
+
+	if CLI.MaxErrorBody > 0 {
+		cliFields = append(cliFields, ConfigField{
+			Name: "MaxErrorBody", Type: jen.Int(),
+			Tags: map[string]string{"help": "Maximum number of response body bytes included in the errors of the failed calls", "default": strconv.Itoa(CLI.MaxErrorBody)},
+		})
+	}
+
+	if CLI.TLSServerName != "" {
+		cliFields = append(cliFields, ConfigField{
+			Name: "TLSServerName", Type: jen.String(),
+			Tags: map[string]string{"help": "TLS server name (SNI) used when connecting to the API", "default": CLI.TLSServerName},
+		})
+	}
+
+	if CLI.FallbackHost != "" {
+		cliFields = append(cliFields, ConfigField{
+			Name: "FallbackHost", Type: jen.String(),
+			Tags: map[string]string{"help": "API server host called once more when a call to the primary one fails with a network error or a 5xx status, empty to disable", "default": CLI.FallbackHost},
+		})
+	}
+
+	if CLI.Retry > 1 {
+		cliFields = append(cliFields, retryFields()...)
+	}
+
+	if CLI.DedupWindow > 0 {
+		cliFields = append(cliFields, dedupField())
+	}
+
+	// Only the GET results are cached
+	cachesResults := CLI.CacheTTL > 0 && anyCachedOperation(operations)
+	if CLI.CacheTTL > 0 && !cachesResults {
+		warnf("--cache-ttl is ignored as the spec has no GET operation")
+	}
+	if cachesResults {
+		cliFields = append(cliFields, cacheFields()...)
+	}
+
+	if CLI.DefaultTimeout > 0 || CLI.MaxTimeout > 0 {
+		cliFields = append(cliFields, ConfigField{
+			Name: "Timeout", Type: jen.Qual("time", "Duration"),
+			Tags: map[string]string{"help": "Default timeout of the API calls, 0 for no timeout", "default": CLI.DefaultTimeout.String()},
+		})
+	}
+
+	if CLI.MaxTimeout > 0 {
+		cliFields = append(cliFields, ConfigField{
+			Name: "MaxTimeout", Type: jen.Qual("time", "Duration"),
+			Tags: map[string]string{"help": "Maximum timeout requested by a tool call", "default": CLI.MaxTimeout.String()},
+		})
+	}
+
+	if CLI.AcceptLanguage != "" {
+		cliFields = append(cliFields, ConfigField{
+			Name: "AcceptLanguage", Type: jen.String(),
+			Tags: map[string]string{"help": "Accept-Language header sent to the API", "default": CLI.AcceptLanguage, "env": CLI.AcceptLanguageEnv},
+		})
+		clientOptions = append(clientOptions,
+			jen.Qual(CLI.ClientImport, "WithRequestEditorFn").Call(headerEditor("Accept-Language", jen.Id("cli").Dot("AcceptLanguage"))),
+		)
+	}
+
+	if credentialTool {
+		cliFields = append(cliFields, ConfigField{
+			Name: "EnableCredentialTool", Type: jen.Bool(),
+			Tags: map[string]string{"help": "Register the tool replacing the API credentials at runtime"},
+		})
+	}
+
+	// The profiles give defaults to the flags, so they are read once all the
+	// flags are known
+	var parseOptions []jen.Code
+	if CLI.ProfilesFile != "" {
+		profiles, err := readProfiles(CLI.ProfilesFile, cliFields)
+		if err != nil {
+			return err
+		}
+		addProfiles(f, profiles)
+		cliFields = append(cliFields, profileField(profiles))
+		parseOptions = append(parseOptions, jen.Qual("github.com/alecthomas/kong", "Resolvers").Call(jen.Id("profileResolver").Call()))
+	}
+
 	// Define the main function properly
 	mainBody := []jen.Code{
+		// The MCP messages go through stdout with the stdio transport, the
+		// logs are written to stderr whatever the default handler becomes
+		jen.Qual("log/slog", "SetDefault").Call(jen.Qual("log/slog", "New").Call(
+			jen.Qual("log/slog", "NewTextHandler").Call(jen.Qual("os", "Stderr"), jen.Nil()),
+		)),
+
 		// Define flags
-		jen.Var().Id("cli").Op("=").Struct(
-			jen.Id("Host").String().Tag(map[string]string{"help": "API server host", "default": CLI.ServerURL}),
-			jen.Id("Username").String().Tag(map[string]string{"help": "API username", "env": CLI.UsernameEnv}),
-			jen.Id("Password").String().Tag(map[string]string{"help": "API password", "env": CLI.PasswordEnv}),
-		).Op("{}"),
+		jen.Var().Id("cli").Op("=").Struct(configFieldsCode(cliFields)...).Op("{}"),
 
 		// Parse flags
-		jen.Qual("github.com/alecthomas/kong", "Parse").Call(jen.Op("&").Id("cli")),
+		jen.Qual("github.com/alecthomas/kong", "Parse").Call(append([]jen.Code{jen.Op("&").Id("cli")}, parseOptions...)...),
 
-		// Create a done channel
-		jen.Id("done").Op(":=").Make(jen.Chan().Struct()),
-
-		// Setup basic auth
-		jen.List(jen.Id("basicAuth"), jen.Err()).Op(":=").Qual("github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider", "NewSecurityProviderBasicAuth").Call(
-			jen.Id("cli").Dot("Username"),
-			jen.Id("cli").Dot("Password"),
-		),
-		jen.If(jen.Err().Op("!=").Nil()).Block(
-			jen.Qual("log", "Fatal").Call(jen.Err()),
+		// The server context is cancelled on shutdown, stopping the calls in flight
+		jen.List(jen.Id("serverCtx"), jen.Id("stop")).Op(":=").Qual("os/signal", "NotifyContext").Call(
+			jen.Qual("context", "Background").Call(), jen.Qual("os", "Interrupt"), jen.Qual("syscall", "SIGTERM"),
 		),
+		jen.Defer().Id("stop").Call(),
+	}
 
-		// Create REST client
-		jen.List(jen.Id("restClient"), jen.Err()).Op(":=").Qual(CLI.ClientImport, "NewClientWithResponses").Call(
-			jen.Id("cli").Dot("Host"),
-			jen.Qual(CLI.ClientImport, "WithRequestEditorFn").Call(jen.Id("basicAuth").Dot("Intercept")),
-		),
-		jen.If(jen.Err().Op("!=").Nil()).Block(
-			jen.Panic(jen.Err()),
-		),
+	if CLI.RecordSpecSource {
+		addSpecSource(f, specContent)
+		mainBody = append(mainBody, jen.Qual("log/slog", "Info").Call(
+			jen.Lit("Generated from OpenAPI spec"),
+			jen.Lit("source"), jen.Id("specSource"),
+			jen.Lit("sha256"), jen.Id("specSHA256"),
+		))
+	}
+
+	// Refuse to start without the credentials required by the auth
+	if withAuth {
+		mainBody = append(mainBody, credentialsCheck(auth)...)
+	}
+	if clientAuth() {
+		mainBody = append(mainBody, clientAuthCheck()...)
+	}
+
+	// HTTP client used by the REST client when the transport is customized
+	var httpDoer jen.Code
+	if doer := httpDoerCode(f); len(doer) > 0 {
+		mainBody = append(mainBody, doer...)
+		httpDoer = jen.Id("httpDoer")
+	}
+
+	// The access tokens are fetched by the generated token source, the
+	// rejected ones are replaced by its doer
+	if withAuth && auth.Generated {
+		addTokenSource(f)
+		if httpDoer == nil {
+			httpDoer = jen.Qual("net/http", "DefaultClient")
+		}
+		httpDoer = jen.Op("&").Id("tokenDoer").Values(jen.Dict{
+			jen.Id("doer"):    httpDoer,
+			jen.Id("sources"): jen.Index().Op("*").Id("tokenSource").Values(auth.tokenSources()...),
+		})
+	}
+	if httpDoer != nil {
+		clientOptions = append(clientOptions, jen.Qual(CLI.ClientImport, "WithHTTPClient").Call(httpDoer))
+	}
+	if withAuth && len(auth.Parts) > 0 {
+		addCombineAuth(f)
+	}
+
+	if withAuth && !credentialTool {
+		// Setup the auth
+		mainBody = append(mainBody, auth.setupCode()...)
+	}
+
+	// The handlers load the REST client of the active connection when the
+	// credentials can be replaced
+	if credentialTool {
+		addConnectionType(f, auth, cachesResults)
+		mainBody = append(mainBody, connectionCode(auth, clientOptions, cachesResults)...)
+	} else {
+		mainBody = append(mainBody,
+			// Create REST client
+			jen.List(jen.Id("restClient"), jen.Err()).Op(":=").Qual(CLI.ClientImport, "NewClientWithResponses").Call(clientOptions...),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Panic(jen.Err()),
+			),
+		)
+	}
+
+	// The tools call the client through an interface, only known for the
+	// oapi-codegen clients shared by all calls
+	clientInterface := CLI.WithClientInterface
+	if clientInterface && (credentialTool || CLI.ClientMethodTemplate != defaultClientMethodTemplate) {
+		warnf("--with-client-interface needs the default client method template and no credential tool, calling the client directly")
+		clientInterface = false
+	}
+	caller := "restClient"
+	if clientInterface {
+		caller = "restAPI"
+		mainBody = append(mainBody, jen.Var().Id("restAPI").Id("restClientAPI").Op("=").Id("restClient"))
+	}
+	clientMethods := make(map[string]jen.Code)
+
+	// The explain tool builds the requests without a client of the connection
+	explainTool := CLI.ExplainTool
+	if explainTool && credentialTool {
+		warnf("--explain-tool cannot be used with the credential tool, not registering it")
+		explainTool = false
+	}
 
+	// The underlying client gives access to the HTTP doer and request editors
+	if (anyFetchesPages(operations) || explainTool) && !credentialTool {
+		mainBody = append(mainBody,
+			jen.Id("baseClient").Op(":=").Id("restClient").Dot("ClientInterface").Assert(jen.Op("*").Qual(CLI.ClientImport, "Client")),
+		)
+	}
+
+	var serverTransport jen.Code = jen.Qual("github.com/metoro-io/mcp-golang/transport/stdio", "NewStdioServerTransport").Call()
+
+	// Logging notifications are sent through a wrapper of the transport
+	if CLI.MCPLogging {
+		addLoggingTransport(f)
+		mainBody = append(mainBody, jen.Id("mcpLogger").Op(":=").Id("newLoggingTransport").Call(serverTransport))
+		serverTransport = jen.Id("mcpLogger")
+	}
+
+	mainBody = append(mainBody,
 		// Create server
-		jen.Id("server").Op(":=").Qual("github.com/metoro-io/mcp-golang", "NewServer").Call(
-			jen.Qual("github.com/metoro-io/mcp-golang/transport/stdio", "NewStdioServerTransport").Call(),
-		),
+		newServerCode(doc, serverTransport),
+	)
+
+	// The GET results are cached in memory or shared through Redis, for the
+	// credentials they were fetched with
+	var cacheIdentity jen.Code
+	if cachesResults {
+		addResultCache(f)
+		mainBody = append(mainBody, cacheCode()...)
+		var identityCode []jen.Code
+		identityCode, cacheIdentity = cacheIdentityCode(f, auth, withAuth, credentialTool)
+		mainBody = append(mainBody, identityCode...)
 	}
 
-	// Add tools registration for each operation
-	for _, op := range operations {
+	if CLI.ReloadOnSighup {
+		mainBody = append(mainBody, jen.Id("handlers").Op(":=").Map(jen.String()).Any().Values())
+	}
+
+	// The operation tools go through the tool set when they can be disabled
+	registrar := "server"
+	if CLI.DynamicTools {
+		mainBody = append(mainBody, jen.Id("tools").Op(":=").Id("newToolSet").Call(jen.Id("server"), jen.Id("cli").Dot("DisabledTools")))
+		registrar = "tools"
+	}
+
+	// Helper functions used by the handlers, emitted after main
+	helpers := newHelperSet()
+
+	successCodes, err := parseStatusCodes(CLI.SuccessCodes, "success")
+	if err != nil {
+		return err
+	}
+	retryCodes, err := parseStatusCodes(CLI.RetryStatuses, "retry")
+	if err != nil {
+		return err
+	}
+
+	methodTemplate, err := template.New("client-method").Parse(CLI.ClientMethodTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid client method template: %w", err)
+	}
+
+	plugins, err := selectHandlerPlugins(CLI.HandlerPlugins)
+	if err != nil {
+		return err
+	}
+
+	var filterRules map[string]map[string]string
+	if CLI.FilterRules != "" {
+		if !CLI.FilterQuery {
+			warnf("--filter-rules has no effect without --filter-query")
+		}
+		filterRules, err = readFilterRules(CLI.FilterRules)
+		if err != nil {
+			return err
+		}
+	}
+	plugins.declare(f)
+
+	if CLI.BatchTool {
+		addBatchTool(f)
+		mainBody = append(mainBody, jen.Id("batchCalls").Op(":=").Map(jen.String()).Id("batchCall").Values())
+	}
+
+	if explainTool {
+		addExplainTool(f, doc)
+		mainBody = append(mainBody, jen.Id("explainers").Op(":=").Map(jen.String()).Id("explainer").Values())
+	}
+
+	// Add tools registration for each operation, in ID order so the output is stable
+	for _, id := range sortedOperationIDs(operations) {
+		op := operations[id]
+		// Extra tool arguments need their own type wrapping the client arguments
+		extraArgs := toolArguments(op)
+		argsType := jen.Qual(CLI.ClientImport, op.ParameterType)
 		paramExpr := jen.Id("arguments")
+		if needsArgumentsType(op, extraArgs) {
+			addArgumentsType(f, op, extraArgs)
+			names, filters := booleanQueryParameters(op), filterParameters(op)
+			if len(names) > 0 {
+				helpers.use("coerceBooleans", addCoerceBooleans)
+			}
+			if len(filters) > 0 {
+				helpers.use("translateFilterQuery", addTranslateFilterQuery)
+			}
+			if len(names) > 0 || len(filters) > 0 {
+				addArgumentsUnmarshal(f, op, names, filters, filterRules)
+			}
+			if len(op.BodyVariants) > 0 {
+				addUnionBody(f, op)
+			}
+			if op.BodyTemplate != "" {
+				helpers.use("renderBody", addRenderBody)
+				addBodyTemplate(f, op)
+			}
+			argsType = jen.Id(argumentsTypeName(op))
+			paramExpr = jen.Id("arguments").Dot(argumentsField(op))
+		}
 		if !op.HasRequestBody {
-			paramExpr = jen.Op("&").Id("arguments")
+			paramExpr = jen.Op("&").Add(paramExpr)
 		}
 
-		mainBody = append(mainBody,
-			jen.Err().Op("=").Id("server").Dot("RegisterTool").Call(
-				jen.Lit(op.ID),
-				jen.Lit(op.Description),
-				jen.Func().Params(
-					jen.Id("arguments").Qual(CLI.ClientImport, op.ParameterType),
-				).Params(
-					jen.Op("*").Qual("github.com/metoro-io/mcp-golang", "ToolResponse"),
-					jen.Error(),
-				).Block(
-					jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("restClient").Dot(op.ID+"WithResponse").Call(
-						jen.Qual("context", "TODO").Call(),
-						paramExpr,
+		ctxExpr := jen.Id("serverCtx")
+		var handlerBody []jen.Code
+
+		// Out-of-enum values are rejected before the API does, the arguments of
+		// the bodies built by the server do not map to the body properties
+		if CLI.EnforceEnums && len(op.Enums) > 0 && len(op.BodyVariants) == 0 && op.BodyTemplate == "" && !op.FreeFormBody {
+			helpers.use("checkEnums", addCheckEnums)
+			handlerBody = append(handlerBody, enumCheckCode(op)...)
+		}
+
+		// The calls of the operation share a context bounded by the timeout
+		if CLI.DefaultTimeout > 0 || CLI.MaxTimeout > 0 {
+			helpers.use("callContext", addCallContext)
+			timeoutArgs := []jen.Code{jen.Id("serverCtx"), jen.Lit(0), jen.Id("cli").Dot("Timeout"), jen.Lit(0)}
+			if CLI.MaxTimeout > 0 {
+				timeoutArgs = []jen.Code{jen.Id("serverCtx"), jen.Id("arguments").Dot("TimeoutSeconds"), jen.Id("cli").Dot("Timeout"), jen.Id("cli").Dot("MaxTimeout")}
+			}
+			handlerBody = append(handlerBody,
+				jen.List(jen.Id("ctx"), jen.Id("cancel")).Op(":=").Id("callContext").Call(timeoutArgs...),
+				jen.Defer().Id("cancel").Call(),
+			)
+			ctxExpr = jen.Id("ctx")
+		}
+
+		var methodName strings.Builder
+		if err := methodTemplate.Execute(&methodName, op); err != nil {
+			return fmt.Errorf("error executing client method template for %s: %w", op.ID, err)
+		}
+
+		// Request editors applied to this operation calls only
+		var reqEditors []jen.Code
+		if perOperationAuth && op.RequiresAuth {
+			reqEditors = append(reqEditors, auth.intercept())
+		}
+		if CLI.AllowCustomHeaders {
+			helpers.use("customHeaders", addCustomHeaders)
+			reqEditors = append(reqEditors, jen.Id("customHeaders").Call(jen.Id("arguments").Dot("Headers")))
+		}
+		if op.hasCursorArgument() {
+			helpers.use("cursorParam", addCursorParam)
+			reqEditors = append(reqEditors, jen.Id("cursorParam").Call(jen.Id("arguments").Dot("Cursor")))
+		}
+		if op.HasRequestBody && len(op.ReadOnlyProperties) > 0 && op.BodyTemplate == "" {
+			helpers.use("removeProperties", addRemoveProperties)
+			helpers.use("omitProperties", addOmitProperties)
+			reqEditors = append(reqEditors, jen.Id("omitProperties").Call(propertyPathsCode(op.ReadOnlyProperties)))
+		}
+
+		if credentialTool {
+			handlerBody = append(handlerBody, connectionPrelude(op, auth, perOperationAuth)...)
+		}
+
+		// The builder of the request sent by the call, with the steps preparing
+		// its arguments, shared with the explain tool
+		var buildSteps []jen.Code
+		builder := "New" + op.ID + "Request"
+
+		// Discriminated bodies are built from the variant selected in the arguments
+		if len(op.BodyVariants) > 0 && op.BodyRequired && !op.BodyInlineUnion {
+			buildSteps = append(buildSteps, unionBodyCode(op)...)
+			paramExpr = jen.Id("unionBody")
+		}
+
+		// Optional bodies are sent raw so nothing is sent when they are absent,
+		// as are the inline unions
+		callArgs := []jen.Code{ctxExpr, paramExpr}
+		if op.HasRequestBody && (!op.BodyRequired || op.BodyInlineUnion) && op.BodyTemplate == "" && !op.FreeFormBody {
+			buildSteps = append(buildSteps, optionalBodyCode(op)...)
+			methodName.Reset()
+			methodName.WriteString(op.ID + "WithBodyWithResponse")
+			builder += "WithBody"
+			callArgs = []jen.Code{ctxExpr, jen.Lit("application/json"), requestBodyReader()}
+		}
+
+		// Templated bodies are rendered from the arguments and sent raw
+		if op.BodyTemplate != "" {
+			buildSteps = append(buildSteps, templateBodyCode(op)...)
+			methodName.Reset()
+			methodName.WriteString(op.ID + "WithBodyWithResponse")
+			builder += "WithBody"
+			callArgs = []jen.Code{ctxExpr, jen.Lit("application/json"), requestBodyReader()}
+		}
+
+		// Free-form bodies are sent as given
+		if op.FreeFormBody {
+			buildSteps = append(buildSteps, freeFormBodyCode(op)...)
+			methodName.Reset()
+			methodName.WriteString(op.ID + "WithBodyWithResponse")
+			builder += "WithBody"
+			callArgs = []jen.Code{ctxExpr, jen.Lit("application/json"), requestBodyReader()}
+		}
+		handlerBody = append(handlerBody, buildSteps...)
+
+		if explainTool {
+			mainBody = append(mainBody, explainerCode(op, argsType, buildSteps, builder, callArgs[1:], reqEditors))
+		}
+
+		// The dry-run editor runs last so it sees the request as it would be sent
+		callEditors := reqEditors
+		if CLI.IdempotencyKeyHeader != "" && !op.IsIdempotent() {
+			handlerBody = append(handlerBody, jen.Id("idempotencyKey").Op(":=").Qual("crypto/rand", "Text").Call())
+			callEditors = append(callEditors, idempotencyKeyEditor(jen.Id("idempotencyKey")))
+		}
+		if CLI.PreviewMutations && op.IsMutating() {
+			helpers.use("previewRequest", addPreviewRequest)
+			handlerBody = append(handlerBody, jen.Var().Id("preview").String())
+			callEditors = append(callEditors, jen.Id("previewRequest").Call(jen.Id("arguments").Dot("DryRun"), jen.Op("&").Id("preview")))
+		}
+
+		if clientInterface {
+			clientMethods[methodName.String()] = clientAPIMethod(op, methodName.String())
+		}
+
+		handlerBody = append(handlerBody, mcpLog("debug", "calling "+op.ID)...)
+		scope := HandlerScope{Op: op, Ctx: ctxExpr}
+		handlerBody = append(handlerBody, plugins.preCall(scope)...)
+
+		// The latency budget covers the REST call only, its retries and the
+		// waits between them included
+		if op.SLABudget > 0 {
+			handlerBody = append(handlerBody, slaStartCode(op)...)
+		}
+		call := jen.Id(caller).Dot(methodName.String()).Call(append(callArgs, callEditors...)...)
+		handlerBody = append(handlerBody, jen.List(jen.Id("resp"), jen.Err()).Op(":=").Add(call))
+
+		// Transient failures are retried with the same arguments, only for the
+		// idempotent methods and the calls carrying an idempotency key unless
+		// asked otherwise
+		if CLI.Retry > 1 && (op.IsIdempotent() || CLI.IdempotencyKeyHeader != "" || CLI.RetryUnsafe) {
+			helpers.use("retryCall", addRetryCall(retryCodes))
+			handlerBody = append(handlerBody, retryCode(op, ctxExpr, call))
+		}
+		if op.SLABudget > 0 {
+			handlerBody = append(handlerBody, slaCheckCode(op)...)
+		}
+		if CLI.LogRequests {
+			handlerBody = append(handlerBody, requestStatusCode())
+		}
+
+		if CLI.PreviewMutations && op.IsMutating() {
+			handlerBody = append(handlerBody,
+				jen.If(jen.Qual("errors", "Is").Call(jen.Err(), jen.Id("errDryRun"))).Block(
+					jen.Return(toolResult(textContent(jen.Id("preview"))), jen.Nil()),
+				),
+			)
+		}
+
+		handlerBody = append(handlerBody,
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				append(append(mcpLog("error", "error calling "+op.ID+": %v", jen.Err()), plugins.onError(scope)...),
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error calling "+op.ID+": %v"), jen.Err())),
+				)...,
+			),
+		)
+
+		// Error statuses are reported with their stable code when enabled
+		errorFormat := "error on " + op.ID + ": %s"
+		errorArgs := []jen.Code{respStatusText()}
+		if CLI.ErrorCodes {
+			helpers.use("errorCode", addErrorCode)
+			errorFormat = "error on " + op.ID + ": [%s] %s"
+			errorArgs = []jen.Code{jen.Id("errorCode").Call(respStatusCode()), respStatusText()}
+		}
+
+		// Problem details are presented as errors whatever the status code
+		if op.HasResponseContentType(problemContentType) || CLI.ProblemDetails {
+			helpers.use("isProblemResponse", addIsProblemResponse)
+			problem := jen.String().Call(respBody())
+			if CLI.ProblemDetails {
+				helpers.use("formatProblem", addFormatProblem)
+				problem = jen.Id("formatProblem").Call(respBodyBytes())
+			}
+			handlerBody = append(handlerBody,
+				jen.If(jen.Id("isProblemResponse").Call(jen.Id("resp").Dot("HTTPResponse"))).Block(
+					append(plugins.onError(scope),
+						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(append([]jen.Code{jen.Lit(errorFormat + ": %s")}, append(errorArgs, problem)...)...)),
+					)...,
+				),
+			)
+		}
+
+		// The body of an error status usually holds the reason of the failure
+		if CLI.MaxErrorBody > 0 {
+			helpers.use("errorDetail", addErrorDetail)
+			errorFormat += "%s"
+			errorArgs = append(errorArgs, jen.Id("errorDetail").Call(respBodyBytes(), jen.Id("cli").Dot("MaxErrorBody")))
+		}
+
+		helpers.use("successStatus", addSuccessStatus(successCodes))
+		handlerBody = append(handlerBody,
+			jen.If(jen.Op("!").Id("successStatus").Call(respStatusCode())).Block(
+				append(append(mcpLog("error", errorFormat, errorArgs...), plugins.onError(scope)...),
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(append([]jen.Code{jen.Lit(errorFormat)}, errorArgs...)...)),
+				)...,
+			),
+		)
+		handlerBody = append(handlerBody, mcpLog("info", op.ID+" returned %s", respStatusText())...)
+		handlerBody = append(handlerBody, plugins.postCall(scope)...)
+
+		// Steps transforming the successful response body before returning it
+		var bodySteps []jen.Code
+
+		// The upstream body is validated, the mismatch is noted once transformed
+		validate := CLI.ValidateResponses && op.HasResponseSchema
+		if validate {
+			helpers.use("validateResponse", addValidateResponse)
+			bodySteps = append(bodySteps,
+				jen.Id("mismatch").Op(":=").Id("validateResponse").Call(jen.Lit(op.ID), respStatusCode(), jen.Id("body")),
+				jen.If(jen.Id("mismatch").Op("!=").Nil()).Block(
+					jen.Qual("log/slog", "Warn").Call(jen.Lit("response does not match the spec"), jen.Lit("operation"), jen.Lit(op.ID), jen.Lit("error"), jen.Id("mismatch")),
+				),
+			)
+		}
+
+		if op.HasPageToken {
+			helpers.use("fetchTokenWindow", addFetchTokenWindow)
+			bodySteps = append(bodySteps,
+				jen.If(jen.Id("arguments").Dot("Offset").Op(">").Lit(0).Op("||").Id("arguments").Dot("Limit").Op(">").Lit(0)).Block(
+					jen.List(jen.Id("body"), jen.Err()).Op("=").Id("fetchTokenWindow").Call(
+						append([]jen.Code{
+							ctxExpr,
+							jen.Id("baseClient"),
+							jen.Id("resp").Dot("HTTPResponse"),
+							jen.Id("body"),
+							jen.Id("arguments").Dot("Offset"),
+							jen.Id("arguments").Dot("Limit"),
+							jen.Id("cli").Dot("MaxPages"),
+						}, reqEditors...)...,
 					),
 					jen.If(jen.Err().Op("!=").Nil()).Block(
-						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error calling "+op.ID+": %v"), jen.Err())),
+						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error paginating "+op.ID+": %v"), jen.Err())),
 					),
-					jen.If(jen.Id("resp").Dot("StatusCode").Call().Op("!=").Lit(200)).Block(
-						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error on "+op.ID+": %s"), jen.Id("resp").Dot("Status").Call())),
+				),
+			)
+		}
+
+		if CLI.AutoPaginate && op.HasLinkPagination {
+			helpers.use("fetchLinkPages", addFetchLinkPages)
+			helpers.use("nextLink", addNextLink)
+			bodySteps = append(bodySteps,
+				jen.List(jen.Id("body"), jen.Err()).Op("=").Id("fetchLinkPages").Call(
+					append([]jen.Code{
+						ctxExpr,
+						jen.Id("baseClient"),
+						jen.Id("resp").Dot("HTTPResponse"),
+						jen.Id("body"),
+						jen.Id("cli").Dot("MaxPages"),
+					}, reqEditors...)...,
+				),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error paginating "+op.ID+": %v"), jen.Err())),
+				),
+			)
+		}
+
+		// The writeOnly properties are hidden from the API response as received
+		if CLI.HideWriteOnly && len(op.WriteOnlyProperties) > 0 {
+			helpers.use("removeProperties", addRemoveProperties)
+			helpers.use("hideProperties", addHideProperties)
+			bodySteps = append(bodySteps,
+				jen.Id("body").Op("=").Id("hideProperties").Call(jen.Id("body"), propertyPathsCode(op.WriteOnlyProperties)),
+			)
+		}
+
+		if CLI.WithResponseTransform {
+			helpers.use("transformResponse", addTransformResponse)
+			bodySteps = append(bodySteps,
+				jen.List(jen.Id("body"), jen.Err()).Op("=").Id("transformResponse").Call(jen.Lit(op.ID), jen.Id("body")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error transforming "+op.ID+" response: %v"), jen.Err())),
+				),
+			)
+		}
+
+		if len(CLI.StripResponseKeys) > 0 {
+			helpers.use("stripResponseKeys", addStripResponseKeys)
+			bodySteps = append(bodySteps,
+				jen.Id("body").Op("=").Id("stripResponseKeys").Call(jen.Id("body")),
+			)
+		}
+
+		if CLI.ResponseKeyCase != "" {
+			helpers.use("convertResponseKeys", addConvertResponseKeys)
+			bodySteps = append(bodySteps,
+				jen.Id("body").Op("=").Id("convertResponseKeys").Call(jen.Id("body")),
+			)
+		}
+
+		if CLI.AllowFieldProjection {
+			helpers.use("projectFields", addProjectFields)
+			bodySteps = append(bodySteps,
+				jen.If(jen.Len(jen.Id("arguments").Dot("Fields")).Op(">").Lit(0)).Block(
+					jen.List(jen.Id("body"), jen.Err()).Op("=").Id("projectFields").Call(jen.Id("body"), jen.Id("arguments").Dot("Fields")),
+					jen.If(jen.Err().Op("!=").Nil()).Block(
+						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error projecting fields of "+op.ID+": %v"), jen.Err())),
 					),
-					jen.Return(
-						jen.Qual("github.com/metoro-io/mcp-golang", "NewToolResponse").Call(
-							jen.Qual("github.com/metoro-io/mcp-golang", "NewTextContent").Call(
-								jen.String().Call(jen.Id("resp").Dot("Body")),
-							),
-						),
-						jen.Nil(),
+				),
+			)
+		}
+
+		if CLI.AllowResponseFormat {
+			helpers.use("formatResponse", addFormatResponse)
+			bodySteps = append(bodySteps, responseFormatCode(op)...)
+		}
+
+		if CLI.MaxReadBytes > 0 {
+			switch CLI.TruncationStrategy {
+			case "tail":
+				helpers.use("truncateTail", addTruncateTail)
+			case "smart":
+				helpers.use("truncateSmart", addTruncateSmart)
+			}
+			bodySteps = append(bodySteps, truncationCode()...)
+		}
+
+		if validate {
+			bodySteps = append(bodySteps,
+				jen.If(jen.Id("mismatch").Op("!=").Nil()).Block(
+					jen.Id("body").Op("=").Append(
+						jen.Id("body"),
+						jen.Qual("fmt", "Sprintf").Call(jen.Lit("\n[response does not match the spec: %v]"), jen.Id("mismatch")).Op("..."),
 					),
 				),
-			),
-			jen.If(jen.Err().Op("!=").Nil()).Block(
-				jen.Panic(jen.Err()),
-			),
+			)
+		}
+
+		if CLI.SurfaceRateLimits {
+			helpers.use("rateLimitNote", addRateLimitNote)
+			bodySteps = append(bodySteps,
+				jen.If(jen.Id("note").Op(":=").Id("rateLimitNote").Call(jen.Id("resp").Dot("HTTPResponse").Dot("Header")), jen.Id("note").Op("!=").Lit("")).Block(
+					jen.Id("body").Op("=").Append(jen.Id("body"), jen.Id("note").Op("...")),
+				),
+			)
+		}
+
+		bodyExpr := respBody()
+		if len(bodySteps) > 0 {
+			handlerBody = append(handlerBody, jen.Id("body").Op(":=").Add(respBodyBytes()))
+			handlerBody = append(handlerBody, bodySteps...)
+			bodyExpr = jen.Id("body")
+		}
+
+		contents := []jen.Code{
+			textContent(jen.String().Call(bodyExpr)),
+		}
+
+		// The metadata goes in its own content so the body is left untouched
+		if CLI.AnnotateMime {
+			helpers.use("mimeAnnotation", addMimeAnnotation)
+			contents = append(contents, jen.Id("mimeAnnotation").Call(jen.Id("resp").Dot("HTTPResponse"), respBodyBytes()))
+		}
+
+		if CLI.AttachOperationMeta {
+			helpers.use("operationMeta", addOperationMeta)
+			contents = append(contents, jen.Id("operationMeta").Call(jen.Lit(op.ID), jen.Lit(op.Method), jen.Id("resp").Dot("HTTPResponse")))
+		}
+
+		// The cursor is read from the API response, before any transformation
+		if op.CursorField != "" {
+			helpers.use("cursorMeta", addCursorMeta)
+			contents = append(contents, jen.Id("cursorMeta").Call(respBodyBytes(), jen.Lit(op.CursorField)))
+		}
+
+		if len(op.ResponseVariants) > 0 {
+			helpers.use("responseVariant", addResponseVariant)
+			contents = append(contents, jen.Id("responseVariant").Call(respStatusCode(), responseVariantsCode(op)))
+		}
+
+		if op.SLABudget > 0 {
+			helpers.use("slaMeta", addSLAMeta)
+			contents = append(contents, jen.Id("slaMeta").Call(jen.Id("elapsed"), jen.Id("budget")))
+		}
+
+		handlerBody = append(handlerBody,
+			jen.Return(toolResult(contents...), jen.Nil()),
 		)
+
+		// The whole call is logged, whichever way the handler returns
+		if CLI.LogRequests {
+			handlerBody = requestLogCode(op, handlerBody)
+		}
+
+		handler := jen.Func().Params(
+			jen.Id("arguments").Add(argsType),
+		).Params(toolResultType(), jen.Error()).Block(handlerBody...)
+
+		if CLI.LazyHandlers {
+			helpers.use("lazyHandler", addLazyHandler)
+			handler = jen.Id("lazyHandler").Call(
+				jen.Func().Params().Add(handlerSignature(argsType)).Block(jen.Return(handler)),
+			)
+		}
+
+		// The results of the GET calls are reused until they expire
+		if cachesResults && op.Method == "GET" {
+			helpers.use("cacheHandler", addCacheHandler)
+			handler = cacheHandlerCode(op, cacheIdentity, handler)
+		}
+
+		// Identical mutating calls in quick succession reach the API once
+		if CLI.DedupWindow > 0 && op.IsMutating() {
+			helpers.use("dedupHandler", addDedupHandler)
+			handler = dedupHandlerCode(op, handler)
+		}
+
+		// The batch tool calls the same handlers as the operation tools
+		if CLI.BatchTool {
+			var batchCode []jen.Code
+			batchCode, handler = batchHandlerCode(op, handler)
+			mainBody = append(mainBody, batchCode...)
+		}
+
+		// Keep the handlers around so tools can be registered again on reload
+		if CLI.ReloadOnSighup {
+			mainBody = append(mainBody, jen.Id("handlers").Index(jen.Lit(op.ID)).Op("=").Add(handler))
+			handler = jen.Id("handlers").Index(jen.Lit(op.ID))
+		}
+
+		// Tools taking only the operation parameters declare them one by one
+		var toolOptions []jen.Code
+		if useMark3labs() && !op.HasRequestBody && !needsArgumentsType(op, extraArgs) {
+			toolOptions = parameterOptions(op, argsType)
+		}
+		mainBody = append(mainBody, registerTool(registrar, op.ID, op.Description, argsType, handler, toolOptions)...)
+	}
+
+	if clientInterface {
+		addClientAPI(f, clientMethods)
+	}
+
+	if CLI.WithSpecTool || CLI.ValidateResponses {
+		if err := embedSpec(f, doc); err != nil {
+			return err
+		}
+	}
+	if CLI.WithSpecTool {
+		mainBody = append(mainBody, registerSpecTool()...)
+	}
+	if explainTool {
+		mainBody = append(mainBody, registerExplainTool()...)
+	}
+	if CLI.BatchTool {
+		mainBody = append(mainBody, registerBatchTool()...)
+	}
+
+	if credentialTool {
+		mainBody = append(mainBody, registerCredentialTool(auth)...)
+	}
+	if CLI.DynamicTools {
+		mainBody = append(mainBody, registerToolAdmin()...)
 	}
 
 	// Add server start and wait for done
-	mainBody = append(mainBody,
-		jen.Err().Op("=").Id("server").Dot("Serve").Call(),
-		jen.If(jen.Err().Op("!=").Nil()).Block(
-			jen.Panic(jen.Err()),
-		),
+	mainBody = append(mainBody, serveCode()...)
+	mainBody = append(mainBody, jen.Qual("log/slog", "Info").Call(jen.Lit("Server started")))
+
+	if CLI.ReloadOnSighup {
+		mainBody = append(mainBody, reloadOnSighup(registrar))
+	}
 
-		jen.Qual("log/slog", "Info").Call(jen.Lit("Server started")),
-		jen.Op("<-").Id("done"),
+	mainBody = append(mainBody,
+		jen.Op("<-").Id("serverCtx").Dot("Done").Call(),
+		jen.Qual("log/slog", "Info").Call(jen.Lit("Server stopped")),
 	)
 
 	// Add the proper main function to the file
 	f.Func().Id("main").Params().Block(mainBody...)
 
+	if CLI.ReloadOnSighup {
+		addReloadToolDescriptions(f, operations)
+	}
+	if CLI.DynamicTools {
+		addToolSet(f)
+	}
+	if useMark3labs() {
+		addMark3labsAdapters(f)
+	}
+	if clientAuth() {
+		addRequireClientToken(f)
+	}
+
+	helpers.emit(f)
+
+	if CLI.WithClient {
+		clientPath, err := generateClient(doc, CLI.ClientOutputDir)
+		if err != nil {
+			return err
+		}
+		report.Files = append(report.Files, clientPath)
+		logf("REST client generated: %s\n", clientPath)
+	}
+
 	// Save the file
-	return f.Save(CLI.Output)
+	if err := f.Save(CLI.Output); err != nil {
+		return err
+	}
+	report.Files = append(report.Files, CLI.Output)
+
+	if CLI.EmitEnvDoc {
+		envDocPath := filepath.Join(filepath.Dir(CLI.Output), "ENVIRONMENT.md")
+		if err := writeEnvDoc(envDocPath, cliFields); err != nil {
+			return fmt.Errorf("error writing environment reference: %w", err)
+		}
+		report.Files = append(report.Files, envDocPath)
+		logf("Environment reference generated: %s\n", envDocPath)
+	}
+
+	return nil
+}
+
+// checkCallbacks reports the operations declaring callbacks, which cannot be
+// mapped to synchronous tools, as a warning or as an error when strict
+func checkCallbacks(operations map[string]OperationInfo) error {
+	var unsupported []string
+	for _, op := range operations {
+		if len(op.Callbacks) > 0 {
+			unsupported = append(unsupported, fmt.Sprintf("%s (%s)", op.ID, strings.Join(op.Callbacks, ", ")))
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	sort.Strings(unsupported)
+
+	message := "callbacks are not supported and are ignored for operations: " + strings.Join(unsupported, "; ")
+	if CLI.Strict {
+		return fmt.Errorf("%s", message)
+	}
+	warnf("%s", message)
+
+	return nil
+}
+
+// callbackNames returns the sorted names of the operation callbacks
+func callbackNames(operation *openapi3.Operation) []string {
+	names := make([]string, 0, len(operation.Callbacks))
+	for name := range operation.Callbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedOperationIDs returns the IDs of the operations in alphabetical order
+func sortedOperationIDs(operations map[string]OperationInfo) []string {
+	ids := make([]string, 0, len(operations))
+	for id := range operations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// limitOperations enforces the maximum number of tools, either failing or
+// keeping the first operations in ID order so the selection is deterministic
+func limitOperations(operations map[string]OperationInfo) error {
+	if CLI.MaxTools <= 0 || len(operations) <= CLI.MaxTools {
+		return nil
+	}
+
+	if CLI.MaxToolsPolicy == "error" {
+		return fmt.Errorf("the spec yields %d tools, more than the maximum of %d, filter the operations or raise --max-tools", len(operations), CLI.MaxTools)
+	}
+
+	ids := sortedOperationIDs(operations)
+	for _, id := range ids[CLI.MaxTools:] {
+		delete(operations, id)
+	}
+	warnf("the spec yields %d tools, only the first %d are generated, dropped: %s", len(ids), CLI.MaxTools, strings.Join(ids[CLI.MaxTools:], ", "))
+
+	return nil
+}
+
+// respStatusCode returns the expression of the client response status code
+func respStatusCode() *jen.Statement {
+	return jen.Id(CLI.ResponseStatusExpr)
+}
+
+// respStatusText returns the expression of the client response status text
+func respStatusText() *jen.Statement {
+	return jen.Id(CLI.ResponseStatusTextExpr)
+}
+
+// respBody returns the expression of the client response body
+func respBody() *jen.Statement {
+	return jen.Id(CLI.ResponseBodyExpr)
+}
+
+// respBodyBytes returns the client response body as a byte slice, custom
+// clients may expose the body as a string
+func respBodyBytes() *jen.Statement {
+	if CLI.ResponseBodyExpr == "resp.Body" {
+		return respBody()
+	}
+	return jen.Index().Byte().Call(respBody())
+}
+
+// credentialsCheck returns the statements making the generated server exit
+// at startup when any of the credentials of the auth is empty
+func credentialsCheck(auth authScheme) []jen.Code {
+	checks := []jen.Code{jen.Var().Id("missingCredentials").Index().String()}
+	for _, field := range auth.Credentials {
+		checks = append(checks,
+			jen.If(jen.Id("cli").Dot(field.Name).Op("==").Lit("")).Block(
+				jen.Id("missingCredentials").Op("=").Append(jen.Id("missingCredentials"), jen.Lit(field.Tags["env"])),
+			),
+		)
+	}
+	checks = append(checks,
+		jen.If(jen.Len(jen.Id("missingCredentials")).Op(">").Lit(0)).Block(
+			jen.Qual("log", "Fatalf").Call(jen.Lit("missing API credentials, set %s"), jen.Qual("strings", "Join").Call(jen.Id("missingCredentials"), jen.Lit(", "))),
+		),
+	)
+
+	return checks
+}
+
+// configFieldsCode returns the struct fields for the generated configuration
+func configFieldsCode(fields []ConfigField) []jen.Code {
+	code := make([]jen.Code, 0, len(fields))
+	for _, field := range fields {
+		code = append(code, jen.Id(field.Name).Add(field.Type).Tag(field.Tags))
+	}
+	return code
+}
+
+// headerEditor returns a request editor function setting the header to the
+// given value, the header is left untouched when the value is empty
+func headerEditor(name string, value jen.Code) jen.Code {
+	return jen.Func().Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Error().Block(
+		jen.If(jen.Add(value).Op("!=").Lit("")).Block(
+			jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit(name), value),
+		),
+		jen.Return(jen.Nil()),
+	)
 }
 
 // processOperation handles an individual operation within a path
-func processOperation(path, method string, operation *openapi3.Operation, operations map[string]OperationInfo) {
+func processOperation(path, method string, operation *openapi3.Operation, pathParameters openapi3.Parameters, globalSecurity openapi3.SecurityRequirements, operations map[string]OperationInfo) {
 	if operation == nil || operation.OperationID == "" {
 		return
 	}
 
 	paramType := fmt.Sprintf("%sParams", operation.OperationID)
 	hasRequestBody := false
+	bodyRequired := false
 
 	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
 		hasRequestBody = true
+		bodyRequired = operation.RequestBody.Value.Required
 		paramType = fmt.Sprintf("%sJSONRequestBody", operation.OperationID)
 	}
 
+	parameters := collectParameters(operation, pathParameters)
+	discriminator, variants := bodyDiscriminator(operation)
+
+	// Bodies without a schema are taken as raw JSON documented by their example
+	freeFormBody, example := isFreeFormBody(operation), ""
+	if freeFormBody {
+		example = bodyExample(operation)
+	}
+
 	summary := operation.Summary
 	if summary == "" {
 		summary = fmt.Sprintf("%s %s", method, path)
 	}
 
-	description := operation.Description
-	if description == "" {
-		description = summary
-	}
+	description := tooldesc.Operation(operation.OperationID, method, path, operation, pathParameters, descriptionOptions())
+	category := operationCategory(operation)
 
 	operations[operation.OperationID] = OperationInfo{
-		ID:             operation.OperationID,
-		Summary:        summary,
-		Description:    description,
-		ParameterType:  paramType,
-		HasRequestBody: hasRequestBody,
+		ID:                   operation.OperationID,
+		Method:               method,
+		Path:                 path,
+		Summary:              summary,
+		Description:          description,
+		ParameterType:        paramType,
+		HasRequestBody:       hasRequestBody,
+		BodyIsObject:         isObjectBody(operation),
+		BodyRequired:         bodyRequired,
+		Parameters:           parameters,
+		ResponseContentTypes: collectResponseContentTypes(operation),
+		HasLinkPagination:    method == "GET" && hasSuccessResponseHeader(operation, "Link"),
+		HasResponseSchema:    hasJSONResponseSchema(operation),
+		HasPageToken:         method == "GET" && hasPageTokenParameter(parameters),
+		CursorField:          cursorField(method, operation, parameters),
+		RequiresAuth:         requiresAuth(operation, globalSecurity),
+		Callbacks:            callbackNames(operation),
+		BodyDiscriminator:    discriminator,
+		BodyVariants:         variants,
+		BodyInlineUnion:      len(variants) > 0 && isInlineUnion(operation),
+		Category:             category,
+		FreeFormBody:         freeFormBody,
+		BodyExample:          example,
+		ResponseVariants:     responseVariants(operation),
+		Enums:                argumentEnums(operation, parameters),
+		ReadOnlyProperties:   readOnlyProperties(operation),
+		WriteOnlyProperties:  writeOnlyProperties(operation),
+	}
+}
+
+// operationCategory returns the category of the operation given with the
+// category extension, empty when there is none
+func operationCategory(operation *openapi3.Operation) string {
+	value, ok := operation.Extensions[tooldesc.CategoryExtension]
+	if !ok {
+		return ""
+	}
+
+	if _, ok := value.(string); !ok {
+		warnf("%s of %s is not a string, the tool has no category", tooldesc.CategoryExtension, operation.OperationID)
+	}
+	return tooldesc.Category(operation)
+}
+
+// descriptionOptions returns the options of the tool descriptions chosen with
+// the generator flags
+func descriptionOptions() tooldesc.Options {
+	return tooldesc.Options{
+		Source:        CLI.DescriptionSource,
+		DescribeLinks: CLI.DescribeLinks,
+		Suffixes:      CLI.DescriptionSuffixMap,
 	}
 }
+
+// hasSuccessResponseHeader reports whether any 2xx response of the operation
+// declares the header, header names are case-insensitive
+func hasSuccessResponseHeader(operation *openapi3.Operation, header string) bool {
+	if operation.Responses == nil {
+		return false
+	}
+
+	for status, response := range operation.Responses.Map() {
+		if !strings.HasPrefix(status, "2") || response == nil || response.Value == nil {
+			continue
+		}
+		for name := range response.Value.Headers {
+			if strings.EqualFold(name, header) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// collectResponseContentTypes returns the sorted media types declared by the
+// operation responses
+func collectResponseContentTypes(operation *openapi3.Operation) []string {
+	if operation.Responses == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var contentTypes []string
+	for _, response := range operation.Responses.Map() {
+		if response == nil || response.Value == nil {
+			continue
+		}
+		for contentType := range response.Value.Content {
+			if !seen[contentType] {
+				seen[contentType] = true
+				contentTypes = append(contentTypes, contentType)
+			}
+		}
+	}
+	sort.Strings(contentTypes)
+
+	return contentTypes
+}
+
+// collectParameters collects the effective parameters of the operation with
+// their serialization, warning about the ones the generated client cannot encode
+func collectParameters(operation *openapi3.Operation, pathParameters openapi3.Parameters) []ParameterInfo {
+	var parameters []ParameterInfo
+	for _, param := range tooldesc.EffectiveParameters(operation, pathParameters) {
+		// Parameters declaring content instead of schema are not styled
+		info := ParameterInfo{Name: param.Name, In: param.In, Required: param.Required, Description: param.Description}
+		info.IsBoolean = param.Schema != nil && param.Schema.Value != nil && param.Schema.Value.Type.Is(openapi3.TypeBoolean)
+		if param.Schema != nil {
+			info.Schema = param.Schema.Value
+		}
+		if sm, err := param.SerializationMethod(); err == nil && param.Content == nil {
+			info.Style = sm.Style
+			info.Explode = sm.Explode
+		}
+		for contentType, mediaType := range param.Content {
+			info.ContentType = contentType
+			if mediaType.Schema != nil {
+				info.Schema = mediaType.Schema.Value
+			}
+		}
+
+		// The client runtime only knows how to delimit arrays
+		isArray := param.Schema != nil && param.Schema.Value != nil && param.Schema.Value.Type.Is(openapi3.TypeArray)
+		switch info.Style {
+		case openapi3.SerializationSpaceDelimited, openapi3.SerializationPipeDelimited:
+			if !isArray {
+				warnf("parameter %s of %s uses style %s on a non-array value which the client cannot serialize", param.Name, operation.OperationID, info.Style)
+			}
+		case openapi3.SerializationDeepObject:
+			if !info.Explode {
+				warnf("parameter %s of %s uses style deepObject without explode which the client cannot serialize", param.Name, operation.OperationID)
+			}
+		}
+
+		parameters = append(parameters, info)
+	}
+
+	return parameters
+}
@@ -0,0 +1,786 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+// defaultCLI is the flags structure before any parsing, restored before each
+// generation as the generator keeps its flags in a global
+var defaultCLI = CLI
+
+// booksSpec is the spec of the example server, with a PUT and a GET operation
+const booksSpec = "testdata/books.yaml"
+
+// parseFlags resets the generator state and parses the generator flags as the
+// command line does
+func parseFlags(t *testing.T, args ...string) {
+	t.Helper()
+	CLI = defaultCLI
+	report = Report{Operations: []string{}, Files: []string{}, Warnings: []string{}, Timings: map[string]int64{}}
+	parser, err := kong.New(&CLI, kong.Name("mcp-rest-server-gen"), kong.Vars{"defaultServerURL": defaultServerURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Parse(args); err != nil {
+		t.Fatalf("parsing %v: %v", args, err)
+	}
+}
+
+// runGenerator generates a server from the spec with the flags, returning the
+// generator error
+func runGenerator(t *testing.T, spec string, args ...string) (string, error) {
+	t.Helper()
+	output := filepath.Join(t.TempDir(), "main.go")
+	parseFlags(t, append([]string{"--spec", spec, "--output", output}, args...)...)
+	if err := generateMCPServer(); err != nil {
+		return "", err
+	}
+	code, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(code), nil
+}
+
+// generate returns the code of the server generated from the spec with the flags
+func generate(t *testing.T, spec string, args ...string) string {
+	t.Helper()
+	code, err := runGenerator(t, spec, args...)
+	if err != nil {
+		t.Fatalf("generating from %s with %v: %v", spec, args, err)
+	}
+	return code
+}
+
+// writeSpec writes the spec to a file of the test and returns its path
+func writeSpec(t *testing.T, spec string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertContains fails when the generated code lacks one of the snippets
+func assertContains(t *testing.T, code string, snippets ...string) {
+	t.Helper()
+	for _, snippet := range snippets {
+		if !strings.Contains(code, snippet) {
+			t.Errorf("generated code lacks %q", snippet)
+		}
+	}
+}
+
+// assertNotContains fails when the generated code has one of the snippets
+func assertNotContains(t *testing.T, code string, snippets ...string) {
+	t.Helper()
+	for _, snippet := range snippets {
+		if strings.Contains(code, snippet) {
+			t.Errorf("generated code has %q", snippet)
+		}
+	}
+}
+
+// assertWarning fails when no warning of the last generation contains the text
+func assertWarning(t *testing.T, text string) {
+	t.Helper()
+	for _, warning := range report.Warnings {
+		if strings.Contains(warning, text) {
+			return
+		}
+	}
+	t.Errorf("no warning contains %q, got %q", text, report.Warnings)
+}
+
+var (
+	// buildModule is the module the generated servers are built in, shared by
+	// the tests of the run
+	buildModule     string
+	buildModuleErr  error
+	buildModuleOnce sync.Once
+)
+
+// TestMain removes the module of the generated servers once the tests ran
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if buildModule != "" {
+		os.RemoveAll(buildModule)
+	}
+	os.Exit(code)
+}
+
+// setupBuildModule creates the module the generated servers are built in,
+// requiring the versions of this module so they build from the module cache
+func setupBuildModule() (string, error) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		return "", err
+	}
+	goMod, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		return "", err
+	}
+
+	// The tool directives of this module are not needed to build the servers
+	var lines []string
+	for _, line := range strings.Split(string(goMod), "\n") {
+		switch {
+		case strings.HasPrefix(line, "module "):
+			line = "module gentest"
+		case strings.HasPrefix(line, "tool "):
+			continue
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines,
+		"require github.com/renato0307/go-mcp-rest v0.0.0",
+		"replace github.com/renato0307/go-mcp-rest => "+root,
+	)
+
+	dir, err := os.MkdirTemp("", "mcp-rest-gentest")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0644); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// packageName turns the test name into the name of a package directory
+var packageName = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// buildServer generates the server and its client from the spec with the
+// flags and builds it, returning the path of the binary. The tests building
+// servers are skipped in short mode.
+func buildServer(t *testing.T, spec string, args ...string) string {
+	t.Helper()
+	dir, _ := serverDir(t)
+	parseFlags(t, append([]string{
+		"--spec", spec,
+		"--output", filepath.Join(dir, "main.go"),
+		"--with-client", "--client-output-dir", filepath.Join(dir, "api"),
+	}, args...)...)
+	if err := generateMCPServer(); err != nil {
+		t.Fatalf("generating from %s with %v: %v", spec, args, err)
+	}
+	return compileServer(t, dir)
+}
+
+// serverDir returns the empty package directory of the server of the test in
+// the module the generated servers are built in, with its import path
+func serverDir(t *testing.T) (string, string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building the generated server is skipped in short mode")
+	}
+	buildModuleOnce.Do(func() {
+		buildModule, buildModuleErr = setupBuildModule()
+	})
+	if buildModuleErr != nil {
+		t.Fatal(buildModuleErr)
+	}
+
+	name := strings.ToLower(packageName.ReplaceAllString(t.Name(), "_"))
+	dir := filepath.Join(buildModule, name)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir, "gentest/" + name
+}
+
+// compileServer builds the server generated in the directory, returning the
+// path of the binary
+func compileServer(t *testing.T, dir string) string {
+	t.Helper()
+	binary := filepath.Join(dir, "server")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = dir
+	build.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building the generated server: %v\n%s", err, output)
+	}
+	return binary
+}
+
+// mcpSession is a generated server started by a test, talking JSON-RPC over
+// its standard input and output
+type mcpSession struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	messages chan map[string]json.RawMessage
+	stderr   *syncBuffer
+	nextID   int
+}
+
+// syncBuffer collects the logs of a server while it runs
+type syncBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
+
+// startServer starts the server binary with the flags and the environment
+// variables added to the ones of the test, by default the API credentials.
+// The server is stopped when the test ends.
+func startServer(t *testing.T, binary string, env []string, args ...string) *mcpSession {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	cmd.Env = append(append(os.Environ(), "API_USERNAME=user", "API_PASSWORD=secret"), env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &mcpSession{cmd: cmd, stdin: stdin, messages: make(chan map[string]json.RawMessage, 100), stderr: &syncBuffer{}}
+	cmd.Stderr = session.stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		if t.Failed() {
+			t.Logf("server logs:\n%s", session.stderr.String())
+		}
+	})
+
+	go func() {
+		defer close(session.messages)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var message map[string]json.RawMessage
+			if json.Unmarshal(scanner.Bytes(), &message) == nil {
+				session.messages <- message
+			}
+		}
+	}()
+	return session
+}
+
+// send writes a JSON-RPC message to the server
+func (s *mcpSession) send(t *testing.T, message map[string]any) {
+	t.Helper()
+	message["jsonrpc"] = "2.0"
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.stdin.Write(append(data, '\n')); err != nil {
+		t.Fatalf("writing to the server: %v", err)
+	}
+}
+
+// request sends a JSON-RPC request and returns its result, the notifications
+// received meanwhile are returned too
+func (s *mcpSession) request(t *testing.T, method string, params any) (json.RawMessage, []map[string]json.RawMessage) {
+	t.Helper()
+	s.nextID++
+	id := s.nextID
+	s.send(t, map[string]any{"id": id, "method": method, "params": params})
+
+	var notifications []map[string]json.RawMessage
+	timeout := time.After(20 * time.Second)
+	for {
+		select {
+		case message, ok := <-s.messages:
+			if !ok {
+				t.Fatalf("server exited waiting for the %s response\n%s", method, s.stderr.String())
+			}
+			if _, ok := message["id"]; !ok {
+				notifications = append(notifications, message)
+				continue
+			}
+			if string(message["id"]) != fmt.Sprint(id) {
+				continue
+			}
+			if message["error"] != nil {
+				t.Fatalf("%s failed: %s", method, message["error"])
+			}
+			return message["result"], notifications
+		case <-timeout:
+			t.Fatalf("no %s response\n%s", method, s.stderr.String())
+		}
+	}
+}
+
+// callResult is the result of a tool call
+type callResult struct {
+	Content []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Resource *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"resource"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// text returns the text of the first content of the result
+func (r callResult) text() string {
+	if len(r.Content) == 0 {
+		return ""
+	}
+	return r.Content[0].Text
+}
+
+// callTool calls the tool with the arguments
+func (s *mcpSession) callTool(t *testing.T, name string, arguments any) callResult {
+	t.Helper()
+	data, _ := s.request(t, "tools/call", map[string]any{"name": name, "arguments": arguments})
+	var result callResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("decoding the %s result %s: %v", name, data, err)
+	}
+	return result
+}
+
+// listTools returns the descriptions of the registered tools by name
+func (s *mcpSession) listTools(t *testing.T) map[string]string {
+	t.Helper()
+	data, _ := s.request(t, "tools/list", map[string]any{})
+	var result struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("decoding the tools %s: %v", data, err)
+	}
+	tools := make(map[string]string, len(result.Tools))
+	for _, tool := range result.Tools {
+		tools[tool.Name] = tool.Description
+	}
+	return tools
+}
+
+func TestDescribeLinks(t *testing.T) {
+	code := generate(t, "testdata/links.yaml", "--describe-links")
+	assertContains(t, code,
+		`Related operations:\n- ListBooks (after a 201 response): pass id=$response.body#/id. Fetch the created book`,
+	)
+
+	code = generate(t, "testdata/links.yaml")
+	assertNotContains(t, code, "Related operations")
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	code := generate(t, booksSpec, "--accept-language", "pt-PT")
+	assertContains(t, code,
+		`AcceptLanguage string `+"`"+`default:"pt-PT" env:"API_ACCEPT_LANGUAGE"`,
+		`req.Header.Set("Accept-Language", cli.AcceptLanguage)`,
+	)
+
+	code = generate(t, booksSpec)
+	assertNotContains(t, code, "Accept-Language")
+}
+
+func TestAcceptLanguageSent(t *testing.T) {
+	languages := make(chan string, 2)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		languages <- r.Header.Get("Accept-Language")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--accept-language", "pt-PT")
+	for env, want := range map[string]string{"": "pt-PT", "API_ACCEPT_LANGUAGE=de": "de"} {
+		session := startServer(t, binary, []string{env}, "--host", upstream.URL)
+		if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+			t.Fatalf("ListBooks failed: %s", result.text())
+		}
+		if got := <-languages; got != want {
+			t.Errorf("Accept-Language with %q is %q, want %q", env, got, want)
+		}
+	}
+}
+
+// waitForLog waits for the server to log the text
+func (s *mcpSession) waitForLog(t *testing.T, text string) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if strings.Contains(s.stderr.String(), text) {
+			return
+		}
+	}
+	t.Fatalf("the server did not log %q\n%s", text, s.stderr.String())
+}
+
+func TestPipeDelimitedParameter(t *testing.T) {
+	generate(t, "testdata/styles.yaml")
+	assertWarning(t, "parameter author of ListBooks uses style pipeDelimited on a non-array value")
+
+	queries := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.RawQuery
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/styles.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{"ids": []string{"a", "b", "c"}}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	if got, want := <-queries, "ids=a%7Cb%7Cc"; got != want {
+		t.Errorf("the query is %q, want %q", got, want)
+	}
+}
+
+// respond returns a handler answering the body with the content type and status
+func respond(status int, contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func TestResponseContentTypes(t *testing.T) {
+	var handler http.HandlerFunc
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/problem.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	handler = respond(http.StatusOK, "application/json", `["Dune"]`)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError || !strings.Contains(result.text(), "Dune") {
+		t.Errorf("the JSON response was presented as %+v", result)
+	}
+
+	// The problem details are an error even with a success status
+	handler = respond(http.StatusOK, "application/problem+json", `{"title":"Catalog offline"}`)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); !result.IsError || !strings.Contains(result.text(), "Catalog offline") {
+		t.Errorf("the problem details response was presented as %+v", result)
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	upstream := httptest.NewServer(respond(http.StatusBadRequest, "application/problem+json",
+		`{"type":"about:blank","title":"Invalid book","detail":"The name is required","errors":[{"field":"name"}]}`))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/problem.yaml", "--auth-type", "none", "--problem-details")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "ListBooks", map[string]any{})
+	if !result.IsError {
+		t.Fatalf("the problem details did not fail the call: %s", result.text())
+	}
+	if want := `Invalid book: The name is required (errors: [{"field":"name"}])`; !strings.Contains(result.text(), want) {
+		t.Errorf("the error is %q, want it to contain %q", result.text(), want)
+	}
+}
+
+func TestResponseVariants(t *testing.T) {
+	assertNotContains(t, generate(t, booksSpec), "responseVariant")
+
+	handler := respond(http.StatusOK, "application/json", `{"name":"Dune"}`)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/variants.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		status      int
+		body, label string
+	}{
+		{http.StatusOK, `{"name":"Dune"}`, `{"response":{"description":"Updated the book","schema":"Book","status":200}}`},
+		{http.StatusCreated, `{"id":7}`, `{"response":{"description":"Created the book","schema":"Created","status":201}}`},
+	}
+	for _, test := range tests {
+		handler = respond(test.status, "application/json", test.body)
+		result := session.callTool(t, "SaveBook", map[string]any{"name": "Dune"})
+		if result.IsError || len(result.Content) != 2 {
+			t.Fatalf("SaveBook answered with %d returned %+v", test.status, result)
+		}
+		if got := result.Content[0].Text; !strings.Contains(got, test.body) {
+			t.Errorf("the %d body is %s, want %s", test.status, got, test.body)
+		}
+		if got := result.Content[1].Text; got != test.label {
+			t.Errorf("the %d response is labelled %s, want %s", test.status, got, test.label)
+		}
+	}
+}
+
+func TestDescriptionSuffixMap(t *testing.T) {
+	generate(t, booksSpec, "--description-suffix-map", "ListBooks=Paginated by 50.;RemoveBook=Requires admin role.")
+	assertWarning(t, "description suffix given for unknown operation RemoveBook")
+
+	binary := buildServer(t, booksSpec, "--description-suffix-map", "ListBooks=Paginated by 50.")
+	tools := startServer(t, binary, nil).listTools(t)
+	if got, want := tools["ListBooks"], "Lists books filtering by name.\n\nPaginated by 50."; got != want {
+		t.Errorf("the ListBooks description is %q, want %q", got, want)
+	}
+
+	// The operations missing from the map keep their description
+	if got, want := tools["AddBook"], "Adds a new book"; got != want {
+		t.Errorf("the AddBook description is %q, want %q", got, want)
+	}
+}
+
+func TestToolCategories(t *testing.T) {
+	generate(t, "testdata/categories.yaml", "--auth-type", "none")
+	assertWarning(t, "x-mcp-category of ListCustomers is not a string, the tool has no category")
+
+	binary := buildServer(t, "testdata/categories.yaml", "--auth-type", "none")
+	tools := startServer(t, binary, nil).listTools(t)
+	want := map[string]string{
+		"ListInvoices":  "[Billing] Lists the invoices",
+		"ListCustomers": "Lists the customers",
+		"ListBooks":     "Lists the books",
+	}
+	for name, description := range want {
+		if got := tools[name]; got != description {
+			t.Errorf("the %s description is %q, want %q", name, got, description)
+		}
+	}
+}
+
+func TestMaxTools(t *testing.T) {
+	_, err := runGenerator(t, "testdata/tickets.yaml", "--auth-type", "none", "--max-tools", "3")
+	if err == nil || !strings.Contains(err.Error(), "the spec yields 5 tools, more than the maximum of 3") {
+		t.Errorf("generating more tools than the maximum failed with %v", err)
+	}
+
+	code := generate(t, "testdata/tickets.yaml", "--auth-type", "none", "--max-tools", "3", "--max-tools-policy", "truncate")
+	if want := []string{"CloseTicket", "CreateTicket", "DeleteTicket"}; !slices.Equal(report.Operations, want) {
+		t.Errorf("the generated operations are %v, want %v", report.Operations, want)
+	}
+	assertNotContains(t, code, `"GetTicket"`, `"ListTickets"`)
+	assertWarning(t, "only the first 3 are generated, dropped: GetTicket, ListTickets")
+
+	// The same tools are kept on every run
+	for range 5 {
+		if again := generate(t, "testdata/tickets.yaml", "--auth-type", "none", "--max-tools", "3", "--max-tools-policy", "truncate"); again != code {
+			t.Fatal("the truncated servers differ between runs")
+		}
+	}
+}
+
+func TestContentParameter(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/content.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "SearchBooks", map[string]any{"filter": map[string]any{"author": "Austen", "year": 1815}, "q": "pride"})
+	if result.IsError {
+		t.Fatalf("SearchBooks failed: %s", result.text())
+	}
+
+	// The JSON content parameter is encoded by the client
+	query := <-queries
+	if got, want := query.Get("filter"), `{"author":"Austen","year":1815}`; got != want {
+		t.Errorf("the filter parameter is %q, want %q", got, want)
+	}
+	if got := query.Get("q"); got != "pride" {
+		t.Errorf("the q parameter is %q, want pride", got)
+	}
+}
+
+func TestCallbacks(t *testing.T) {
+	generate(t, "testdata/callbacks.yaml")
+	assertWarning(t, "callbacks are not supported and are ignored for operations: Subscribe (onEvent)")
+
+	_, err := runGenerator(t, "testdata/callbacks.yaml", "--strict")
+	if err == nil || !strings.Contains(err.Error(), "Subscribe (onEvent)") {
+		t.Errorf("generating strictly from a spec with callbacks failed with %v", err)
+	}
+}
+
+func TestPathItemParameters(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/pathparams.yaml", "--auth-type", "none")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The parameters of the path item are arguments of each of its tools
+	data, _ := session.request(t, "tools/list", map[string]any{})
+	var result struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("decoding the tools %s: %v", data, err)
+	}
+	for _, tool := range result.Tools {
+		for _, name := range []string{"tenant", "locale"} {
+			if _, ok := tool.InputSchema.Properties[name]; !ok {
+				t.Errorf("the %s tool has no %s argument: %v", tool.Name, name, tool.InputSchema.Properties)
+			}
+		}
+	}
+
+	// The path item parameters are forwarded with those of the operation
+	tests := []struct {
+		tool string
+		args map[string]any
+		want url.Values
+	}{
+		{"CountBooks", map[string]any{"tenant": "acme", "locale": "pt"}, url.Values{"tenant": {"acme"}, "locale": {"pt"}}},
+		{"ListBooks", map[string]any{"tenant": "acme", "locale": "en", "q": "dune"}, url.Values{"tenant": {"acme"}, "locale": {"en"}, "q": {"dune"}}},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, test.tool, test.args); result.IsError {
+			t.Errorf("%s failed with %s", test.tool, result.text())
+			continue
+		}
+		if got := <-queries; !maps.EqualFunc(got, test.want, slices.Equal) {
+			t.Errorf("%s sent the query %v, want %v", test.tool, got, test.want)
+		}
+	}
+}
+
+func TestMultiFileSpec(t *testing.T) {
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", `[{"name":"Herbert"}]`))
+	defer upstream.Close()
+
+	// The path items are in nested files referencing further files
+	binary := buildServer(t, "testdata/multifile/openapi.yaml")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tools := session.listTools(t)
+	for name, want := range map[string]string{"ListBooks": "Lists the books", "ListAuthors": "Lists the authors"} {
+		if got := tools[name]; got != want {
+			t.Errorf("the %s description is %q, want %q", name, got, want)
+		}
+	}
+	if result := session.callTool(t, "ListAuthors", map[string]any{"q": "Herbert"}); result.IsError || !strings.Contains(result.text(), "Herbert") {
+		t.Errorf("ListAuthors returned %+v", result)
+	}
+}
+
+func TestStdinSpec(t *testing.T) {
+	want := generate(t, booksSpec, "--auth-type", "none")
+
+	// The piped spec gives the server of the same spec read from its file
+	spec, err := os.Open(booksSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spec.Close()
+	stdin := os.Stdin
+	os.Stdin = spec
+	defer func() { os.Stdin = stdin }()
+	if got := generate(t, stdinSpec, "--auth-type", "none"); got != want {
+		t.Errorf("the server generated from standard input differs from the one of %s:\n%s", booksSpec, got)
+	}
+}
+
+func TestOnDuplicateRename(t *testing.T) {
+	paths := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		respond(http.StatusOK, "application/json", `["Dune"]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	// The former name of --dedupe-strategy is still accepted
+	binary := buildServer(t, "testdata/versioned.yaml", "--auth-type", "none", "--on-duplicate", "rename")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tools := session.listTools(t)
+	for _, name := range []string{"ListBooksV1", "ListBooksV2", "ListAuthorsV1", "ListAuthorsV2"} {
+		if _, ok := tools[name]; !ok {
+			t.Errorf("no %s tool in %v", name, tools)
+		}
+	}
+
+	// Each renamed tool calls the path of its version
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"ListBooksV1", map[string]any{}, "/v1/books"},
+		{"ListBooksV2", map[string]any{}, "/v2/books"},
+		{"ListAuthorsV2", map[string]any{"q": "Herbert"}, "/v2/authors"},
+	}
+	for _, test := range tests {
+		if result := session.callTool(t, test.tool, test.args); result.IsError {
+			t.Errorf("%s failed with %s", test.tool, result.text())
+			continue
+		}
+		if got := <-paths; got != test.want {
+			t.Errorf("%s called %s, want %s", test.tool, got, test.want)
+		}
+	}
+}
+
+func TestDuplicateOperationIDs(t *testing.T) {
+	_, err := runGenerator(t, "testdata/versioned.yaml", "--auth-type", "none")
+	if err == nil {
+		t.Fatal("the spec with duplicate operationIds was accepted")
+	}
+
+	// Every collision is reported at once with its operations
+	for _, want := range []string{
+		"ListAuthors (GET /v1/authors, GET /v2/authors)",
+		"ListBooks (GET /v1/books, GET /v2/books)",
+		"--dedupe-strategy=rename",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("the error %q does not list %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Import paths of the MCP libraries the generated server can be built on
+const (
+	metoroMCP       = "github.com/metoro-io/mcp-golang"
+	mark3labsMCP    = "github.com/mark3labs/mcp-go/mcp"
+	mark3labsServer = "github.com/mark3labs/mcp-go/server"
+)
+
+// useMark3labs reports whether the generated server is built on the
+// mark3labs/mcp-go library instead of the metoro-io one
+func useMark3labs() bool {
+	return CLI.MCPLibrary == "mark3labs"
+}
+
+// checkMCPLibrary reports the options that the selected MCP library does not
+// support, they rely on metoro-io internals such as its transports, or on the
+// HTTP transports that mcp-golang v0.8.0 does not provide working
+func checkMCPLibrary() error {
+	if !useMark3labs() {
+		if CLI.Transport != "stdio" {
+			return fmt.Errorf("--transport=%s is only supported with --mcp-library=mark3labs", CLI.Transport)
+		}
+		return nil
+	}
+
+	unsupported := map[string]bool{
+		"--dynamic-tools":    CLI.DynamicTools,
+		"--mcp-logging":      CLI.MCPLogging,
+		"--reload-on-sighup": CLI.ReloadOnSighup,
+	}
+	for _, flag := range []string{"--dynamic-tools", "--mcp-logging", "--reload-on-sighup"} {
+		if unsupported[flag] {
+			return fmt.Errorf("%s is not supported with --mcp-library=mark3labs", flag)
+		}
+	}
+	return nil
+}
+
+// toolResultType returns the type of the results of the tool handlers
+func toolResultType() *jen.Statement {
+	return jen.Op("*").Add(toolResultStruct())
+}
+
+// toolResultStruct returns the struct type the tool results point to
+func toolResultStruct() *jen.Statement {
+	if useMark3labs() {
+		return jen.Qual(mark3labsMCP, "CallToolResult")
+	}
+	return jen.Qual(metoroMCP, "ToolResponse")
+}
+
+// contentType returns the type of the contents of the tool results
+func contentType() *jen.Statement {
+	if useMark3labs() {
+		return jen.Qual(mark3labsMCP, "Content")
+	}
+	return jen.Op("*").Qual(metoroMCP, "Content")
+}
+
+// textContent returns the expression of a text content holding the text
+func textContent(text jen.Code) *jen.Statement {
+	if useMark3labs() {
+		return jen.Qual(mark3labsMCP, "NewTextContent").Call(text)
+	}
+	return jen.Qual(metoroMCP, "NewTextContent").Call(text)
+}
+
+// toolResult returns the expression of a tool result holding the contents
+func toolResult(contents ...jen.Code) *jen.Statement {
+	if useMark3labs() {
+		return jen.Op("&").Qual(mark3labsMCP, "CallToolResult").Values(jen.Dict{
+			jen.Id("Content"): jen.Index().Qual(mark3labsMCP, "Content").Values(contents...),
+		})
+	}
+	return jen.Qual(metoroMCP, "NewToolResponse").Call(contents...)
+}
+
+// newServerCode returns the statement creating the MCP server, the metoro-io
+// server is given its transport while the mark3labs one is served later
+func newServerCode(doc *openapi3.T, transport jen.Code) jen.Code {
+	if !useMark3labs() {
+		return jen.Id("server").Op(":=").Qual(metoroMCP, "NewServer").Call(transport)
+	}
+
+	name, version := "", ""
+	if doc.Info != nil {
+		name, version = doc.Info.Title, doc.Info.Version
+	}
+	return jen.Id("server").Op(":=").Qual(mark3labsServer, "NewMCPServer").Call(
+		jen.Lit(name), jen.Lit(version), jen.Qual(mark3labsServer, "WithToolCapabilities").Call(jen.False()),
+	)
+}
+
+// serveCode returns the statements starting to serve the MCP server, the
+// mark3labs server stops the generated server when the client goes away or
+// the HTTP server of its transport fails
+func serveCode() []jen.Code {
+	if !useMark3labs() {
+		return []jen.Code{
+			jen.Err().Op("=").Id("server").Dot("Serve").Call(),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Panic(jen.Err()),
+			),
+		}
+	}
+
+	if CLI.Transport == "stdio" {
+		return []jen.Code{
+			jen.Go().Func().Params().Block(
+				jen.If(
+					jen.Err().Op(":=").Qual(mark3labsServer, "NewStdioServer").Call(jen.Id("server")).Dot("Listen").Call(
+						jen.Id("serverCtx"), jen.Qual("os", "Stdin"), jen.Qual("os", "Stdout"),
+					),
+					jen.Err().Op("!=").Nil().Op("&&").Id("serverCtx").Dot("Err").Call().Op("==").Nil(),
+				).Block(
+					jen.Qual("log/slog", "Error").Call(jen.Lit("Error serving MCP"), jen.Lit("error"), jen.Err()),
+				),
+				jen.Id("stop").Call(),
+			).Call(),
+		}
+	}
+
+	// The HTTP transports are served on the listen address, the bound address
+	// is logged as the port may be chosen by the system
+	handler := jen.Qual(mark3labsServer, "NewSSEServer").Call(jen.Id("server"))
+	name := "SSE"
+	if CLI.Transport == "http" {
+		handler = jen.Qual(mark3labsServer, "NewStreamableHTTPServer").Call(jen.Id("server"))
+		name = "streamable HTTP"
+	}
+	code := []jen.Code{
+		jen.Var().Id("mcpHandler").Qual("net/http", "Handler").Op("=").Add(handler),
+	}
+	if clientAuth() {
+		code = append(code, jen.Id("mcpHandler").Op("=").Id("requireClientToken").Call(jen.Id("mcpHandler"), jen.Id("cli").Dot("ClientAuthToken")))
+	}
+	return append(code,
+		jen.List(jen.Id("listener"), jen.Err()).Op(":=").Qual("net", "Listen").Call(jen.Lit("tcp"), jen.Id("cli").Dot("ListenAddr")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("log", "Fatal").Call(jen.Err()),
+		),
+		jen.Id("httpServer").Op(":=").Op("&").Qual("net/http", "Server").Values(jen.Dict{jen.Id("Handler"): jen.Id("mcpHandler")}),
+		jen.Defer().Id("httpServer").Dot("Close").Call(),
+		jen.Go().Func().Params().Block(
+			jen.If(
+				jen.Err().Op(":=").Id("httpServer").Dot("Serve").Call(jen.Id("listener")),
+				jen.Op("!").Qual("errors", "Is").Call(jen.Err(), jen.Qual("net/http", "ErrServerClosed")),
+			).Block(
+				jen.Qual("log/slog", "Error").Call(jen.Lit("Error serving MCP"), jen.Lit("error"), jen.Err()),
+			),
+			jen.Id("stop").Call(),
+		).Call(),
+		jen.Qual("log/slog", "Info").Call(jen.Lit("Serving MCP over "+name), jen.Lit("address"), jen.Id("listener").Dot("Addr").Call().Dot("String").Call()),
+	)
+}
+
+// listenAddrField returns the field of the generated server configuration
+// holding the address the HTTP transports listen on
+func listenAddrField() ConfigField {
+	return ConfigField{
+		Name: "ListenAddr", Type: jen.String(),
+		Tags: map[string]string{"help": "Address the MCP server listens on, a port of 0 is chosen by the system", "default": CLI.ListenAddr},
+	}
+}
+
+// registerTool returns the statements registering a tool on the registrar,
+// the mark3labs tools take their input schema from the options or, when they
+// are nil, from the arguments type as the metoro-io library does
+func registerTool(registrar, name, description string, argsType, handler jen.Code, options []jen.Code) []jen.Code {
+	if !useMark3labs() {
+		return []jen.Code{
+			jen.Err().Op("=").Id(registrar).Dot("RegisterTool").Call(
+				jen.Lit(name),
+				jen.Lit(description),
+				handler,
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Panic(jen.Err()),
+			),
+		}
+	}
+
+	tool := jen.Qual(mark3labsMCP, "NewToolWithRawSchema").Call(jen.Lit(name), jen.Lit(description), jen.Id("inputSchema").Types(argsType).Call())
+	if options != nil {
+		tool = jen.Qual(mark3labsMCP, "NewTool").Call(
+			append([]jen.Code{jen.Lit(name), jen.Qual(mark3labsMCP, "WithDescription").Call(jen.Lit(description))}, options...)...,
+		)
+	}
+	return []jen.Code{
+		jen.Id(registrar).Dot("AddTool").Call(tool, jen.Id("toolHandler").Call(handler)),
+	}
+}
+
+// parameterOptions returns the mark3labs tool options declaring the tool
+// arguments of an operation taking only its parameters, one per query, header
+// or cookie parameter named as in the client parameters type. The arguments
+// the options cannot type, such as integers and objects, take the schema
+// reflected from their field of the arguments type.
+func parameterOptions(op OperationInfo, argsType jen.Code) []jen.Code {
+	options := []jen.Code{}
+	for _, param := range op.Parameters {
+		if param.In == openapi3.ParameterInPath {
+			continue
+		}
+
+		// Parameters without a description are described by their schema
+		schema := param.Schema
+		description := param.Description
+		if description == "" && schema != nil {
+			description = schema.Description
+		}
+
+		var propertyOptions []jen.Code
+		option := "WithAny"
+		switch {
+		case schema == nil || param.ContentType != "":
+		case schema.Type.Is(openapi3.TypeString):
+			option = "WithString"
+			if values := stringEnum(schema); len(values) > 0 {
+				propertyOptions = append(propertyOptions, jen.Qual(mark3labsMCP, "Enum").Call(values...))
+			}
+		case schema.Type.Is(openapi3.TypeNumber):
+			option = "WithNumber"
+		case schema.Type.Is(openapi3.TypeBoolean):
+			option = "WithBoolean"
+		case schema.Type.Is(openapi3.TypeArray):
+			if items := arrayItemsOption(schema); items != nil {
+				option = "WithArray"
+				propertyOptions = append(propertyOptions, items)
+			}
+		}
+		if option == "WithAny" {
+			propertyOptions = append(propertyOptions, jen.Id("argumentSchema").Types(argsType).Call(jen.Lit(param.Name)))
+		}
+
+		if param.Required {
+			propertyOptions = append(propertyOptions, jen.Qual(mark3labsMCP, "Required").Call())
+		}
+		if description != "" {
+			propertyOptions = append(propertyOptions, jen.Qual(mark3labsMCP, "Description").Call(jen.Lit(description)))
+		}
+
+		options = append(options, jen.Qual(mark3labsMCP, option).Call(append([]jen.Code{jen.Lit(param.Name)}, propertyOptions...)...))
+	}
+	return options
+}
+
+// stringEnum returns the enum values of a string schema as literals
+func stringEnum(schema *openapi3.Schema) []jen.Code {
+	values := make([]jen.Code, 0, len(schema.Enum))
+	for _, value := range schema.Enum {
+		text, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		values = append(values, jen.Lit(text))
+	}
+	return values
+}
+
+// arrayItemsOption returns the property option typing the items of an array
+// schema, with their values when they are a string enum, nil when the items
+// are not strings, numbers or booleans
+func arrayItemsOption(schema *openapi3.Schema) jen.Code {
+	if schema.Items == nil || schema.Items.Value == nil {
+		return nil
+	}
+
+	items := schema.Items.Value
+	switch {
+	case items.Type.Is(openapi3.TypeString):
+		if values := stringEnum(items); len(values) > 0 {
+			return jen.Qual(mark3labsMCP, "WithStringItems").Call(jen.Qual(mark3labsMCP, "Enum").Call(values...))
+		}
+		return jen.Qual(mark3labsMCP, "WithStringItems").Call()
+	case items.Type.Is(openapi3.TypeNumber):
+		return jen.Qual(mark3labsMCP, "WithNumberItems").Call()
+	case items.Type.Is(openapi3.TypeBoolean):
+		return jen.Qual(mark3labsMCP, "WithBooleanItems").Call()
+	}
+	return nil
+}
+
+// addMark3labsAdapters adds the functions adapting the tool handlers taking
+// their arguments type to the mark3labs handlers and reflecting the input
+// schema of the arguments type
+func addMark3labsAdapters(f *jen.File) {
+	handlerType := handlerSignature(jen.Id("T"))
+
+	f.Comment("toolHandler adapts a handler taking the decoded tool arguments to a mark3labs tool")
+	f.Comment("handler, the errors of the handler are returned as tool errors")
+	f.Func().Id("toolHandler").Types(jen.Id("T").Any()).Params(
+		jen.Id("handler").Add(handlerType),
+	).Qual(mark3labsServer, "ToolHandlerFunc").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("request").Qual(mark3labsMCP, "CallToolRequest"),
+		).Params(toolResultType(), jen.Error()).Block(
+			jen.Var().Id("arguments").Id("T"),
+			jen.If(jen.Err().Op(":=").Id("request").Dot("BindArguments").Call(jen.Op("&").Id("arguments")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Qual(mark3labsMCP, "NewToolResultError").Call(jen.Lit("invalid arguments: ").Op("+").Err().Dot("Error").Call()), jen.Nil()),
+			),
+			jen.List(jen.Id("result"), jen.Err()).Op(":=").Id("handler").Call(jen.Id("arguments")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Qual(mark3labsMCP, "NewToolResultError").Call(jen.Err().Dot("Error").Call()), jen.Nil()),
+			),
+			jen.Return(jen.Id("result"), jen.Nil()),
+		)),
+	)
+
+	f.Comment("inputSchema returns the JSON schema of the tool arguments type, reflected from the")
+	f.Comment("jsonschema tags of its fields")
+	f.Func().Id("inputSchema").Types(jen.Id("T").Any()).Params().Qual("encoding/json", "RawMessage").Block(
+		jen.Id("reflector").Op(":=").Qual("github.com/invopop/jsonschema", "Reflector").Values(jen.Dict{
+			jen.Id("Anonymous"):                  jen.True(),
+			jen.Id("AllowAdditionalProperties"):  jen.True(),
+			jen.Id("RequiredFromJSONSchemaTags"): jen.True(),
+			jen.Id("DoNotReference"):             jen.True(),
+			jen.Id("ExpandedStruct"):             jen.True(),
+		}),
+		jen.List(jen.Id("schema"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(
+			jen.Id("reflector").Dot("ReflectFromType").Call(jen.Qual("reflect", "TypeFor").Types(jen.Id("T")).Call()),
+		),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Panic(jen.Err()),
+		),
+		jen.Return(jen.Id("schema")),
+	)
+
+	f.Comment("argumentSchema returns a property option giving a tool argument the schema reflected")
+	f.Comment("from its field of the arguments type")
+	f.Func().Id("argumentSchema").Types(jen.Id("T").Any()).Params(jen.Id("name").String()).Qual(mark3labsMCP, "PropertyOption").Block(
+		jen.Var().Id("schema").Struct(
+			jen.Id("Properties").Map(jen.String()).Map(jen.String()).Any().Tag(map[string]string{"json": "properties"}),
+		),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("inputSchema").Types(jen.Id("T")).Call(), jen.Op("&").Id("schema")), jen.Err().Op("!=").Nil()).Block(
+			jen.Panic(jen.Err()),
+		),
+		jen.Return(jen.Func().Params(jen.Id("property").Map(jen.String()).Any()).Block(
+			jen.Qual("maps", "Copy").Call(jen.Id("property"), jen.Id("schema").Dot("Properties").Index(jen.Id("name"))),
+		)),
+	)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMark3labsServer(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	handler := respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		handler(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--mcp-library", "mark3labs")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	session.request(t, "initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "test", "version": "1.0"},
+	})
+
+	tools := session.listTools(t)
+	if got, want := tools["ListBooks"], "Lists books filtering by name."; got != want {
+		t.Errorf("the ListBooks description is %q, want %q", got, want)
+	}
+
+	result := session.callTool(t, "ListBooks", map[string]any{"NameFilter": "Dune"})
+	if result.IsError || !strings.Contains(result.text(), "Dune") {
+		t.Fatalf("ListBooks returned %+v", result)
+	}
+	if got := (<-queries).Get("NameFilter"); got != "Dune" {
+		t.Errorf("the NameFilter parameter is %q, want Dune", got)
+	}
+
+	// The API errors are tool errors, not JSON-RPC ones
+	handler = respond(http.StatusNotFound, "text/plain", "no such book")
+	if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); !result.IsError || !strings.Contains(result.text(), "404 Not Found: no such book") {
+		t.Errorf("the failed AddBook returned %+v", result)
+	}
+}
+
+func TestMark3labsParameterSchema(t *testing.T) {
+	code := generate(t, "testdata/params.yaml", "--mcp-library", "mark3labs")
+	assertContains(t, code,
+		`mcp.WithString("q", mcp.Required(), mcp.Description("Words of the title"))`,
+		`mcp.WithString("status", mcp.Enum("available", "lent"))`,
+		`mcp.WithAny("limit", argumentSchema[api.ListBooksParams]("limit"), mcp.Description("Maximum number of books"))`,
+		`mcp.WithNumber("minScore")`,
+		`mcp.WithBoolean("signed")`,
+		`mcp.WithArray("tags", mcp.WithStringItems(mcp.Enum("novel", "poetry")))`,
+		`mcp.WithAny("years", argumentSchema[api.ListBooksParams]("years"))`,
+		`mcp.WithAny("published", argumentSchema[api.ListBooksParams]("published"))`,
+	)
+
+	queries := make(chan url.Values, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/params.yaml", "--mcp-library", "mark3labs")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	data, _ := session.request(t, "tools/list", map[string]any{})
+	var result struct {
+		Tools []struct {
+			InputSchema struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || len(result.Tools) != 1 {
+		t.Fatalf("decoding the tools %s: %v", data, err)
+	}
+
+	// The reflected schemas type the integers and the objects
+	schema := result.Tools[0].InputSchema
+	if !slices.Equal(schema.Required, []string{"q"}) {
+		t.Errorf("the required arguments are %v, want [q]", schema.Required)
+	}
+	properties := map[string]string{
+		"q":         `{"description":"Words of the title","type":"string"}`,
+		"status":    `{"enum":["available","lent"],"type":"string"}`,
+		"limit":     `{"description":"Maximum number of books","type":"integer"}`,
+		"minScore":  `{"type":"number"}`,
+		"signed":    `{"type":"boolean"}`,
+		"tags":      `{"items":{"enum":["novel","poetry"],"type":"string"},"type":"array"}`,
+		"years":     `{"items":{"type":"integer"},"type":"array"}`,
+		"published": `{"properties":{"after":{"type":"integer"},"before":{"type":"integer"}},"type":"object"}`,
+	}
+	for name, want := range properties {
+		if got := string(schema.Properties[name]); got != want {
+			t.Errorf("the %s argument schema is %s, want %s", name, got, want)
+		}
+	}
+
+	args := map[string]any{"q": "dune", "limit": 5, "years": []int{1965, 1969}, "published": map[string]any{"after": 1960}}
+	if result := session.callTool(t, "ListBooks", args); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	want := url.Values{"q": {"dune"}, "limit": {"5"}, "years": {"1965", "1969"}, "published[after]": {"1960"}}
+	if got := <-queries; !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("ListBooks sent the query %v, want %v", got, want)
+	}
+}
+
+// listenAddress waits for the server to log the address its HTTP transport
+// listens on
+func (s *mcpSession) listenAddress(t *testing.T) string {
+	t.Helper()
+	s.waitForLog(t, "Serving MCP over")
+	match := regexp.MustCompile(`address=(\S+)`).FindStringSubmatch(s.stderr.String())
+	if match == nil {
+		t.Fatalf("the server did not log its address\n%s", s.stderr.String())
+	}
+	return match[1]
+}
+
+// sseClient is an MCP client of a server with the SSE transport, its requests
+// are posted to the message endpoint and answered on the event stream
+type sseClient struct {
+	endpoint string
+	messages chan map[string]json.RawMessage
+	nextID   int
+}
+
+// connectSSE opens the event stream of the server at the address and reads
+// the message endpoint it announces
+func connectSSE(t *testing.T, address string) *sseClient {
+	t.Helper()
+	resp, err := http.Get("http://" + address + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("opening the event stream: %s", resp.Status)
+	}
+
+	client := &sseClient{messages: make(chan map[string]json.RawMessage, 100)}
+	endpoint := make(chan string, 1)
+	go func() {
+		defer close(client.messages)
+		scanner := bufio.NewScanner(resp.Body)
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+				continue
+			}
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			var message map[string]json.RawMessage
+			switch {
+			case event == "endpoint":
+				endpoint <- data
+			case json.Unmarshal([]byte(data), &message) == nil:
+				client.messages <- message
+			}
+		}
+	}()
+
+	select {
+	case path := <-endpoint:
+		client.endpoint = "http://" + address + path
+	case <-time.After(10 * time.Second):
+		t.Fatal("the server announced no message endpoint")
+	}
+	return client
+}
+
+// request posts a JSON-RPC request and returns its result from the event stream
+func (c *sseClient) request(t *testing.T, method string, params any) json.RawMessage {
+	t.Helper()
+	c.nextID++
+	data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	resp, err := http.Post(c.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("posting %s: %s", method, resp.Status)
+	}
+
+	timeout := time.After(20 * time.Second)
+	for {
+		select {
+		case message, ok := <-c.messages:
+			if !ok {
+				t.Fatalf("the event stream closed waiting for the %s response", method)
+			}
+			if string(message["id"]) == fmt.Sprint(c.nextID) {
+				return message["result"]
+			}
+		case <-timeout:
+			t.Fatalf("no %s response", method)
+		}
+	}
+}
+
+func TestSSETransport(t *testing.T) {
+	_, err := runGenerator(t, booksSpec, "--transport", "sse")
+	if err == nil || !strings.Contains(err.Error(), "--transport=sse is only supported with --mcp-library=mark3labs") {
+		t.Errorf("generating a metoro-io SSE server failed with %v", err)
+	}
+
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--mcp-library", "mark3labs", "--transport", "sse")
+	session := startServer(t, binary, nil, "--host", upstream.URL, "--listen-addr", "127.0.0.1:0")
+	client := connectSSE(t, session.listenAddress(t))
+	client.request(t, "initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "test", "version": "1.0"},
+	})
+
+	var result callResult
+	data := client.request(t, "tools/call", map[string]any{"name": "ListBooks", "arguments": map[string]any{}})
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("decoding the ListBooks result %s: %v", data, err)
+	}
+	if result.IsError || !strings.Contains(result.text(), "Dune") {
+		t.Errorf("ListBooks returned %+v", result)
+	}
+}
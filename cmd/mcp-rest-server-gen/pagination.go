@@ -0,0 +1,229 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// hasPageTokenParameter reports whether the parameters include the page token
+// query parameter
+func hasPageTokenParameter(parameters []ParameterInfo) bool {
+	if CLI.PageTokenParam == "" {
+		return false
+	}
+	for _, param := range parameters {
+		if param.In == openapi3.ParameterInQuery && param.Name == CLI.PageTokenParam {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchesPages reports whether the handler of the operation fetches further
+// pages through the underlying client
+func (op OperationInfo) fetchesPages() bool {
+	return (CLI.AutoPaginate && op.HasLinkPagination) || op.HasPageToken
+}
+
+// anyFetchesPages reports whether the handler of any operation fetches pages
+func anyFetchesPages(operations map[string]OperationInfo) bool {
+	for _, op := range operations {
+		if op.fetchesPages() {
+			return true
+		}
+	}
+	return false
+}
+
+// addFetchLinkPages adds the function following the next links of a response
+// and aggregating the JSON array pages
+func addFetchLinkPages(f *jen.File) {
+	f.Comment("fetchLinkPages follows the RFC 5988 next links of the response and aggregates")
+	f.Comment("the JSON array pages, bodies that are not arrays are returned unchanged. The request")
+	f.Comment("editors of the client and the given ones are applied to each page request")
+	f.Func().Id("fetchLinkPages").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("client").Op("*").Qual(CLI.ClientImport, "Client"),
+		jen.Id("resp").Op("*").Qual("net/http", "Response"),
+		jen.Id("body").Index().Byte(),
+		jen.Id("maxPages").Int(),
+		jen.Id("reqEditors").Op("...").Qual(CLI.ClientImport, "RequestEditorFn"),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Var().Id("items").Index().Qual("encoding/json", "RawMessage"),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("items")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body"), jen.Nil()),
+		),
+
+		jen.Id("next").Op(":=").Id("nextLink").Call(jen.Id("resp").Dot("Header").Dot("Values").Call(jen.Lit("Link"))),
+		jen.For(jen.Id("page").Op(":=").Lit(1), jen.Id("next").Op("!=").Lit("").Op("&&").Id("page").Op("<").Id("maxPages"), jen.Id("page").Op("++")).Block(
+			// Relative links are resolved against the page that returned them
+			jen.List(jen.Id("nextURL"), jen.Err()).Op(":=").Id("resp").Dot("Request").Dot("URL").Dot("Parse").Call(jen.Id("next")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid next link %q: %w"), jen.Id("next"), jen.Err())),
+			),
+			jen.List(jen.Id("req"), jen.Err()).Op(":=").Qual("net/http", "NewRequestWithContext").Call(
+				jen.Id("ctx"), jen.Qual("net/http", "MethodGet"), jen.Id("nextURL").Dot("String").Call(), jen.Nil(),
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.For(jen.List(jen.Id("_"), jen.Id("editor")).Op(":=").Range().Append(jen.Id("client").Dot("RequestEditors"), jen.Id("reqEditors").Op("..."))).Block(
+				jen.If(jen.Err().Op(":=").Id("editor").Call(jen.Id("ctx"), jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Err()),
+				),
+			),
+
+			jen.List(jen.Id("resp"), jen.Err()).Op("=").Id("client").Dot("Client").Dot("Do").Call(jen.Id("req")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error fetching page %s: %w"), jen.Id("nextURL"), jen.Err())),
+			),
+			jen.List(jen.Id("pageBody"), jen.Err()).Op(":=").Qual("io", "ReadAll").Call(jen.Id("resp").Dot("Body")),
+			jen.Id("resp").Dot("Body").Dot("Close").Call(),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error reading page %s: %w"), jen.Id("nextURL"), jen.Err())),
+			),
+			jen.If(jen.Id("resp").Dot("StatusCode").Op("<").Lit(200).Op("||").Id("resp").Dot("StatusCode").Op(">").Lit(299)).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error fetching page %s: %s"), jen.Id("nextURL"), jen.Id("resp").Dot("Status"))),
+			),
+
+			jen.Var().Id("pageItems").Index().Qual("encoding/json", "RawMessage"),
+			jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("pageBody"), jen.Op("&").Id("pageItems")), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error parsing page %s: %w"), jen.Id("nextURL"), jen.Err())),
+			),
+			jen.Id("items").Op("=").Append(jen.Id("items"), jen.Id("pageItems").Op("...")),
+			jen.Id("next").Op("=").Id("nextLink").Call(jen.Id("resp").Dot("Header").Dot("Values").Call(jen.Lit("Link"))),
+		),
+
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id("items"))),
+	)
+}
+
+// addNextLink adds the function extracting the next link from Link headers
+func addNextLink(f *jen.File) {
+	f.Comment("nextLink returns the target of the rel=\"next\" link in RFC 5988 Link headers")
+	f.Func().Id("nextLink").Params(
+		jen.Id("headers").Index().String(),
+	).String().Block(
+		jen.For(jen.List(jen.Id("_"), jen.Id("header")).Op(":=").Range().Id("headers")).Block(
+			jen.For(jen.Id("header").Op("!=").Lit("")).Block(
+				// Each link is <target> followed by its ;-separated params
+				jen.Id("start").Op(":=").Qual("strings", "Index").Call(jen.Id("header"), jen.Lit("<")),
+				jen.Id("end").Op(":=").Qual("strings", "Index").Call(jen.Id("header"), jen.Lit(">")),
+				jen.If(jen.Id("start").Op("<").Lit(0).Op("||").Id("end").Op("<").Id("start")).Block(
+					jen.Break(),
+				),
+				jen.Id("target").Op(":=").Id("header").Index(jen.Id("start").Op("+").Lit(1).Op(":").Id("end")),
+				jen.Id("header").Op("=").Id("header").Index(jen.Id("end").Op("+").Lit(1).Op(":")),
+
+				jen.Id("params").Op(":=").Id("header"),
+				jen.If(jen.Id("next").Op(":=").Qual("strings", "Index").Call(jen.Id("header"), jen.Lit("<")), jen.Id("next").Op(">=").Lit(0)).Block(
+					jen.Id("params").Op("=").Id("header").Index(jen.Op(":").Id("next")),
+				),
+				jen.For(jen.List(jen.Id("_"), jen.Id("param")).Op(":=").Range().Qual("strings", "Split").Call(jen.Id("params"), jen.Lit(";"))).Block(
+					jen.List(jen.Id("name"), jen.Id("value"), jen.Id("ok")).Op(":=").Qual("strings", "Cut").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("param")), jen.Lit("=")),
+					jen.If(jen.Op("!").Id("ok").Op("||").Op("!").Qual("strings", "EqualFold").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("name")), jen.Lit("rel"))).Block(
+						jen.Continue(),
+					),
+					jen.Id("value").Op("=").Qual("strings", "Trim").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("value")), jen.Lit(`",`)),
+					jen.For(jen.List(jen.Id("_"), jen.Id("rel")).Op(":=").Range().Qual("strings", "Fields").Call(jen.Id("value"))).Block(
+						jen.If(jen.Qual("strings", "EqualFold").Call(jen.Id("rel"), jen.Lit("next"))).Block(
+							jen.Return(jen.Id("target")),
+						),
+					),
+				),
+			),
+		),
+		jen.Return(jen.Lit("")),
+	)
+}
+
+// addFetchTokenWindow adds the function adapting offset and limit to the page
+// token pagination of the API
+func addFetchTokenWindow(f *jen.File) {
+	f.Comment("fetchTokenWindow returns the items between offset and offset+limit, following the")
+	f.Comment("next page tokens of the JSON responses. Skipped pages are discarded and bodies without")
+	f.Comment("items are returned unchanged. The request editors of the client and the given ones")
+	f.Comment("are applied to each page request. The next page token of the last page fetched is")
+	f.Comment("returned next to the items, the items of that page beyond the window are not returned")
+	f.Func().Id("fetchTokenWindow").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("client").Op("*").Qual(CLI.ClientImport, "Client"),
+		jen.Id("resp").Op("*").Qual("net/http", "Response"),
+		jen.Id("body").Index().Byte(),
+		jen.List(jen.Id("offset"), jen.Id("limit"), jen.Id("maxPages")).Int(),
+		jen.Id("reqEditors").Op("...").Qual(CLI.ClientImport, "RequestEditorFn"),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Id("window").Op(":=").Index().Qual("encoding/json", "RawMessage").Values(),
+		jen.Id("skip").Op(":=").Id("offset"),
+		jen.Id("token").Op(":=").Lit(""),
+		jen.For(jen.Id("page").Op(":=").Lit(1), jen.Empty(), jen.Id("page").Op("++")).Block(
+			jen.Var().Id("envelope").Map(jen.String()).Qual("encoding/json", "RawMessage"),
+			jen.Var().Id("items").Index().Qual("encoding/json", "RawMessage"),
+			jen.If(
+				jen.Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("envelope")).Op("!=").Nil().Op("||").
+					Qual("encoding/json", "Unmarshal").Call(jen.Id("envelope").Index(jen.Lit(CLI.PageItemsField)), jen.Op("&").Id("items")).Op("!=").Nil(),
+			).Block(
+				jen.If(jen.Id("page").Op("==").Lit(1)).Block(
+					jen.Return(jen.Id("body"), jen.Nil()),
+				),
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("page %d has no "+CLI.PageItemsField), jen.Id("page"))),
+			),
+
+			jen.Id("token").Op("=").Lit(""),
+			jen.Id("_").Op("=").Qual("encoding/json", "Unmarshal").Call(jen.Id("envelope").Index(jen.Lit(CLI.NextPageTokenField)), jen.Op("&").Id("token")),
+
+			jen.If(jen.Id("skip").Op(">=").Len(jen.Id("items"))).Block(
+				jen.Id("skip").Op("-=").Len(jen.Id("items")),
+			).Else().Block(
+				jen.Id("window").Op("=").Append(jen.Id("window"), jen.Id("items").Index(jen.Id("skip").Op(":")).Op("...")),
+				jen.Id("skip").Op("=").Lit(0),
+			),
+			jen.If(jen.Id("limit").Op(">").Lit(0).Op("&&").Len(jen.Id("window")).Op(">=").Id("limit")).Block(
+				jen.Id("window").Op("=").Id("window").Index(jen.Op(":").Id("limit")),
+				jen.Break(),
+			),
+
+			jen.If(jen.Id("token").Op("==").Lit("").Op("||").Id("page").Op(">=").Id("maxPages")).Block(
+				jen.Break(),
+			),
+
+			// The next page repeats the request with the token
+			jen.Id("nextURL").Op(":=").Op("*").Id("resp").Dot("Request").Dot("URL"),
+			jen.Id("query").Op(":=").Id("nextURL").Dot("Query").Call(),
+			jen.Id("query").Dot("Set").Call(jen.Lit(CLI.PageTokenParam), jen.Id("token")),
+			jen.Id("nextURL").Dot("RawQuery").Op("=").Id("query").Dot("Encode").Call(),
+			jen.List(jen.Id("req"), jen.Err()).Op(":=").Qual("net/http", "NewRequestWithContext").Call(
+				jen.Id("ctx"), jen.Qual("net/http", "MethodGet"), jen.Id("nextURL").Dot("String").Call(), jen.Nil(),
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.For(jen.List(jen.Id("_"), jen.Id("editor")).Op(":=").Range().Append(jen.Id("client").Dot("RequestEditors"), jen.Id("reqEditors").Op("..."))).Block(
+				jen.If(jen.Err().Op(":=").Id("editor").Call(jen.Id("ctx"), jen.Id("req")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Err()),
+				),
+			),
+
+			jen.List(jen.Id("resp"), jen.Err()).Op("=").Id("client").Dot("Client").Dot("Do").Call(jen.Id("req")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error fetching page %s: %w"), jen.Id("nextURL").Dot("String").Call(), jen.Err())),
+			),
+			jen.List(jen.Id("body"), jen.Err()).Op("=").Qual("io", "ReadAll").Call(jen.Id("resp").Dot("Body")),
+			jen.Id("resp").Dot("Body").Dot("Close").Call(),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error reading page %s: %w"), jen.Id("nextURL").Dot("String").Call(), jen.Err())),
+			),
+			jen.If(jen.Id("resp").Dot("StatusCode").Op("<").Lit(200).Op("||").Id("resp").Dot("StatusCode").Op(">").Lit(299)).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error fetching page %s: %s"), jen.Id("nextURL").Dot("String").Call(), jen.Id("resp").Dot("Status"))),
+			),
+		),
+
+		jen.Id("result").Op(":=").Map(jen.String()).Any().Values(jen.Dict{
+			jen.Lit(CLI.PageItemsField): jen.Id("window"),
+		}),
+		jen.If(jen.Id("token").Op("!=").Lit("")).Block(
+			jen.Id("result").Index(jen.Lit(CLI.NextPageTokenField)).Op("=").Id("token"),
+		),
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id("result"))),
+	)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestLinkPagination(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			t.Errorf("page %q requested without the credentials", r.URL.Query().Get("page"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Add("Link", `</books?page=2>; rel="next", </books?page=3>; rel="last"`)
+			w.Write([]byte(`["Dune","Emma"]`))
+		case "2":
+			w.Header().Add("Link", `</books?page=1>; rel="prev", </books?page=3>; rel="next"`)
+			w.Write([]byte(`["Ulysses"]`))
+		case "3":
+			w.Write([]byte(`["Walden"]`))
+		}
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/pages.yaml", "--auth-type", "basic", "--auto-paginate")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "ListBooks", map[string]any{})
+	if got, want := result.text(), `["Dune","Emma","Ulysses","Walden"]`; result.IsError || got != want {
+		t.Errorf("the aggregated pages are %q, want %q", got, want)
+	}
+
+	// The pages beyond the maximum are not fetched
+	session = startServer(t, binary, nil, "--host", upstream.URL, "--max-pages", "2")
+	result = session.callTool(t, "ListBooks", map[string]any{})
+	if got, want := result.text(), `["Dune","Emma","Ulysses"]`; result.IsError || got != want {
+		t.Errorf("the aggregated pages are %q, want %q", got, want)
+	}
+}
+
+func TestTokenPagination(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"items":["Dune","Emma"],"nextPageToken":"p2"}`,
+		"p2": `{"items":["Ulysses","Walden"],"nextPageToken":"p3"}`,
+		"p3": `{"items":["Zorba"]}`,
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(http.StatusOK, "application/json", pages[r.URL.Query().Get("pageToken")])(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/tokenpages.yaml", "--auth-type", "none", "--page-token-param", "pageToken")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The window ends with the token following the last page fetched
+	tests := []struct {
+		offset, limit int
+		want          string
+	}{
+		{1, 2, `{"items":["Emma","Ulysses"],"nextPageToken":"p3"}`},
+		{0, 2, `{"items":["Dune","Emma"],"nextPageToken":"p2"}`},
+		{3, 5, `{"items":["Walden","Zorba"]}`},
+	}
+	for _, test := range tests {
+		result := session.callTool(t, "ListBooks", map[string]any{"offset": test.offset, "limit": test.limit})
+		if result.IsError || result.text() != test.want {
+			t.Errorf("the window at %d of %d items is %q, want %q", test.offset, test.limit, result.text(), test.want)
+		}
+	}
+}
+
+func TestCursorParam(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"items":["Dune","Emma"],"nextCursor":"c2"}`,
+		"c2": `{"items":["Ulysses"]}`,
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(http.StatusOK, "application/json", pages[r.URL.Query().Get("after")])(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/cursor.yaml", "--auth-type", "none", "--cursor-param", "after")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The cursor of the result is the argument fetching the next page
+	cursor := ""
+	var items []string
+	for _, want := range []string{`{"pagination":{"hasMore":true,"nextCursor":"c2"}}`, `{"pagination":{"hasMore":false,"nextCursor":""}}`} {
+		result := session.callTool(t, "ListBooks", map[string]any{"cursor": cursor})
+		if result.IsError || len(result.Content) != 2 {
+			t.Fatalf("ListBooks with cursor %q returned %+v", cursor, result)
+		}
+		var page struct {
+			Items []string `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &page); err != nil {
+			t.Fatalf("decoding the page %s: %v", result.Content[0].Text, err)
+		}
+		items = append(items, page.Items...)
+		if got := result.Content[1].Text; got != want {
+			t.Fatalf("the pagination of the page at %q is %s, want %s", cursor, got, want)
+		}
+
+		var meta struct {
+			Pagination struct {
+				NextCursor string `json:"nextCursor"`
+			} `json:"pagination"`
+		}
+		json.Unmarshal([]byte(result.Content[1].Text), &meta)
+		cursor = meta.Pagination.NextCursor
+	}
+	if want := []string{"Dune", "Emma", "Ulysses"}; !slices.Equal(items, want) {
+		t.Errorf("the pages hold %v, want %v", items, want)
+	}
+}
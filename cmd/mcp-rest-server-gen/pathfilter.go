@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pathFilter selects the spec paths whose operations become tools
+type pathFilter struct {
+	patterns []string
+	matchers []*regexp.Regexp
+}
+
+// newPathFilter compiles the path patterns, a pattern with wildcards is a
+// glob where * matches within a path segment and ** across segments, one
+// without wildcards selects the path and the paths below it. Matching is case
+// sensitive as are OpenAPI paths.
+func newPathFilter(patterns []string) (*pathFilter, error) {
+	filter := &pathFilter{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid path filter %q: paths start with /", pattern)
+		}
+
+		expr := regexp.QuoteMeta(pattern)
+		if strings.ContainsAny(pattern, "*?") {
+			expr = strings.ReplaceAll(expr, `\*\*`, `.*`)
+			expr = strings.ReplaceAll(expr, `\*`, `[^/]*`)
+			expr = strings.ReplaceAll(expr, `\?`, `[^/]`)
+		} else {
+			expr = strings.TrimSuffix(expr, "/") + `(/.*)?`
+		}
+
+		matcher, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid path filter %q: %w", pattern, err)
+		}
+		filter.patterns = append(filter.patterns, pattern)
+		filter.matchers = append(filter.matchers, matcher)
+	}
+	return filter, nil
+}
+
+// match reports whether the path is selected, all paths are without patterns
+func (p *pathFilter) match(path string) bool {
+	if len(p.matchers) == 0 {
+		return true
+	}
+	for _, matcher := range p.matchers {
+		if matcher.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnUnmatched reports the patterns matching none of the paths
+func (p *pathFilter) warnUnmatched(paths []string) {
+	for i, matcher := range p.matchers {
+		matched := false
+		for _, path := range paths {
+			if matcher.MatchString(path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			warnf("path filter %s matches no path of the spec", p.patterns[i])
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		want     bool
+	}{
+		// Without patterns all the paths are selected
+		{nil, "/books", true},
+		{[]string{" "}, "/books", true},
+
+		// A plain pattern selects the path and the paths below it only
+		{[]string{"/admin"}, "/admin", true},
+		{[]string{"/admin"}, "/admin/users", true},
+		{[]string{"/admin/"}, "/admin/users", true},
+		{[]string{"/admin"}, "/administrator", false},
+		{[]string{"/admin"}, "/books/admin", false},
+
+		// * matches within a segment and ** across segments
+		{[]string{"/admin/*"}, "/admin/users", true},
+		{[]string{"/admin/*"}, "/admin/{id}", true},
+		{[]string{"/admin/*"}, "/admin", false},
+		{[]string{"/admin/*"}, "/admin/users/{id}", false},
+		{[]string{"/admin/**"}, "/admin/users/{id}", true},
+		{[]string{"/*/users"}, "/admin/users", true},
+		{[]string{"/*/users"}, "/v1/admin/users", false},
+		{[]string{"/**/users"}, "/v1/admin/users", true},
+		{[]string{"/v?/books"}, "/v2/books", true},
+		{[]string{"/v?/books"}, "/v10/books", false},
+
+		// Matching is case sensitive
+		{[]string{"/admin"}, "/Admin", false},
+		{[]string{"/Admin/*"}, "/admin/users", false},
+
+		// The other characters are taken literally
+		{[]string{"/books.json"}, "/books-json", false},
+		{[]string{"/books/{id}"}, "/books/{id}", true},
+
+		// A path matching one of the patterns is selected
+		{[]string{"/admin/*", "/books"}, "/books/{id}", true},
+		{[]string{"/admin/*", " /books "}, "/books", true},
+		{[]string{"/admin/*", "/books"}, "/authors", false},
+	}
+	for _, test := range tests {
+		filter, err := newPathFilter(test.patterns)
+		if err != nil {
+			t.Fatalf("compiling %q: %v", test.patterns, err)
+		}
+		if got := filter.match(test.path); got != test.want {
+			t.Errorf("%q matching %s is %v, want %v", test.patterns, test.path, got, test.want)
+		}
+	}
+
+	if _, err := newPathFilter([]string{"admin/*"}); err == nil || !strings.Contains(err.Error(), `invalid path filter "admin/*": paths start with /`) {
+		t.Errorf("a pattern not starting with / failed with %v", err)
+	}
+}
+
+func TestPathFilterFlag(t *testing.T) {
+	// The comma-separated patterns select the operations of their paths
+	generate(t, "testdata/tickets.yaml", "--auth-type", "none", "--path-filter", "/tickets/*,/Tickets,/users/**")
+	if want := []string{"DeleteTicket", "GetTicket"}; !slices.Equal(report.Operations, want) {
+		t.Errorf("the generated operations are %v, want %v", report.Operations, want)
+	}
+
+	// The patterns matching no path are reported
+	assertWarning(t, "path filter /Tickets matches no path of the spec")
+	assertWarning(t, "path filter /users/** matches no path of the spec")
+	for _, warning := range report.Warnings {
+		if strings.Contains(warning, "/tickets/*") {
+			t.Errorf("the matching pattern was reported: %s", warning)
+		}
+	}
+
+	generate(t, "testdata/tickets.yaml", "--auth-type", "none", "--path-filter", "/tickets")
+	if want := []string{"CloseTicket", "CreateTicket", "DeleteTicket", "GetTicket", "ListTickets"}; !slices.Equal(report.Operations, want) {
+		t.Errorf("the operations below /tickets are %v, want %v", report.Operations, want)
+	}
+}
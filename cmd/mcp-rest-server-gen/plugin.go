@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// HandlerPlugin injects statements into the generated tool handlers at their
+// extension points. Plugins are compiled into the generator, usually from a
+// file guarded by a build tag, register themselves from an init function
+// with RegisterHandlerPlugin and are applied when named in --handler-plugins.
+//
+// Every function is optional. The statements returned see the handler
+// variables: arguments, the tool arguments, and at the points after the call
+// resp, the client response, and err, the call error.
+type HandlerPlugin struct {
+	// Name selects the plugin in --handler-plugins
+	Name string
+	// Declare adds the declarations used by the injected statements to the
+	// generated file, it is called once
+	Declare func(f *jen.File)
+	// PreCall returns the statements run before the REST call
+	PreCall func(scope HandlerScope) []jen.Code
+	// PostCall returns the statements run after a successful call, before the
+	// response body is turned into the tool result
+	PostCall func(scope HandlerScope) []jen.Code
+	// OnError returns the statements run before a failed call returns its
+	// error, err is nil when the API answered with an error status
+	OnError func(scope HandlerScope) []jen.Code
+}
+
+// HandlerScope describes the handler the plugin statements are injected into
+type HandlerScope struct {
+	// Op is the operation called by the handler
+	Op OperationInfo
+	// Ctx is the expression of the context of the REST call
+	Ctx jen.Code
+}
+
+// handlerPlugins are the plugins compiled into the generator, by name
+var handlerPlugins = map[string]HandlerPlugin{}
+
+// RegisterHandlerPlugin makes the plugin available to --handler-plugins, it
+// panics when another plugin has the same name
+func RegisterHandlerPlugin(plugin HandlerPlugin) {
+	if plugin.Name == "" {
+		panic("handler plugin without a name")
+	}
+	if _, ok := handlerPlugins[plugin.Name]; ok {
+		panic("handler plugin " + plugin.Name + " registered twice")
+	}
+	handlerPlugins[plugin.Name] = plugin
+}
+
+// handlerPluginSet holds the plugins applied to the handlers, in order
+type handlerPluginSet []HandlerPlugin
+
+// selectHandlerPlugins returns the registered plugins with the given names
+func selectHandlerPlugins(names []string) (handlerPluginSet, error) {
+	var plugins handlerPluginSet
+	for _, name := range names {
+		plugin, ok := handlerPlugins[name]
+		if !ok && len(handlerPlugins) == 0 {
+			return nil, fmt.Errorf("unknown handler plugin %q, no plugins are compiled in", name)
+		}
+		if !ok {
+			known := make([]string, 0, len(handlerPlugins))
+			for name := range handlerPlugins {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown handler plugin %q, compiled-in plugins: %s", name, strings.Join(known, ", "))
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+// declare adds the declarations of the plugins to the file
+func (s handlerPluginSet) declare(f *jen.File) {
+	for _, plugin := range s {
+		if plugin.Declare != nil {
+			plugin.Declare(f)
+		}
+	}
+}
+
+// statements returns the statements of the plugins at an extension point
+func (s handlerPluginSet) statements(scope HandlerScope, point func(plugin HandlerPlugin) func(scope HandlerScope) []jen.Code) []jen.Code {
+	var code []jen.Code
+	for _, plugin := range s {
+		if inject := point(plugin); inject != nil {
+			code = append(code, inject(scope)...)
+		}
+	}
+	return code
+}
+
+// preCall returns the statements of the plugins run before the REST call
+func (s handlerPluginSet) preCall(scope HandlerScope) []jen.Code {
+	return s.statements(scope, func(plugin HandlerPlugin) func(scope HandlerScope) []jen.Code { return plugin.PreCall })
+}
+
+// postCall returns the statements of the plugins run after a successful call
+func (s handlerPluginSet) postCall(scope HandlerScope) []jen.Code {
+	return s.statements(scope, func(plugin HandlerPlugin) func(scope HandlerScope) []jen.Code { return plugin.PostCall })
+}
+
+// onError returns the statements of the plugins run when the call fails
+func (s handlerPluginSet) onError(scope HandlerScope) []jen.Code {
+	return s.statements(scope, func(plugin HandlerPlugin) func(scope HandlerScope) []jen.Code { return plugin.OnError })
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+)
+
+func init() {
+	// The audit plugin logs each call of the handlers it is injected into
+	audit := func(event string) func(scope HandlerScope) []jen.Code {
+		return func(scope HandlerScope) []jen.Code {
+			return []jen.Code{jen.Id("auditCall").Call(jen.Lit(scope.Op.ID), jen.Lit(event))}
+		}
+	}
+	RegisterHandlerPlugin(HandlerPlugin{
+		Name: "audit",
+		Declare: func(f *jen.File) {
+			f.Func().Id("auditCall").Params(jen.List(jen.Id("operation"), jen.Id("event")).String()).Block(
+				jen.Qual("log/slog", "Info").Call(jen.Lit("audit"), jen.Lit("operation"), jen.Id("operation"), jen.Lit("event"), jen.Id("event")),
+			)
+		},
+		PreCall:  audit("call"),
+		PostCall: audit("success"),
+		OnError:  audit("error"),
+	})
+}
+
+func TestHandlerPlugins(t *testing.T) {
+	_, err := runGenerator(t, booksSpec, "--handler-plugins", "metrics")
+	if err == nil || !strings.Contains(err.Error(), `unknown handler plugin "metrics", compiled-in plugins: audit`) {
+		t.Errorf("generating with an unknown plugin failed with %v", err)
+	}
+
+	code := generate(t, booksSpec)
+	assertNotContains(t, code, "auditCall")
+
+	handler := respond(http.StatusOK, "application/json", `[]`)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--handler-plugins", "audit")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	session.waitForLog(t, "msg=audit operation=ListBooks event=call")
+	session.waitForLog(t, "msg=audit operation=ListBooks event=success")
+
+	// Error statuses run the statements of failed calls
+	handler = respond(http.StatusInternalServerError, "text/plain", "down")
+	if result := session.callTool(t, "ListBooks", map[string]any{}); !result.IsError {
+		t.Fatalf("ListBooks succeeded on an error status: %s", result.text())
+	}
+	session.waitForLog(t, "msg=audit operation=ListBooks event=error")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"sigs.k8s.io/yaml"
+)
+
+// readProfiles reads the config profiles of the profiles file, YAML or JSON
+// mapping each profile name to the values of the server flags it sets, and
+// checks the flags are flags of the generated server
+func readProfiles(path string, fields []ConfigField) (map[string]map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading profiles file: %w", err)
+	}
+
+	var file map[string]map[string]any
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("error parsing profiles file: %w", err)
+	}
+	if len(file) == 0 {
+		return nil, fmt.Errorf("no profiles in profiles file %s", path)
+	}
+
+	flags := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		flags[flagName(field.Name)] = true
+	}
+
+	profiles := make(map[string]map[string]string, len(file))
+	for name, values := range file {
+		if name == "" || strings.Contains(name, ",") {
+			return nil, fmt.Errorf("invalid profile name %q", name)
+		}
+		profile := make(map[string]string, len(values))
+		for flag, value := range values {
+			if !flags[flag] {
+				return nil, fmt.Errorf("profile %s sets %s, which is not a flag of the generated server", name, flag)
+			}
+			profile[flag] = fmt.Sprint(value)
+		}
+		profiles[name] = profile
+	}
+
+	return profiles, nil
+}
+
+// profileNames returns the sorted names of the profiles
+func profileNames(profiles map[string]map[string]string) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileField returns the configuration field selecting the profile, none
+// is selected by default
+func profileField(profiles map[string]map[string]string) ConfigField {
+	names := profileNames(profiles)
+	return ConfigField{
+		Name: "Profile", Type: jen.String(),
+		Tags: map[string]string{
+			"help":    "Config profile setting the defaults of the flags, one of " + strings.Join(names, ", "),
+			"enum":    "," + strings.Join(names, ","),
+			"default": "",
+		},
+	}
+}
+
+// addProfiles adds the embedded profiles and the kong resolver giving their
+// values to the flags
+func addProfiles(f *jen.File, profiles map[string]map[string]string) {
+	values := jen.Dict{}
+	for name, profile := range profiles {
+		flags := jen.Dict{}
+		for flag, value := range profile {
+			flags[jen.Lit(flag)] = jen.Lit(value)
+		}
+		values[jen.Lit(name)] = jen.Values(flags)
+	}
+	f.Comment("profiles are the config profiles embedded at generation time, the values of the flags")
+	f.Comment("they set by profile name")
+	f.Var().Id("profiles").Op("=").Map(jen.String()).Map(jen.String()).String().Values(values)
+
+	f.Comment("profileResolver gives the values of the selected profile to the flags set neither on the")
+	f.Comment("command line nor in the environment")
+	f.Func().Id("profileResolver").Params().Qual("github.com/alecthomas/kong", "ResolverFunc").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("context").Op("*").Qual("github.com/alecthomas/kong", "Context"),
+			jen.Id("parent").Op("*").Qual("github.com/alecthomas/kong", "Path"),
+			jen.Id("flag").Op("*").Qual("github.com/alecthomas/kong", "Flag"),
+		).Params(jen.Any(), jen.Error()).Block(
+			jen.Var().Id("profile").String(),
+			jen.For(jen.List(jen.Id("_"), jen.Id("f")).Op(":=").Range().Id("context").Dot("Flags").Call()).Block(
+				jen.If(jen.Id("f").Dot("Name").Op("==").Lit("profile")).Block(
+					jen.List(jen.Id("profile"), jen.Id("_")).Op("=").Id("context").Dot("FlagValue").Call(jen.Id("f")).Assert(jen.String()),
+				),
+			),
+			jen.List(jen.Id("value"), jen.Id("ok")).Op(":=").Id("profiles").Index(jen.Id("profile")).Index(jen.Id("flag").Dot("Name")),
+			jen.If(jen.Op("!").Id("ok")).Block(
+				jen.Return(jen.Nil(), jen.Nil()),
+			),
+			jen.For(jen.List(jen.Id("_"), jen.Id("env")).Op(":=").Range().Id("flag").Dot("Envs")).Block(
+				jen.If(jen.List(jen.Id("_"), jen.Id("set")).Op(":=").Qual("os", "LookupEnv").Call(jen.Id("env")), jen.Id("set")).Block(
+					jen.Return(jen.Nil(), jen.Nil()),
+				),
+			),
+			jen.Return(jen.Id("value"), jen.Nil()),
+		)),
+	)
+}
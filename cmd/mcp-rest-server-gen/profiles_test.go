@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	hosts := make(chan string, 1)
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			hosts <- name
+			respond(http.StatusOK, "application/json", `[]`)(w, r)
+		}
+	}
+	staging := httptest.NewServer(handler("staging"))
+	defer staging.Close()
+	production := httptest.NewServer(handler("production"))
+	defer production.Close()
+
+	profilesFile := filepath.Join(t.TempDir(), "profiles.yaml")
+	profiles := "staging:\n  host: " + staging.URL + "\nproduction:\n  host: " + production.URL + "\n"
+	if err := os.WriteFile(profilesFile, []byte(profiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The profiles are embedded, the file is not read by the server
+	binary := buildServer(t, booksSpec, "--profiles-file", profilesFile)
+	os.Remove(profilesFile)
+	for _, name := range []string{"staging", "production"} {
+		session := startServer(t, binary, nil, "--profile", name)
+		if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+			t.Fatalf("ListBooks with the %s profile failed: %s", name, result.text())
+		}
+		if got := <-hosts; got != name {
+			t.Errorf("the %s profile called the %s host", name, got)
+		}
+	}
+
+	// The flags given override the profile
+	session := startServer(t, binary, nil, "--profile", "production", "--host", staging.URL)
+	session.callTool(t, "ListBooks", map[string]any{})
+	if got := <-hosts; got != "staging" {
+		t.Errorf("the host given with the production profile is the %s one", got)
+	}
+
+	if err := os.WriteFile(profilesFile, []byte("staging:\n  color: blue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGenerator(t, booksSpec, "--profiles-file", profilesFile); err == nil || !strings.Contains(err.Error(), "profile staging sets color, which is not a flag of the generated server") {
+		t.Errorf("generating with an unknown profile flag failed with %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// schemaPropertyPaths returns the sorted dotted paths of the properties of the
+// schema matching the predicate, at any depth. Array items are walked through
+// so a path applies to every item.
+func schemaPropertyPaths(schema *openapi3.Schema, match func(*openapi3.Schema) bool) []string {
+	var paths []string
+	seen := make(map[*openapi3.Schema]bool)
+
+	var walk func(schema *openapi3.Schema, prefix string)
+	walk = func(schema *openapi3.Schema, prefix string) {
+		if schema == nil || seen[schema] {
+			return
+		}
+		seen[schema] = true
+		defer delete(seen, schema)
+
+		if schema.Items != nil {
+			walk(schema.Items.Value, prefix)
+		}
+		for _, composed := range [][]*openapi3.SchemaRef{schema.AllOf, schema.OneOf, schema.AnyOf} {
+			for _, ref := range composed {
+				if ref != nil {
+					walk(ref.Value, prefix)
+				}
+			}
+		}
+		for name, property := range schema.Properties {
+			if property == nil || property.Value == nil {
+				continue
+			}
+			if match(property.Value) {
+				paths = append(paths, prefix+name)
+				continue
+			}
+			walk(property.Value, prefix+name+".")
+		}
+	}
+	walk(schema, "")
+
+	sort.Strings(paths)
+	return slices.Compact(paths)
+}
+
+// readOnlyProperties returns the paths of the readOnly properties of the JSON
+// request body, which are managed by the server and not sent
+func readOnlyProperties(operation *openapi3.Operation) []string {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return nil
+	}
+	mediaType := operation.RequestBody.Value.Content.Get("application/json")
+	if mediaType == nil || mediaType.Schema == nil {
+		return nil
+	}
+	return schemaPropertyPaths(mediaType.Schema.Value, func(schema *openapi3.Schema) bool { return schema.ReadOnly })
+}
+
+// writeOnlyProperties returns the paths of the writeOnly properties of the
+// JSON bodies of the success responses
+func writeOnlyProperties(operation *openapi3.Operation) []string {
+	if operation.Responses == nil {
+		return nil
+	}
+
+	var paths []string
+	for code, response := range operation.Responses.Map() {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 200 || status > 299 || response == nil || response.Value == nil {
+			continue
+		}
+		mediaType := response.Value.Content.Get("application/json")
+		if mediaType == nil || mediaType.Schema == nil {
+			continue
+		}
+		paths = append(paths, schemaPropertyPaths(mediaType.Schema.Value, func(schema *openapi3.Schema) bool { return schema.WriteOnly })...)
+	}
+
+	sort.Strings(paths)
+	return slices.Compact(paths)
+}
+
+// propertyPathsCode returns the slice literal of the property paths
+func propertyPathsCode(paths []string) jen.Code {
+	values := make([]jen.Code, 0, len(paths))
+	for _, path := range paths {
+		values = append(values, jen.Lit(path))
+	}
+	return jen.Index().String().Values(values...)
+}
+
+// addRemoveProperties adds the function removing properties from a decoded
+// JSON value by dotted path
+func addRemoveProperties(f *jen.File) {
+	f.Comment("removeProperties removes the property at the dotted path from the decoded JSON value, from")
+	f.Comment("every item of the arrays along the path")
+	f.Func().Id("removeProperties").Params(
+		jen.Id("value").Any(),
+		jen.Id("path").Index().String(),
+	).Block(
+		jen.Switch(jen.Id("value").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Index().Any()).Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("item")).Op(":=").Range().Id("value")).Block(
+					jen.Id("removeProperties").Call(jen.Id("item"), jen.Id("path")),
+				),
+			),
+			jen.Case(jen.Map(jen.String()).Any()).Block(
+				jen.If(jen.Len(jen.Id("path")).Op("==").Lit(1)).Block(
+					jen.Delete(jen.Id("value"), jen.Id("path").Index(jen.Lit(0))),
+					jen.Return(),
+				),
+				jen.Id("removeProperties").Call(jen.Id("value").Index(jen.Id("path").Index(jen.Lit(0))), jen.Id("path").Index(jen.Lit(1), jen.Empty())),
+			),
+		),
+	)
+}
+
+// addOmitProperties adds the request editor removing the readOnly properties
+// from the JSON request bodies
+func addOmitProperties(f *jen.File) {
+	f.Comment("omitProperties returns a request editor removing the properties at the dotted paths from")
+	f.Comment("the JSON request body, the readOnly properties the server manages")
+	f.Func().Id("omitProperties").Params(
+		jen.Id("paths").Index().String(),
+	).Qual(CLI.ClientImport, "RequestEditorFn").Block(
+		jen.Return(jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("req").Op("*").Qual("net/http", "Request"),
+		).Error().Block(
+			jen.If(jen.Id("req").Dot("Body").Op("==").Nil().Op("||").Op("!").Qual("strings", "HasPrefix").Call(jen.Id("req").Dot("Header").Dot("Get").Call(jen.Lit("Content-Type")), jen.Lit("application/json"))).Block(
+				jen.Return(jen.Nil()),
+			),
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("io", "ReadAll").Call(jen.Id("req").Dot("Body")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			),
+			jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("data"))),
+			jen.Id("decoder").Dot("UseNumber").Call(),
+			jen.Var().Id("body").Any(),
+			jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("body")), jen.Err().Op("==").Nil()).Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("path")).Op(":=").Range().Id("paths")).Block(
+					jen.Id("removeProperties").Call(jen.Id("body"), jen.Qual("strings", "Split").Call(jen.Id("path"), jen.Lit("."))),
+				),
+				jen.If(jen.List(jen.Id("encoded"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("body")), jen.Err().Op("==").Nil()).Block(
+					jen.Id("data").Op("=").Id("encoded"),
+				),
+			),
+			jen.Id("req").Dot("Body").Op("=").Qual("io", "NopCloser").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("data"))),
+			jen.Id("req").Dot("ContentLength").Op("=").Int64().Call(jen.Len(jen.Id("data"))),
+			jen.Id("req").Dot("GetBody").Op("=").Func().Params().Params(jen.Qual("io", "ReadCloser"), jen.Error()).Block(
+				jen.Return(jen.Qual("io", "NopCloser").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("data"))), jen.Nil()),
+			),
+			jen.Return(jen.Nil()),
+		)),
+	)
+}
+
+// addHideProperties adds the function removing the writeOnly properties from
+// the JSON response bodies
+func addHideProperties(f *jen.File) {
+	f.Comment("hideProperties removes the properties at the dotted paths from the JSON body, the writeOnly")
+	f.Comment("properties the API should not return, other bodies are left untouched")
+	f.Func().Id("hideProperties").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("paths").Index().String(),
+	).Index().Byte().Block(
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("body"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.Var().Id("value").Any(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("value")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.For(jen.List(jen.Id("_"), jen.Id("path")).Op(":=").Range().Id("paths")).Block(
+			jen.Id("removeProperties").Call(jen.Id("value"), jen.Qual("strings", "Split").Call(jen.Id("path"), jen.Lit("."))),
+		),
+		jen.List(jen.Id("hidden"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("value")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("body")),
+		),
+		jen.Return(jen.Id("hidden")),
+	)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnlyProperties(t *testing.T) {
+	bodies := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			bodies <- string(body)
+			respond(http.StatusOK, "application/json", `{"id":1,"name":"Ada"}`)(w, r)
+			return
+		}
+		respond(http.StatusOK, "application/json", `[{"id":1,"name":"Ada","password":"s3cret","profile":{"bio":"Poet"}}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/readonly.yaml", "--auth-type", "none", "--hide-write-only")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The readOnly properties are managed by the API, nested ones included
+	arguments := map[string]any{"id": 7, "name": "Ada", "password": "s3cret", "profile": map[string]any{"created": "today", "bio": "Poet"}}
+	if result := session.callTool(t, "AddUser", arguments); result.IsError {
+		t.Fatalf("AddUser failed: %s", result.text())
+	}
+	if got, want := <-bodies, `{"name":"Ada","password":"s3cret","profile":{"bio":"Poet"}}`; got != want {
+		t.Errorf("AddUser sent %s, want %s", got, want)
+	}
+
+	// The writeOnly properties are not returned
+	result := session.callTool(t, "ListUsers", map[string]any{})
+	if got, want := result.text(), `[{"id":1,"name":"Ada","profile":{"bio":"Poet"}}]`; result.IsError || got != want {
+		t.Errorf("ListUsers returned %s, want %s", got, want)
+	}
+
+	binary = buildServer(t, "testdata/readonly.yaml", "--auth-type", "none")
+	result = startServer(t, binary, nil, "--host", upstream.URL).callTool(t, "ListUsers", map[string]any{})
+	if got, want := result.text(), `[{"id":1,"name":"Ada","password":"s3cret","profile":{"bio":"Poet"}}]`; result.IsError || got != want {
+		t.Errorf("ListUsers without --hide-write-only returned %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// tooldescPackage builds the tool descriptions of the reloaded spec as the
+// generator does
+const tooldescPackage = "github.com/renato0307/go-mcp-rest/tooldesc"
+
+// defaultSpecFile returns the spec path to embed as the default spec file of
+// the generated server, remote, piped and bundled specs cannot be reloaded so
+// they are ignored
+func defaultSpecFile(specPath string) string {
+	if specPath == stdinSpec || isConfigMapSpec(specPath) || isSpecBundle(specPath) {
+		return ""
+	}
+
+	parsedURL, err := url.Parse(specPath)
+	if err == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") {
+		return ""
+	}
+
+	absPath, err := filepath.Abs(specPath)
+	if err != nil {
+		return specPath
+	}
+
+	return absPath
+}
+
+// reloadOnSighup returns the statement starting the goroutine that reloads
+// the tool descriptions each time the server receives a SIGHUP, registering
+// the tools again through the registrar
+func reloadOnSighup(registrar string) jen.Code {
+	return jen.Go().Func().Params().Block(
+		jen.Id("sighup").Op(":=").Make(jen.Chan().Qual("os", "Signal"), jen.Lit(1)),
+		jen.Qual("os/signal", "Notify").Call(jen.Id("sighup"), jen.Qual("syscall", "SIGHUP")),
+		jen.For(jen.Range().Id("sighup")).Block(
+			jen.If(
+				jen.Err().Op(":=").Id("reloadToolDescriptions").Call(jen.Id(registrar), jen.Id("cli").Dot("SpecFile"), jen.Id("handlers")),
+				jen.Err().Op("!=").Nil(),
+			).Block(
+				jen.Qual("log/slog", "Error").Call(jen.Lit("Error reloading tool descriptions"), jen.Lit("error"), jen.Err()),
+			),
+		),
+	).Call()
+}
+
+// addReloadToolDescriptions adds the function that re-reads the spec file and
+// registers the known tool handlers again using the updated descriptions,
+// built as the generator builds them. The tools are found by the method and
+// path of their operation, their name being synthesized or renamed by the
+// generator when the spec has no or a duplicated operationId.
+func addReloadToolDescriptions(f *jen.File, operations map[string]OperationInfo) {
+	tools := jen.Dict{}
+	for _, id := range sortedOperationIDs(operations) {
+		op := operations[id]
+		tools[jen.Lit(op.Method+" "+op.Path)] = jen.Lit(op.ID)
+	}
+	f.Comment("toolNames are the names of the tools of the operations, by method and path")
+	f.Var().Id("toolNames").Op("=").Map(jen.String()).String().Values(tools)
+
+	suffixes := jen.Dict{}
+	for name, suffix := range CLI.DescriptionSuffixMap {
+		suffixes[jen.Lit(name)] = jen.Lit(suffix)
+	}
+	f.Comment("descriptionOptions are the options the tool descriptions were generated with")
+	f.Var().Id("descriptionOptions").Op("=").Qual(tooldescPackage, "Options").Values(jen.Dict{
+		jen.Id("Source"):        jen.Lit(CLI.DescriptionSource),
+		jen.Id("DescribeLinks"): jen.Lit(CLI.DescribeLinks),
+		jen.Id("Suffixes"):      jen.Map(jen.String()).String().Values(suffixes),
+	})
+
+	f.Comment("reloadToolDescriptions re-reads the OpenAPI spec and registers the tools again with the updated descriptions")
+	server := jen.Op("*").Qual("github.com/metoro-io/mcp-golang", "Server")
+	if CLI.DynamicTools {
+		server = jen.Op("*").Id("toolSet")
+	}
+	f.Func().Id("reloadToolDescriptions").Params(
+		jen.Id("server").Add(server),
+		jen.Id("specFile").String(),
+		jen.Id("handlers").Map(jen.String()).Any(),
+	).Error().Block(
+		jen.Id("loader").Op(":=").Qual("github.com/getkin/kin-openapi/openapi3", "NewLoader").Call(),
+		jen.Id("loader").Dot("IsExternalRefsAllowed").Op("=").True(),
+		jen.List(jen.Id("doc"), jen.Err()).Op(":=").Id("loader").Dot("LoadFromFile").Call(jen.Id("specFile")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("error loading OpenAPI spec: %w"), jen.Err())),
+		),
+		jen.For(jen.List(jen.Id("path"), jen.Id("pathItem")).Op(":=").Range().Id("doc").Dot("Paths").Dot("Map").Call()).Block(
+			jen.For(jen.List(jen.Id("method"), jen.Id("operation")).Op(":=").Range().Id("pathItem").Dot("Operations").Call()).Block(
+				jen.Id("name").Op(":=").Id("toolNames").Index(jen.Id("method").Op("+").Lit(" ").Op("+").Id("path")),
+				jen.List(jen.Id("handler"), jen.Id("ok")).Op(":=").Id("handlers").Index(jen.Id("name")),
+				jen.If(jen.Op("!").Id("ok")).Block(
+					jen.Continue(),
+				),
+
+				jen.Id("description").Op(":=").Qual(tooldescPackage, "Operation").Call(
+					jen.Id("name"), jen.Id("method"), jen.Id("path"), jen.Id("operation"), jen.Id("pathItem").Dot("Parameters"), jen.Id("descriptionOptions"),
+				),
+				jen.If(
+					jen.Err().Op(":=").Id("server").Dot("RegisterTool").Call(jen.Id("name"), jen.Id("description"), jen.Id("handler")),
+					jen.Err().Op("!=").Nil(),
+				).Block(
+					jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("error registering %s: %w"), jen.Id("name"), jen.Err())),
+				),
+			),
+		),
+		jen.Qual("log/slog", "Info").Call(jen.Lit("Tool descriptions reloaded"), jen.Lit("spec"), jen.Id("specFile")),
+		jen.Return(jen.Nil()),
+	)
+}
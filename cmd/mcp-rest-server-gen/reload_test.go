@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestReloadToolDescriptions(t *testing.T) {
+	code := generate(t, "testdata/reload.yaml", "--reload-on-sighup", "--synthesize-ids")
+	assertContains(t, code,
+		`var toolNames = map[string]string{"GET /books": "GetBooks"}`,
+		`signal.Notify(sighup, syscall.SIGHUP)`,
+		`tooldesc.Operation(name, method, path, operation, pathItem.Parameters, descriptionOptions)`,
+	)
+
+	code = generate(t, "testdata/reload.yaml", "--synthesize-ids")
+	assertNotContains(t, code, "reloadToolDescriptions")
+}
+
+func TestReloadOnSighup(t *testing.T) {
+	spec, err := os.ReadFile("testdata/reload.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	specFile := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(specFile, spec, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binary := buildServer(t, specFile, "--reload-on-sighup", "--synthesize-ids", "--describe-links",
+		"--description-suffix-map", "GetBooks=Paginated by 50.")
+	session := startServer(t, binary, nil, "--spec-file", specFile)
+	if description := session.listTools(t)["GetBooks"]; !strings.HasPrefix(description, "Lists the books") {
+		t.Fatalf("the description before the reload is %q", description)
+	}
+
+	spec = []byte(strings.Replace(string(spec), "Lists the books", "Lists all the books", 1))
+	if err := os.WriteFile(specFile, spec, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.cmd.Process.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	session.waitForLog(t, "Tool descriptions reloaded")
+
+	// The reloaded description is built as the generator built the first one
+	want := "Lists all the books\n\nRelated operations:\n- GetBooksById (after a 200 response): pass id=$response.body#/0/id\n\nPaginated by 50."
+	if description := session.listTools(t)["GetBooks"]; description != want {
+		t.Errorf("the description after the reload is %q, want %q", description, want)
+	}
+}
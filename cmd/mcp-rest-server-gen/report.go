@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Report summarizes a generation run for machine consumption
+type Report struct {
+	Operations []string         `json:"operations"`
+	Files      []string         `json:"files"`
+	Warnings   []string         `json:"warnings"`
+	Timings    map[string]int64 `json:"timings"`
+}
+
+// report collects the outcome of the current generation run
+var report = Report{
+	Operations: []string{},
+	Files:      []string{},
+	Warnings:   []string{},
+	Timings:    map[string]int64{},
+}
+
+// print writes the report as indented JSON
+func (r Report) print(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// logf prints progress messages, they go to stderr when stdout is reserved
+// for the JSON report
+func logf(format string, args ...any) {
+	if CLI.OutputFormat == "json" {
+		fmt.Fprintf(os.Stderr, format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// warnf prints a warning and records it in the report
+func warnf(format string, args ...any) {
+	warning := fmt.Sprintf(format, args...)
+	report.Warnings = append(report.Warnings, warning)
+	logf("Warning: %s\n", warning)
+}
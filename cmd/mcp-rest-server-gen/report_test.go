@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONReport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building the generator is skipped in short mode")
+	}
+	dir := t.TempDir()
+	generator := filepath.Join(dir, "mcp-rest-server-gen")
+	if output, err := exec.Command("go", "build", "-o", generator, ".").CombinedOutput(); err != nil {
+		t.Fatalf("building the generator: %v\n%s", err, output)
+	}
+
+	cmd := exec.Command(generator, "--spec", booksSpec, "--output", filepath.Join(dir, "main.go"), "--output-format", "json")
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+
+	// The progress messages go to stderr, stdout only holds the report
+	var generated Report
+	if err := json.Unmarshal(stdout, &generated); err != nil {
+		t.Fatalf("the report %q is not JSON: %v", stdout, err)
+	}
+	if len(generated.Operations) != 2 || generated.Operations[0] != "AddBook" || generated.Operations[1] != "ListBooks" {
+		t.Errorf("the reported operations are %v, want the 2 of the spec", generated.Operations)
+	}
+	if len(generated.Files) != 1 || generated.Files[0] != filepath.Join(dir, "main.go") {
+		t.Errorf("the reported files are %v", generated.Files)
+	}
+	if _, ok := generated.Timings["totalMs"]; !ok {
+		t.Errorf("the report has no total time: %v", generated.Timings)
+	}
+}
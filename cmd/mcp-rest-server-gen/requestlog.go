@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// requestStatusCode returns the statement recording the status code of the
+// API response for the log of the tool call
+func requestStatusCode() jen.Code {
+	return jen.If(jen.Err().Op("==").Nil()).Block(
+		jen.Id("callStatus").Op("=").Add(respStatusCode()),
+	)
+}
+
+// requestLogCode wraps the handler body so the start and the end of each tool
+// call are logged with slog, with the method and the path template of the
+// operation, and the end with the status code of the API response, the
+// elapsed time and the error of failed calls. The path is logged as declared
+// so the credentials sent in the query or the host are not.
+func requestLogCode(op OperationInfo, body []jen.Code) []jen.Code {
+	attrs := func(extra ...jen.Code) []jen.Code {
+		return append([]jen.Code{
+			jen.Lit("operation"), jen.Lit(op.ID),
+			jen.Lit("method"), jen.Lit(op.Method),
+			jen.Lit("path"), jen.Lit(op.Path),
+			jen.Lit("status"), jen.Id("callStatus"),
+			jen.Lit("elapsed"), jen.Qual("time", "Since").Call(jen.Id("callStart")),
+		}, extra...)
+	}
+	return []jen.Code{
+		jen.Id("callStart").Op(":=").Qual("time", "Now").Call(),
+		jen.Id("callStatus").Op(":=").Lit(0),
+		jen.Qual("log/slog", "Info").Call(jen.Lit("Tool call started"), jen.Lit("operation"), jen.Lit(op.ID), jen.Lit("method"), jen.Lit(op.Method), jen.Lit("path"), jen.Lit(op.Path)),
+		jen.List(jen.Id("result"), jen.Err()).Op(":=").Func().Params().Params(toolResultType(), jen.Error()).Block(body...).Call(),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("log/slog", "Error").Call(append([]jen.Code{jen.Lit("Tool call failed")}, attrs(jen.Lit("error"), jen.Err())...)...),
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.Qual("log/slog", "Info").Call(append([]jen.Code{jen.Lit("Tool call finished")}, attrs()...)...),
+		jen.Return(jen.Id("result"), jen.Nil()),
+	}
+}
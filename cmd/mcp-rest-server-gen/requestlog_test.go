@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogRequests(t *testing.T) {
+	handler := respond(http.StatusOK, "application/json", `[{"Name":"Dune"}]`)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r)
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         []string
+		credentials []string
+	}{
+		{"basic", nil, nil, []string{"secret", base64.StdEncoding.EncodeToString([]byte("user:secret"))}},
+		{"apikey", []string{"--auth-type", "apikey", "--apikey-name", "X-Books-Key", "--apikey-env", "BOOKS_KEY"}, []string{"BOOKS_KEY=key-1234"}, []string{"key-1234"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			binary := buildServer(t, booksSpec, append([]string{"--log-requests"}, test.args...)...)
+			session := startServer(t, binary, test.env, "--host", upstream.URL)
+
+			// The calls are logged with their method, path and status
+			handler = respond(http.StatusOK, "application/json", `[{"Name":"Dune"}]`)
+			if result := session.callTool(t, "ListBooks", map[string]any{"NameFilter": "Dune"}); result.IsError {
+				t.Fatalf("ListBooks failed: %s", result.text())
+			}
+			session.waitForLog(t, `msg="Tool call finished" operation=ListBooks method=GET path=/ListBooks status=200 elapsed=`)
+			handler = respond(http.StatusNotFound, "text/plain", "no such book")
+			session.callTool(t, "AddBook", map[string]any{"Name": "Dune"})
+			session.waitForLog(t, `msg="Tool call failed" operation=AddBook method=PUT path=/AddBook status=404 elapsed=`)
+			if logs := session.stderr.String(); !strings.Contains(logs, `msg="Tool call started" operation=ListBooks method=GET path=/ListBooks`) {
+				t.Errorf("the start of the call was not logged:\n%s", logs)
+			}
+
+			// Neither the credentials nor the arguments are
+			logs := session.stderr.String()
+			for _, secret := range append(test.credentials, "Authorization", "X-Books-Key", "NameFilter") {
+				if strings.Contains(logs, secret) {
+					t.Errorf("the logs contain %q:\n%s", secret, logs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// formatArgument returns the tool argument choosing how the response is
+// rendered
+func formatArgument(op OperationInfo) ConfigField {
+	if op.hasParameter("format") {
+		warnf("parameter format of %s is shadowed by the response format argument", op.ID)
+	}
+	return ConfigField{
+		Name: "Format", Type: jen.String(),
+		Tags: map[string]string{
+			"json":                   "format,omitempty",
+			"jsonschema":             "enum=json,enum=pretty,enum=table",
+			"jsonschema_description": "Optional rendering of the JSON response, json returns it as sent by the API, pretty indents it and table renders an array of objects as a text table, json when empty",
+		},
+	}
+}
+
+// responseFormatCode returns the statements rendering the response body in
+// the format requested by the tool call
+func responseFormatCode(op OperationInfo) []jen.Code {
+	return []jen.Code{
+		jen.List(jen.Id("body"), jen.Err()).Op("=").Id("formatResponse").Call(jen.Id("body"), jen.Id("arguments").Dot("Format")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("error formatting "+op.ID+" response: %v"), jen.Err())),
+		),
+	}
+}
+
+// addFormatResponse adds the functions rendering a JSON response as indented
+// JSON or as a text table
+func addFormatResponse(f *jen.File) {
+	f.Comment("formatResponse renders the JSON body in the format, json leaves it as is, pretty indents it")
+	f.Comment("and table renders an array of objects with a column per field")
+	f.Func().Id("formatResponse").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("format").String(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Switch(jen.Id("format")).Block(
+			jen.Case(jen.Lit(""), jen.Lit("json")).Block(
+				jen.Return(jen.Id("body"), jen.Nil()),
+			),
+			jen.Case(jen.Lit("pretty")).Block(
+				jen.Var().Id("indented").Qual("bytes", "Buffer"),
+				jen.If(jen.Err().Op(":=").Qual("encoding/json", "Indent").Call(jen.Op("&").Id("indented"), jen.Id("body"), jen.Lit(""), jen.Lit("  ")), jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("response is not JSON: %w"), jen.Err())),
+				),
+				jen.Return(jen.Id("indented").Dot("Bytes").Call(), jen.Nil()),
+			),
+			jen.Case(jen.Lit("table")).Block(
+				jen.Return(jen.Id("formatTable").Call(jen.Id("body"))),
+			),
+		),
+		jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("unknown format %q, expected json, pretty or table"), jen.Id("format"))),
+	)
+
+	f.Comment("formatTable renders the JSON array of objects as a text table, the columns are the fields of")
+	f.Comment("the objects sorted by name and the nested values are written as JSON")
+	f.Func().Id("formatTable").Params(
+		jen.Id("body").Index().Byte(),
+	).Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("body"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.Var().Id("rows").Index().Map(jen.String()).Any(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("rows")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("the table format needs an array of objects: %w"), jen.Err())),
+		),
+		jen.If(jen.Len(jen.Id("rows")).Op("==").Lit(0)).Block(
+			jen.Return(jen.Index().Byte().Call(jen.Lit("(no rows)")), jen.Nil()),
+		),
+
+		jen.Id("seen").Op(":=").Make(jen.Map(jen.String()).Bool()),
+		jen.Var().Id("columns").Index().String(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("row")).Op(":=").Range().Id("rows")).Block(
+			jen.For(jen.Id("column").Op(":=").Range().Id("row")).Block(
+				jen.If(jen.Op("!").Id("seen").Index(jen.Id("column"))).Block(
+					jen.Id("seen").Index(jen.Id("column")).Op("=").True(),
+					jen.Id("columns").Op("=").Append(jen.Id("columns"), jen.Id("column")),
+				),
+			),
+		),
+		jen.Qual("sort", "Strings").Call(jen.Id("columns")),
+
+		jen.Var().Id("table").Qual("bytes", "Buffer"),
+		jen.Id("writer").Op(":=").Qual("text/tabwriter", "NewWriter").Call(jen.Op("&").Id("table"), jen.Lit(0), jen.Lit(0), jen.Lit(2), jen.LitRune(' '), jen.Lit(0)),
+		jen.Qual("fmt", "Fprintln").Call(jen.Id("writer"), jen.Qual("strings", "Join").Call(jen.Id("columns"), jen.Lit("\t"))),
+		jen.Id("cells").Op(":=").Make(jen.Index().String(), jen.Len(jen.Id("columns"))),
+		jen.For(jen.List(jen.Id("_"), jen.Id("row")).Op(":=").Range().Id("rows")).Block(
+			jen.For(jen.List(jen.Id("i"), jen.Id("column")).Op(":=").Range().Id("columns")).Block(
+				jen.Id("cells").Index(jen.Id("i")).Op("=").Id("tableCell").Call(jen.Id("row").Index(jen.Id("column"))),
+			),
+			jen.Qual("fmt", "Fprintln").Call(jen.Id("writer"), jen.Qual("strings", "Join").Call(jen.Id("cells"), jen.Lit("\t"))),
+		),
+		jen.If(jen.Err().Op(":=").Id("writer").Dot("Flush").Call(), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.Return(jen.Qual("bytes", "TrimRight").Call(jen.Id("table").Dot("Bytes").Call(), jen.Lit("\n")), jen.Nil()),
+	)
+
+	f.Comment("tableCell returns the text of a table cell, on a single line")
+	f.Func().Id("tableCell").Params(
+		jen.Id("value").Any(),
+	).String().Block(
+		jen.Var().Id("text").String(),
+		jen.Switch(jen.Id("v").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Nil()).Block(
+				jen.Return(jen.Lit("")),
+			),
+			jen.Case(jen.String()).Block(
+				jen.Id("text").Op("=").Id("v"),
+			),
+			jen.Case(jen.Index().Any(), jen.Map(jen.String()).Any()).Block(
+				jen.List(jen.Id("data"), jen.Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("v")),
+				jen.Id("text").Op("=").String().Call(jen.Id("data")),
+			),
+			jen.Default().Block(
+				jen.Id("text").Op("=").Qual("fmt", "Sprint").Call(jen.Id("v")),
+			),
+		),
+		jen.Return(jen.Qual("strings", "Join").Call(jen.Qual("strings", "Fields").Call(jen.Id("text")), jen.Lit(" "))),
+	)
+}
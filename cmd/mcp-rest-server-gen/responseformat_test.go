@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseFormat(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			respond(http.StatusOK, "application/json", `{"Id":1,"Name":"Dune"}`)(w, r)
+			return
+		}
+		respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"},{"Id":2,"Name":"The Left Hand of Darkness"}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--allow-response-format")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	tests := []struct {
+		format, want string
+	}{
+		{"", `[{"Id":1,"Name":"Dune"},{"Id":2,"Name":"The Left Hand of Darkness"}]`},
+		{"json", `[{"Id":1,"Name":"Dune"},{"Id":2,"Name":"The Left Hand of Darkness"}]`},
+		{"pretty", "[\n  {\n    \"Id\": 1,\n    \"Name\": \"Dune\"\n  },\n  {\n    \"Id\": 2,\n    \"Name\": \"The Left Hand of Darkness\"\n  }\n]"},
+		{"table", "Id  Name\n1   Dune\n2   The Left Hand of Darkness"},
+	}
+	for _, test := range tests {
+		result := session.callTool(t, "ListBooks", map[string]any{"format": test.format})
+		if result.IsError || result.text() != test.want {
+			t.Errorf("the %q format is %q, want %q", test.format, result.text(), test.want)
+		}
+	}
+
+	// The table needs an array of objects
+	result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune", "format": "table"})
+	if !result.IsError || !strings.Contains(result.text(), "the table format needs an array of objects") {
+		t.Errorf("formatting an object as a table returned %+v", result)
+	}
+}
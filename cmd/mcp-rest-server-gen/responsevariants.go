@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/codegen"
+)
+
+// ResponseVariant holds a success response declared for a status code
+type ResponseVariant struct {
+	Status      int
+	Description string
+	// Schema is the name of the component schema of the JSON body, empty
+	// when the schema is inline
+	Schema string
+}
+
+// responseVariants returns the 2xx responses of the operation when at least
+// two of them declare different JSON body schemas, ordered by status
+func responseVariants(operation *openapi3.Operation) []ResponseVariant {
+	if operation.Responses == nil {
+		return nil
+	}
+
+	var variants []ResponseVariant
+	schemas := make(map[string]bool)
+	for code, response := range operation.Responses.Map() {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 200 || status > 299 || response == nil || response.Value == nil {
+			continue
+		}
+		mediaType := response.Value.Content.Get("application/json")
+		if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+			continue
+		}
+
+		variant := ResponseVariant{Status: status}
+		if response.Value.Description != nil {
+			variant.Description = *response.Value.Description
+		}
+		if strings.HasPrefix(mediaType.Schema.Ref, "#/components/schemas/") {
+			variant.Schema = codegen.RefPathToObjName(mediaType.Schema.Ref)
+		}
+		variants = append(variants, variant)
+
+		// Inline schemas are told apart by identity
+		key := mediaType.Schema.Ref
+		if key == "" {
+			key = strconv.Itoa(len(variants))
+		}
+		schemas[key] = true
+	}
+
+	if len(schemas) < 2 {
+		return nil
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Status < variants[j].Status })
+	return variants
+}
+
+// responseVariantsCode returns the map literal of the JSON objects describing
+// the response variants of the operation, by status code
+func responseVariantsCode(op OperationInfo) jen.Code {
+	values := jen.Dict{}
+	for _, variant := range op.ResponseVariants {
+		response := map[string]any{"status": variant.Status}
+		if variant.Description != "" {
+			response["description"] = variant.Description
+		}
+		if variant.Schema != "" {
+			response["schema"] = variant.Schema
+		}
+		data, _ := json.Marshal(map[string]any{"response": response})
+		values[jen.Lit(variant.Status)] = jen.Lit(string(data))
+	}
+	return jen.Map(jen.Int()).String().Values(values)
+}
+
+// addResponseVariant adds the function describing the response variant
+// received, as a separate content of the tool result
+func addResponseVariant(f *jen.File) {
+	f.Comment("responseVariant returns a JSON object naming the response the spec declares for the")
+	f.Comment("status of the API response, kept apart from the response body")
+	f.Func().Id("responseVariant").Params(
+		jen.Id("status").Int(),
+		jen.Id("variants").Map(jen.Int()).String(),
+	).Add(contentType()).Block(
+		jen.If(jen.List(jen.Id("variant"), jen.Id("ok")).Op(":=").Id("variants").Index(jen.Id("status")), jen.Id("ok")).Block(
+			jen.Return(textContent(jen.Id("variant"))),
+		),
+		jen.List(jen.Id("data"), jen.Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Map(jen.String()).Any().Values(jen.Dict{
+			jen.Lit("response"): jen.Map(jen.String()).Any().Values(jen.Dict{
+				jen.Lit("status"): jen.Id("status"),
+			}),
+		})),
+		jen.Return(textContent(jen.String().Call(jen.Id("data")))),
+	)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// retryFields returns the runtime flags of the retries of the REST calls
+func retryFields() []ConfigField {
+	return []ConfigField{
+		{
+			Name: "Retry", Type: jen.Int(),
+			Tags: map[string]string{"help": "Maximum number of attempts of the REST calls failing with a network error or a transient status, 1 to not retry", "default": strconv.Itoa(CLI.Retry)},
+		},
+		{
+			Name: "RetryBackoff", Type: jen.Qual("time", "Duration"),
+			Tags: map[string]string{"help": "Wait before the first retry of a REST call, doubled at each retry", "default": CLI.RetryBackoff.String()},
+		},
+	}
+}
+
+// retryCode returns the loop making the REST call again while the helper
+// reports it should be retried
+func retryCode(op OperationInfo, ctx jen.Code, call jen.Code) jen.Code {
+	return jen.For(
+		jen.Id("attempt").Op(":=").Lit(1),
+		jen.Id("retryCall").Call(
+			ctx, jen.Lit(op.ID), jen.Id("attempt"), jen.Id("cli").Dot("Retry"), jen.Id("cli").Dot("RetryBackoff"), jen.Err(),
+			jen.Func().Params().Int().Block(jen.Return(respStatusCode())),
+		),
+		jen.Id("attempt").Op("++"),
+	).Block(
+		jen.List(jen.Id("resp"), jen.Err()).Op("=").Add(call),
+	)
+}
+
+// idempotencyKeyEditor returns the request editor sending the key in the
+// idempotency key header, unless the tool arguments already set it
+func idempotencyKeyEditor(key jen.Code) jen.Code {
+	return jen.Func().Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Error().Block(
+		jen.If(jen.Id("req").Dot("Header").Dot("Get").Call(jen.Lit(CLI.IdempotencyKeyHeader)).Op("==").Lit("")).Block(
+			jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit(CLI.IdempotencyKeyHeader), key),
+		),
+		jen.Return(jen.Nil()),
+	)
+}
+
+// addRetryCall adds the function deciding whether a REST call is retried, on
+// network errors and on the retry status codes, waiting an exponential backoff
+func addRetryCall(ranges []statusRange) func(f *jen.File) {
+	return func(f *jen.File) {
+		f.Comment("retryCall reports whether the REST call is made again after the attempt, because it failed")
+		f.Comment("with a network error or a transient status, once the backoff doubled at each attempt has")
+		f.Comment("passed. The status is only read when the call did not fail.")
+		f.Func().Id("retryCall").Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("operation").String(),
+			jen.List(jen.Id("attempt"), jen.Id("attempts")).Int(),
+			jen.Id("backoff").Qual("time", "Duration"),
+			jen.Err().Error(),
+			jen.Id("responseStatus").Func().Params().Int(),
+		).Bool().Block(
+			jen.If(jen.Id("attempt").Op(">=").Id("attempts")).Block(
+				jen.Return(jen.False()),
+			),
+			jen.Id("reason").Op(":=").Index().Any().Values(jen.Lit("operation"), jen.Id("operation"), jen.Lit("attempt"), jen.Id("attempt").Op("+").Lit(1)),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				// Only the failures to reach the API are retried, not the
+				// errors of the request editors
+				jen.Var().Id("urlErr").Op("*").Qual("net/url", "Error"),
+				jen.If(jen.Op("!").Qual("errors", "As").Call(jen.Err(), jen.Op("&").Id("urlErr")).Op("||").Id("ctx").Dot("Err").Call().Op("!=").Nil()).Block(
+					jen.Return(jen.False()),
+				),
+				jen.Id("reason").Op("=").Append(jen.Id("reason"), jen.Lit("error"), jen.Err()),
+			).Else().Block(
+				jen.Id("status").Op(":=").Id("responseStatus").Call(),
+				jen.If(jen.Op("!").Parens(statusCheck(ranges))).Block(
+					jen.Return(jen.False()),
+				),
+				jen.Id("reason").Op("=").Append(jen.Id("reason"), jen.Lit("status"), jen.Id("status")),
+			),
+			jen.Id("wait").Op(":=").Id("backoff").Op("<<").Parens(jen.Id("attempt").Op("-").Lit(1)),
+			jen.Qual("log/slog", "Warn").Call(jen.Lit("Retrying the API call"), jen.Append(jen.Id("reason"), jen.Lit("wait"), jen.Id("wait")).Op("...")),
+			jen.Select().Block(
+				jen.Case(jen.Op("<-").Id("ctx").Dot("Done").Call()).Block(
+					jen.Return(jen.False()),
+				),
+				jen.Case(jen.Op("<-").Qual("time", "After").Call(jen.Id("wait"))).Block(
+					jen.Return(jen.True()),
+				),
+			),
+		)
+	}
+}
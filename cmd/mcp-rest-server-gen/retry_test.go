@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRetryIdempotentOnly(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.Method]++
+		mu.Unlock()
+		respond(http.StatusServiceUnavailable, "text/plain", "busy")(w, r)
+	}))
+	defer upstream.Close()
+
+	attempts := func(method string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := calls[method]
+		delete(calls, method)
+		return n
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		get, post int
+	}{
+		{"safe", nil, 3, 1},
+		{"unsafe", []string{"--retry-unsafe"}, 3, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{"--auth-type", "none", "--retry", "3", "--retry-backoff", "1ms"}, test.args...)
+			binary := buildServer(t, "testdata/retry.yaml", args...)
+			session := startServer(t, binary, nil, "--host", upstream.URL)
+
+			if result := session.callTool(t, "ListBooks", map[string]any{}); !result.IsError {
+				t.Errorf("ListBooks succeeded with %s", result.text())
+			}
+			if got := attempts(http.MethodGet); got != test.get {
+				t.Errorf("ListBooks was called %d times, want %d", got, test.get)
+			}
+			if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); !result.IsError {
+				t.Errorf("AddBook succeeded with %s", result.text())
+			}
+			if got := attempts(http.MethodPost); got != test.post {
+				t.Errorf("AddBook was called %d times, want %d", got, test.post)
+			}
+		})
+	}
+}
+
+func TestRetryIdempotencyKey(t *testing.T) {
+	keys := make(chan string, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			keys <- r.Header.Get("Idempotency-Key")
+		}
+		respond(http.StatusServiceUnavailable, "text/plain", "busy")(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/retry.yaml", "--auth-type", "none", "--retry", "3", "--retry-backoff", "1ms", "--idempotency-key-header", "Idempotency-Key")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The attempts of a call share their key, the calls do not
+	var previous string
+	for range 2 {
+		if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); !result.IsError {
+			t.Errorf("AddBook succeeded with %s", result.text())
+		}
+		attempts := []string{<-keys, <-keys, <-keys}
+		if attempts[0] == "" || attempts[1] != attempts[0] || attempts[2] != attempts[0] {
+			t.Errorf("the attempts were sent with the keys %q, want the same key", attempts)
+		}
+		if attempts[0] == previous {
+			t.Errorf("two calls were sent with the key %q", previous)
+		}
+		previous = attempts[0]
+	}
+	select {
+	case key := <-keys:
+		t.Errorf("AddBook was attempted more than 3 times, with %q", key)
+	default:
+	}
+}
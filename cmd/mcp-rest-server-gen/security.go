@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// requiresAuth reports whether the effective security of the operation, its
+// own security or else the global one, requires authentication. Specs that
+// declare no security at all keep sending the credentials on every call.
+func requiresAuth(operation *openapi3.Operation, globalSecurity openapi3.SecurityRequirements) bool {
+	if operation.Security != nil {
+		return len(*operation.Security) > 0
+	}
+	if globalSecurity != nil {
+		return len(globalSecurity) > 0
+	}
+	return true
+}
+
+// authPerOperation reports whether only some operations require auth, in
+// which case the auth is applied on each call instead of on the client
+func authPerOperation(operations map[string]OperationInfo) bool {
+	for _, op := range operations {
+		if !op.RequiresAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// anyRequiresAuth reports whether any of the operations requires auth
+func anyRequiresAuth(operations map[string]OperationInfo) bool {
+	for _, op := range operations {
+		if op.RequiresAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// usedSecuritySchemes returns the sorted names of the security schemes the
+// credentials are sent for, the ones required globally or by an operation,
+// or all the declared ones when none is required
+func usedSecuritySchemes(doc *openapi3.T) []string {
+	names := make(map[string]bool)
+	addRequirements := func(requirements openapi3.SecurityRequirements) {
+		for _, requirement := range requirements {
+			for name := range requirement {
+				names[name] = true
+			}
+		}
+	}
+
+	addRequirements(doc.Security)
+	for _, pathItem := range doc.Paths.Map() {
+		for _, operation := range pathItem.Operations() {
+			if operation.Security != nil {
+				addRequirements(*operation.Security)
+			}
+		}
+	}
+	if len(names) == 0 && doc.Components != nil {
+		for name := range doc.Components.SecuritySchemes {
+			names[name] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// securitySchemeAuth returns the auth scheme matching a security scheme of
+// the spec, false when the generated server cannot provide it
+func securitySchemeAuth(scheme *openapi3.SecurityScheme) (authScheme, bool) {
+	switch {
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+		auth, err := newAuthScheme("basic")
+		return auth, err == nil
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"):
+		auth, err := newAuthScheme("bearer")
+		return auth, err == nil
+	case scheme.Type == "apiKey" && scheme.Name != "":
+		return newAPIKeyAuthScheme(scheme.In, scheme.Name), true
+	case clientCredentialsTokenURL(scheme) != "":
+		tokenURL := CLI.TokenURL
+		if tokenURL == "" {
+			tokenURL = clientCredentialsTokenURL(scheme)
+		}
+		return newOAuth2AuthScheme(tokenURL), true
+	}
+	return authScheme{}, false
+}
+
+// clientCredentialsTokenURL returns the token endpoint of the client
+// credentials flow of an oauth2 security scheme, empty when it has none
+func clientCredentialsTokenURL(scheme *openapi3.SecurityScheme) string {
+	if scheme.Type != "oauth2" || scheme.Flows == nil || scheme.Flows.ClientCredentials == nil {
+		return ""
+	}
+	return scheme.Flows.ClientCredentials.TokenURL
+}
+
+// specTokenURL returns the token endpoint of the first security scheme of
+// the spec with a client credentials flow, by name, empty when none has one
+func specTokenURL(doc *openapi3.T) string {
+	if doc.Components == nil {
+		return ""
+	}
+	names := make([]string, 0, len(doc.Components.SecuritySchemes))
+	for name := range doc.Components.SecuritySchemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ref := doc.Components.SecuritySchemes[name]; ref != nil && ref.Value != nil {
+			if tokenURL := clientCredentialsTokenURL(ref.Value); tokenURL != "" {
+				return tokenURL
+			}
+		}
+	}
+	return ""
+}
+
+// describeSecurityScheme returns a short description of a security scheme
+// for the error messages
+func describeSecurityScheme(name string, scheme *openapi3.SecurityScheme) string {
+	switch scheme.Type {
+	case "http":
+		return fmt.Sprintf("%s (http %s)", name, scheme.Scheme)
+	case "apiKey":
+		return fmt.Sprintf("%s (apiKey %s in %s)", name, scheme.Name, scheme.In)
+	case "oauth2":
+		if clientCredentialsTokenURL(scheme) != "" {
+			return fmt.Sprintf("%s (oauth2 client credentials)", name)
+		}
+	}
+	return fmt.Sprintf("%s (%s)", name, scheme.Type)
+}
+
+// detectAuthScheme returns the auth scheme given with --auth-type or, with
+// --auth-type=auto, the one of the single security scheme used by the spec,
+// basic auth when the spec declares no security schemes
+func detectAuthScheme(doc *openapi3.T) (authScheme, error) {
+	if CLI.AuthType == "oauth2" && CLI.TokenURL == "" {
+		if tokenURL := specTokenURL(doc); tokenURL != "" {
+			return newOAuth2AuthScheme(tokenURL), nil
+		}
+	}
+	if CLI.AuthType != "auto" {
+		if CLI.SecurityAlternative != "" {
+			warnf("--security-alternative is ignored without --auth-type=auto")
+		}
+		return newAuthScheme(CLI.AuthType)
+	}
+
+	var schemes openapi3.SecuritySchemes
+	if doc.Components != nil {
+		schemes = doc.Components.SecuritySchemes
+	}
+	names := usedSecuritySchemes(doc)
+	if len(names) == 0 {
+		return newAuthScheme("basic")
+	}
+
+	descriptions := make([]string, 0, len(names))
+	for _, name := range names {
+		ref := schemes[name]
+		if ref == nil || ref.Value == nil {
+			return authScheme{}, fmt.Errorf("security scheme %s is not declared in the spec", name)
+		}
+		descriptions = append(descriptions, describeSecurityScheme(name, ref.Value))
+	}
+	if len(names) == 1 {
+		auth, ok := securitySchemeAuth(schemes[names[0]].Value)
+		if !ok {
+			return authScheme{}, fmt.Errorf("security scheme %s is not supported, choose the auth with --auth-type", descriptions[0])
+		}
+		logf("Using %s auth from security scheme %s\n", auth.Type, descriptions[0])
+		return auth, nil
+	}
+
+	alternatives := securityAlternatives(doc)
+	if len(alternatives) == 0 {
+		return authScheme{}, fmt.Errorf("the spec uses several security schemes, choose the auth with --auth-type: %s", strings.Join(descriptions, ", "))
+	}
+	selected, err := selectSecurityAlternative(alternatives)
+	if err != nil {
+		return authScheme{}, err
+	}
+
+	// The schemes of the alternative are all satisfied, one after the other
+	parts := make([]authScheme, 0, len(selected))
+	for _, name := range selected {
+		description := describeSecurityScheme(name, schemes[name].Value)
+		auth, ok := securitySchemeAuth(schemes[name].Value)
+		if !ok {
+			return authScheme{}, fmt.Errorf("security scheme %s is not supported, choose another alternative with --security-alternative or the auth with --auth-type", description)
+		}
+		logf("Using %s auth from security scheme %s\n", auth.Type, description)
+		parts = append(parts, auth)
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return newCombinedAuthScheme(selected, parts), nil
+}
+
+// securityAlternatives returns the sorted alternatives of the security
+// requirements of the spec, global or of an operation, each one the names of
+// the schemes it requires together joined by +. The empty requirements making
+// the auth optional are left out.
+func securityAlternatives(doc *openapi3.T) []string {
+	seen := make(map[string]bool)
+	var alternatives []string
+	addRequirements := func(requirements openapi3.SecurityRequirements) {
+		for _, requirement := range requirements {
+			if len(requirement) == 0 {
+				continue
+			}
+			names := make([]string, 0, len(requirement))
+			for name := range requirement {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if alternative := strings.Join(names, "+"); !seen[alternative] {
+				seen[alternative] = true
+				alternatives = append(alternatives, alternative)
+			}
+		}
+	}
+
+	addRequirements(doc.Security)
+	for _, pathItem := range doc.Paths.Map() {
+		for _, operation := range pathItem.Operations() {
+			if operation.Security != nil {
+				addRequirements(*operation.Security)
+			}
+		}
+	}
+	sort.Strings(alternatives)
+	return alternatives
+}
+
+// selectSecurityAlternative returns the names of the schemes of the security
+// alternative given with --security-alternative, or of the single one of the
+// spec
+func selectSecurityAlternative(alternatives []string) ([]string, error) {
+	if CLI.SecurityAlternative == "" {
+		if len(alternatives) > 1 {
+			return nil, fmt.Errorf("the spec accepts several security alternatives, choose one with --security-alternative or the auth with --auth-type: %s", strings.Join(alternatives, ", "))
+		}
+		return strings.Split(alternatives[0], "+"), nil
+	}
+
+	names := strings.Split(CLI.SecurityAlternative, "+")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+	}
+	sort.Strings(names)
+	wanted := strings.Join(names, "+")
+	for _, alternative := range alternatives {
+		if alternative == wanted {
+			return names, nil
+		}
+	}
+	return nil, fmt.Errorf("security alternative %s is not accepted by the spec, choose one of: %s", CLI.SecurityAlternative, strings.Join(alternatives, ", "))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// defaultServerURL is the default of --server-url, replaced by the first
+// server of the spec when it declares one
+const defaultServerURL = "https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend"
+
+// specServerURLs returns the URLs of the servers declared by the spec, in
+// order, with their variables replaced. Relative URLs are resolved against
+// the spec URL, they are skipped when the spec is not read from a URL.
+func specServerURLs(doc *openapi3.T) ([]string, error) {
+	specURL, err := url.Parse(CLI.Spec)
+	remoteSpec := err == nil && (specURL.Scheme == "http" || specURL.Scheme == "https")
+
+	var urls []string
+	used := make(map[string]bool)
+	for _, server := range doc.Servers {
+		if server == nil || server.URL == "" {
+			continue
+		}
+		resolved, err := resolveServerVariables(server, used)
+		if err != nil {
+			return nil, err
+		}
+
+		serverURL, err := url.Parse(resolved)
+		if err != nil {
+			warnf("invalid server URL %s: %v", resolved, err)
+			continue
+		}
+		if !serverURL.IsAbs() {
+			if !remoteSpec {
+				warnf("server %s is relative to the spec URL, it is not used as default host", server.URL)
+				continue
+			}
+			serverURL = specURL.ResolveReference(serverURL)
+		}
+		urls = append(urls, strings.TrimSuffix(serverURL.String(), "/"))
+	}
+
+	for name := range CLI.ServerVars {
+		if !used[name] {
+			warnf("server variable %s is not used by the servers of the spec", name)
+		}
+	}
+	return urls, nil
+}
+
+// resolveServerVariables returns the URL of the server with its variables
+// replaced by the values given with --server-var or else by their defaults,
+// recording the variables used. The values of variables with an enum must be
+// one of the enum.
+func resolveServerVariables(server *openapi3.Server, used map[string]bool) (string, error) {
+	resolved := server.URL
+	for name, variable := range server.Variables {
+		placeholder := "{" + name + "}"
+		if variable == nil || !strings.Contains(resolved, placeholder) {
+			continue
+		}
+
+		value, ok := CLI.ServerVars[name]
+		if !ok {
+			value = variable.Default
+		}
+		if ok {
+			used[name] = true
+			if len(variable.Enum) > 0 && !slices.Contains(variable.Enum, value) {
+				return "", fmt.Errorf("invalid value %q of server variable %s, expected one of %s", value, name, strings.Join(variable.Enum, ", "))
+			}
+		}
+		resolved = strings.ReplaceAll(resolved, placeholder, value)
+	}
+	return resolved, nil
+}
+
+// defaultHost returns the default host of the generated server, the first
+// server of the spec unless another one is given with --server-url, and the
+// help of the host flag listing the servers of the spec
+func defaultHost(doc *openapi3.T) (string, string, error) {
+	host, help := CLI.ServerURL, "API server host"
+
+	urls, err := specServerURLs(doc)
+	if err != nil || len(urls) == 0 {
+		return host, help, err
+	}
+	if CLI.ServerURL == defaultServerURL {
+		host = urls[0]
+		logf("Using the first server of the spec as default host: %s\n", host)
+	}
+	if len(urls) > 1 {
+		help = "API server host, the spec declares " + strings.Join(urls, ", ")
+		logf("The spec declares %d servers: %s\n", len(urls), strings.Join(urls, ", "))
+	}
+	return host, help, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// serversSpec declares templated servers, the second one relative to the spec
+const serversSpec = `openapi: 3.0.1
+info: {title: Servers, version: "1.0"}
+servers:
+  - url: https://{region}.api.example.com/{basePath}
+    variables:
+      region: {default: eu, enum: [eu, us]}
+      basePath: {default: v1}
+  - url: /{basePath}/
+    variables:
+      basePath: {default: v1}
+paths:
+  /books:
+    get:
+      operationId: ListBooks
+      responses:
+        "200": {description: ok}
+`
+
+// loadServersSpec parses the flags and returns the spec with the servers
+func loadServersSpec(t *testing.T, spec string, args ...string) *openapi3.T {
+	t.Helper()
+	parseFlags(t, append([]string{"--spec", "https://specs.example.com/books/openapi.yaml"}, args...)...)
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestServerVariables(t *testing.T) {
+	tests := []struct {
+		args     []string
+		want     []string
+		warnings []string
+	}{
+		// The variables default to their default values
+		{nil, []string{"https://eu.api.example.com/v1", "https://specs.example.com/v1"}, nil},
+		// The values given replace them in all the servers
+		{[]string{"--server-var", "region=us", "--server-var", "basePath=v2"}, []string{"https://us.api.example.com/v2", "https://specs.example.com/v2"}, nil},
+		{[]string{"--server-var", "basePath=beta/v3"}, []string{"https://eu.api.example.com/beta/v3", "https://specs.example.com/beta/v3"}, nil},
+		// The variables unknown to the servers are reported
+		{[]string{"--server-var", "tenant=acme"}, []string{"https://eu.api.example.com/v1", "https://specs.example.com/v1"}, []string{"server variable tenant is not used by the servers of the spec"}},
+	}
+	for _, test := range tests {
+		doc := loadServersSpec(t, serversSpec, test.args...)
+		urls, err := specServerURLs(doc)
+		if err != nil || !slices.Equal(urls, test.want) {
+			t.Errorf("the servers with %v are %v, %v, want %v", test.args, urls, err, test.want)
+		}
+		if !slices.Equal(report.Warnings, test.warnings) {
+			t.Errorf("the warnings with %v are %q, want %q", test.args, report.Warnings, test.warnings)
+		}
+	}
+
+	// The resolved URL is the default host of the generated server
+	code := generate(t, writeSpec(t, serversSpec), "--auth-type", "none", "--server-var", "region=us")
+	assertContains(t, code, `default:"https://us.api.example.com/v1"`)
+
+	// The values of a variable with an enum must be one of the enum
+	doc := loadServersSpec(t, serversSpec, "--server-var", "region=asia")
+	if _, err := specServerURLs(doc); err == nil || err.Error() != `invalid value "asia" of server variable region, expected one of eu, us` {
+		t.Errorf("a region outside the enum failed with %v", err)
+	}
+	_, _, err := defaultHost(doc)
+	if err == nil {
+		t.Error("the default host was chosen with a region outside the enum")
+	}
+}
+
+func TestDefaultHost(t *testing.T) {
+	// The first server of the spec is the default host
+	host, help, err := defaultHost(loadServersSpec(t, serversSpec))
+	if err != nil || host != "https://eu.api.example.com/v1" {
+		t.Errorf("the default host is %s, %v, want the first server", host, err)
+	}
+	if want := "API server host, the spec declares https://eu.api.example.com/v1, https://specs.example.com/v1"; help != want {
+		t.Errorf("the host help is %q, want %q", help, want)
+	}
+
+	// --server-url takes over the servers of the spec
+	host, _, err = defaultHost(loadServersSpec(t, serversSpec, "--server-url", "https://staging.example.com"))
+	if err != nil || host != "https://staging.example.com" {
+		t.Errorf("the default host given with --server-url is %s, %v", host, err)
+	}
+
+	// A spec without server keeps the default of --server-url
+	host, help, err = defaultHost(loadServersSpec(t, "openapi: 3.0.1\ninfo: {title: None, version: \"1.0\"}\npaths: {}\n"))
+	if err != nil || host != defaultServerURL || help != "API server host" {
+		t.Errorf("without servers the default host is %s with help %q, %v", host, help, err)
+	}
+
+	// Relative servers are skipped when the spec is not read from a URL
+	parseFlags(t, "--spec", "testdata/books.yaml")
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(serversSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls, err := specServerURLs(doc); err != nil || !slices.Equal(urls, []string{"https://eu.api.example.com/v1"}) {
+		t.Errorf("the servers of a local spec are %v, %v", urls, err)
+	}
+	assertWarning(t, "server /{basePath}/ is relative to the spec URL, it is not used as default host")
+}
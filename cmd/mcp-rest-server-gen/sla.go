@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// loadSLABudgets parses the latency budgets given for the operations
+func loadSLABudgets(operations map[string]OperationInfo) error {
+	for id, value := range CLI.SLABudgets {
+		op, ok := operations[id]
+		if !ok {
+			warnf("SLA budget given for unknown operation %s", id)
+			continue
+		}
+
+		budget, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid SLA budget of %s: %w", id, err)
+		}
+		if budget <= 0 {
+			return fmt.Errorf("invalid SLA budget of %s: %s is not positive", id, value)
+		}
+		op.SLABudget = budget
+		operations[id] = op
+	}
+	return nil
+}
+
+// slaStartCode returns the statements holding the latency budget of the
+// operation and the start time of the REST call
+func slaStartCode(op OperationInfo) []jen.Code {
+	budget := jen.Qual("time", "Duration").Call(jen.Lit(int(op.SLABudget)))
+	if op.SLABudget%time.Millisecond == 0 {
+		budget = jen.Lit(int(op.SLABudget.Milliseconds())).Op("*").Qual("time", "Millisecond")
+	}
+	return []jen.Code{
+		jen.Id("budget").Op(":=").Add(budget),
+		jen.Id("start").Op(":=").Qual("time", "Now").Call(),
+	}
+}
+
+// slaCheckCode returns the statements measuring the time taken by the REST
+// call, with its retries, and logging the calls over the budget
+func slaCheckCode(op OperationInfo) []jen.Code {
+	return []jen.Code{
+		jen.Id("elapsed").Op(":=").Qual("time", "Since").Call(jen.Id("start")),
+		jen.If(jen.Id("elapsed").Op(">").Id("budget")).Block(
+			jen.Qual("log/slog", "Warn").Call(
+				jen.Lit("Call over its SLA budget"),
+				jen.Lit("operation"), jen.Lit(op.ID),
+				jen.Lit("elapsed"), jen.Id("elapsed"),
+				jen.Lit("budget"), jen.Id("budget"),
+			),
+		),
+	}
+}
+
+// addSLAMeta adds the function describing the latency of a call against its
+// budget, as a separate content of the tool result
+func addSLAMeta(f *jen.File) {
+	f.Comment("slaMeta returns a JSON object with the time taken by the call, its budget and whether")
+	f.Comment("the call was over the budget, kept apart from the response body")
+	f.Func().Id("slaMeta").Params(
+		jen.List(jen.Id("elapsed"), jen.Id("budget")).Qual("time", "Duration"),
+	).Add(contentType()).Block(
+		jen.List(jen.Id("data"), jen.Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Map(jen.String()).Any().Values(jen.Dict{
+			jen.Lit("sla"): jen.Map(jen.String()).Any().Values(jen.Dict{
+				jen.Lit("elapsedMs"):  jen.Id("elapsed").Dot("Milliseconds").Call(),
+				jen.Lit("budgetMs"):   jen.Id("budget").Dot("Milliseconds").Call(),
+				jen.Lit("overBudget"): jen.Id("elapsed").Op(">").Id("budget"),
+			}),
+		})),
+		jen.Return(textContent(jen.String().Call(jen.Id("data")))),
+	)
+}
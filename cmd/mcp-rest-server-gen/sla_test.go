@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slaResult returns the SLA metadata of the tool result
+func slaResult(t *testing.T, result callResult) map[string]any {
+	t.Helper()
+	for _, content := range result.Content {
+		var meta struct {
+			SLA map[string]any `json:"sla"`
+		}
+		if json.Unmarshal([]byte(content.Text), &meta) == nil && meta.SLA != nil {
+			return meta.SLA
+		}
+	}
+	t.Fatalf("the result has no SLA metadata: %+v", result)
+	return nil
+}
+
+func TestSLABudget(t *testing.T) {
+	generate(t, booksSpec, "--sla-budget", "ListBooks=300ms;RemoveBook=1s")
+	assertWarning(t, "SLA budget given for unknown operation RemoveBook")
+	if _, err := runGenerator(t, booksSpec, "--sla-budget", "ListBooks=-1s"); err == nil || !strings.Contains(err.Error(), "invalid SLA budget of ListBooks: -1s is not positive") {
+		t.Errorf("generating with a negative budget failed with %v", err)
+	}
+
+	delay := 500 * time.Millisecond
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--sla-budget", "ListBooks=300ms")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The slow call keeps its response and is flagged
+	result := session.callTool(t, "ListBooks", map[string]any{})
+	if result.IsError || !strings.Contains(result.text(), "Dune") {
+		t.Fatalf("ListBooks returned %+v", result)
+	}
+	sla := slaResult(t, result)
+	if sla["overBudget"] != true || sla["budgetMs"] != 300.0 || sla["elapsedMs"].(float64) < 500 {
+		t.Errorf("the slow call SLA is %v, want over the 300ms budget", sla)
+	}
+	session.waitForLog(t, "Call over its SLA budget")
+
+	delay = 0
+	if sla := slaResult(t, session.callTool(t, "ListBooks", map[string]any{})); sla["overBudget"] != false {
+		t.Errorf("the fast call SLA is %v, want within the budget", sla)
+	}
+}
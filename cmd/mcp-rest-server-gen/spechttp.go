@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/renato0307/go-mcp-rest/internal/specload"
+)
+
+// specHTTPClient returns the HTTP client fetching the spec at the given URL and
+// the files it references, with the --spec-header and --spec-bearer headers
+func specHTTPClient(specURL *url.URL) (*http.Client, error) {
+	headers, err := specload.Headers(CLI.SpecHeaders, CLI.SpecBearer)
+	if err != nil {
+		return nil, err
+	}
+	return specload.HTTPClient(specURL, headers), nil
+}
+
+// fetchSpec downloads the content at the URL of a remote spec, or reads it from
+// the spec cache, and returns the spec loader reading the remote references
+// through the same client
+func fetchSpec(specURL *url.URL) ([]byte, openapi3.ReadFromURIFunc, error) {
+	client, err := specHTTPClient(specURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	readFromURI := openapi3.URIMapCache(openapi3.ReadFromURIs(openapi3.ReadFromHTTP(client), openapi3.ReadFromFile))
+
+	// A spec fetched by a recent run is reused, the files it references are
+	// still fetched
+	if content, ok := specload.Cached(CLI.SpecCacheDir, specURL.String(), CLI.SpecCacheTTL); ok {
+		logf("Using the spec cached in %s\n", CLI.SpecCacheDir)
+		return content, readFromURI, nil
+	}
+
+	resp, err := client.Get(specURL.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching from URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if CLI.SpecCacheDir != "" {
+		if err := specload.Cache(CLI.SpecCacheDir, specURL.String(), content); err != nil {
+			warnf("could not cache the spec: %v", err)
+		}
+	}
+
+	return content, readFromURI, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/renato0307/go-mcp-rest/internal/specload"
+)
+
+func TestSpecCache(t *testing.T) {
+	spec, err := os.ReadFile(booksSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(spec)
+	}))
+	defer upstream.Close()
+	specURL := upstream.URL + "/openapi.yaml"
+	dir := t.TempDir()
+
+	steps := []struct {
+		name    string
+		args    []string
+		fetches int32
+	}{
+		{"the first run fetches the spec", []string{"--spec-cache-dir", dir}, 1},
+		{"the next run reuses it", []string{"--spec-cache-dir", dir}, 1},
+		{"a zero TTL bypasses the cache", []string{"--spec-cache-dir", dir, "--spec-cache-ttl", "0"}, 2},
+		{"no cache directory bypasses the cache", nil, 3},
+		{"the cache is still used", []string{"--spec-cache-dir", dir, "--spec-cache-ttl", "1m"}, 3},
+	}
+	want := generate(t, booksSpec, "--auth-type", "none")
+	for _, step := range steps {
+		if code := generate(t, specURL, append([]string{"--auth-type", "none"}, step.args...)...); code != want {
+			t.Errorf("when %s the server differs from the one of the spec file", step.name)
+		}
+		if got := fetches.Load(); got != step.fetches {
+			t.Errorf("when %s the spec was fetched %d times, want %d", step.name, got, step.fetches)
+		}
+	}
+
+	// The cached spec expires after the TTL
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(specload.CachePath(dir, specURL), old, old); err != nil {
+		t.Fatal(err)
+	}
+	generate(t, specURL, "--auth-type", "none", "--spec-cache-dir", dir)
+	if got := fetches.Load(); got != 4 {
+		t.Errorf("the expired spec was fetched %d times in all, want 4", got)
+	}
+	generate(t, specURL, "--auth-type", "none", "--spec-cache-dir", dir)
+	if got := fetches.Load(); got != 4 {
+		t.Errorf("the spec fetched again was not cached, fetched %d times in all", got)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// specFileName is the name of the spec file embedded in the generated server
+const specFileName = "openapi.json"
+
+// embedSpec writes the spec next to the generated code and declares the
+// openAPISpec variable embedding it
+func embedSpec(f *jen.File, doc *openapi3.T) error {
+	// The files referenced by the spec are not embedded, their definitions
+	// are moved into the spec itself
+	doc.InternalizeRefs(context.Background(), nil)
+
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling OpenAPI spec: %w", err)
+	}
+
+	specPath := filepath.Join(filepath.Dir(CLI.Output), specFileName)
+	if err := os.WriteFile(specPath, content, 0644); err != nil {
+		return fmt.Errorf("error writing embedded OpenAPI spec: %w", err)
+	}
+	report.Files = append(report.Files, specPath)
+
+	f.Anon("embed")
+	f.Comment("openAPISpec holds the OpenAPI spec the server was generated from")
+	f.Comment("//go:embed " + specFileName)
+	f.Var().Id("openAPISpec").String()
+
+	f.Comment("SpecToolArguments are the (empty) arguments of the spec tool")
+	f.Type().Id("SpecToolArguments").Struct()
+
+	return nil
+}
+
+// registerSpecTool returns the statements registering the tool that returns
+// the embedded OpenAPI spec
+func registerSpecTool() []jen.Code {
+	return registerTool(
+		"server",
+		"GetOpenAPISpec",
+		"Returns the OpenAPI specification of the API, use it when the other tools are not enough to understand the API contract",
+		jen.Id("SpecToolArguments"),
+		jen.Func().Params(
+			jen.Id("arguments").Id("SpecToolArguments"),
+		).Params(toolResultType(), jen.Error()).Block(
+			jen.Return(toolResult(textContent(jen.Id("openAPISpec"))), jen.Nil()),
+		),
+		nil,
+	)
+}
+
+// addSpecSource declares the constants recording the spec the server was
+// generated from, so deployed servers can be traced back to it
+func addSpecSource(f *jen.File, content []byte) {
+	hash := sha256.Sum256(content)
+
+	// Credentials in the spec URL are not embedded
+	source := CLI.Spec
+	if parsedURL, err := url.Parse(source); err == nil && parsedURL.User != nil {
+		source = parsedURL.Redacted()
+	}
+
+	f.Comment("specSource is the path or URL of the OpenAPI spec the server was generated from")
+	f.Const().Id("specSource").Op("=").Lit(source)
+
+	f.Comment("specSHA256 is the SHA-256 hash of the OpenAPI spec content")
+	f.Const().Id("specSHA256").Op("=").Lit(hex.EncodeToString(hash[:]))
+}
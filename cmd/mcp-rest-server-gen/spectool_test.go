@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpecTool(t *testing.T) {
+	binary := buildServer(t, booksSpec, "--with-spec-tool")
+	session := startServer(t, binary, nil)
+	if _, ok := session.listTools(t)["GetOpenAPISpec"]; !ok {
+		t.Fatal("the spec tool is not registered")
+	}
+
+	result := session.callTool(t, "GetOpenAPISpec", map[string]any{})
+	if result.IsError {
+		t.Fatalf("GetOpenAPISpec failed: %s", result.text())
+	}
+	var spec struct {
+		Info  struct{ Title string }
+		Paths map[string]any
+	}
+	if err := json.Unmarshal([]byte(result.text()), &spec); err != nil {
+		t.Fatalf("the spec tool returned %q: %v", result.text(), err)
+	}
+	if spec.Info.Title != "Backend" || spec.Paths["/ListBooks"] == nil || spec.Paths["/AddBook"] == nil {
+		t.Errorf("the spec tool did not return the embedded spec: %s", result.text())
+	}
+}
+
+func TestRecordSpecSource(t *testing.T) {
+	content, err := os.ReadFile(booksSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(content)
+	sum := hex.EncodeToString(hash[:])
+
+	code := generate(t, booksSpec, "--record-spec-source")
+	assertContains(t, code,
+		`const specSource = "testdata/books.yaml"`,
+		`const specSHA256 = "`+sum+`"`,
+	)
+
+	binary := buildServer(t, booksSpec, "--record-spec-source")
+	startServer(t, binary, nil).waitForLog(t, `msg="Generated from OpenAPI spec" source=testdata/books.yaml sha256=`+sum)
+}
+
+func TestRecordSpecSourceRedactsURL(t *testing.T) {
+	content, err := os.ReadFile(booksSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := httptest.NewServer(respond(http.StatusOK, "application/yaml", string(content)))
+	defer spec.Close()
+
+	specURL := strings.Replace(spec.URL, "http://", "http://reader:hunter2@", 1) + "/openapi.yaml"
+	code := generate(t, specURL, "--record-spec-source")
+	assertContains(t, code, `const specSource = "`+strings.Replace(specURL, "hunter2", "xxxxx", 1)+`"`)
+	assertNotContains(t, code, "hunter2")
+}
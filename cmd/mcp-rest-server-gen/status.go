@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// statusRange is an inclusive range of HTTP status codes
+type statusRange struct {
+	From int
+	To   int
+}
+
+// parseStatusCodes parses the status codes of the kind, success or retry,
+// each one a single code such as 304 or a range such as 200-299
+func parseStatusCodes(codes []string, kind string) ([]statusRange, error) {
+	ranges := make([]statusRange, 0, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		from, to, isRange := strings.Cut(code, "-")
+		if !isRange {
+			to = from
+		}
+
+		first, err := parseStatusCode(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s code %q: %w", kind, code, err)
+		}
+		last, err := parseStatusCode(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s code %q: %w", kind, code, err)
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid %s code %q: the range is empty", kind, code)
+		}
+		ranges = append(ranges, statusRange{From: first, To: last})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no %s codes given", kind)
+	}
+	return ranges, nil
+}
+
+// parseStatusCode parses an HTTP status code, from 100 to 599
+func parseStatusCode(value string) (int, error) {
+	status, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	if status < 100 || status > 599 {
+		return 0, fmt.Errorf("%d is not an HTTP status code", status)
+	}
+	return status, nil
+}
+
+// addSuccessStatus adds the function reporting whether a status code is one
+// of the success codes
+func addSuccessStatus(ranges []statusRange) func(f *jen.File) {
+	return func(f *jen.File) {
+		f.Comment("successStatus reports whether the status code of an API response is a success")
+		f.Func().Id("successStatus").Params(jen.Id("status").Int()).Bool().Block(
+			jen.Return(statusCheck(ranges)),
+		)
+	}
+}
+
+// statusCheck returns the condition matching the status variable against
+// the status code ranges
+func statusCheck(ranges []statusRange) *jen.Statement {
+	var check *jen.Statement
+	for _, r := range ranges {
+		condition := jen.Id("status").Op("==").Lit(r.From)
+		if r.From != r.To {
+			condition = jen.Id("status").Op(">=").Lit(r.From).Op("&&").Id("status").Op("<=").Lit(r.To)
+		}
+		if check == nil {
+			check = condition
+			continue
+		}
+		check = check.Op("||").Add(condition)
+	}
+	return check
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	tests := []struct {
+		codes []string
+		want  []statusRange
+	}{
+		{[]string{"200-299"}, []statusRange{{200, 299}}},
+		{[]string{"201", "204"}, []statusRange{{201, 201}, {204, 204}}},
+		{[]string{" 200-299 ", "304"}, []statusRange{{200, 299}, {304, 304}}},
+		{[]string{"204-204"}, []statusRange{{204, 204}}},
+	}
+	for _, test := range tests {
+		got, err := parseStatusCodes(test.codes, "success")
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parsing %q gave %v, %v, want %v", test.codes, got, err, test.want)
+		}
+	}
+
+	errors := []struct {
+		codes []string
+		want  string
+	}{
+		{[]string{"abc"}, `invalid success code "abc": "abc" is not a number`},
+		{[]string{"299-200"}, `invalid success code "299-200": the range is empty`},
+		{[]string{"99"}, `invalid success code "99": 99 is not an HTTP status code`},
+		{[]string{"200-600"}, `invalid success code "200-600": 600 is not an HTTP status code`},
+		{[]string{"200", ""}, `invalid success code "": "" is not a number`},
+		{nil, "no success codes given"},
+	}
+	for _, test := range errors {
+		if _, err := parseStatusCodes(test.codes, "success"); err == nil || err.Error() != test.want {
+			t.Errorf("parsing %q failed with %v, want %s", test.codes, err, test.want)
+		}
+	}
+
+	_, err := runGenerator(t, booksSpec, "--success-codes", "2xx")
+	if err == nil || !strings.Contains(err.Error(), `invalid success code "2xx"`) {
+		t.Errorf("generating with --success-codes=2xx failed with %v", err)
+	}
+}
+
+func TestSuccessCodes(t *testing.T) {
+	var status atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer upstream.Close()
+
+	// Each server is called with statuses inside and outside its success codes
+	tests := []struct {
+		codes   string
+		success int
+		failure int
+	}{
+		{"200-299", http.StatusAccepted, http.StatusNotModified},
+		{"200", http.StatusOK, http.StatusAccepted},
+		{"200-299,304", http.StatusNotModified, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.codes, func(t *testing.T) {
+			binary := buildServer(t, booksSpec, "--success-codes", test.codes)
+			session := startServer(t, binary, nil, "--host", upstream.URL)
+
+			status.Store(int32(test.success))
+			if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+				t.Errorf("the %d status failed: %s", test.success, result.text())
+			}
+			status.Store(int32(test.failure))
+			if result := session.callTool(t, "ListBooks", map[string]any{}); !result.IsError {
+				t.Errorf("the %d status succeeded", test.failure)
+			}
+		})
+	}
+}
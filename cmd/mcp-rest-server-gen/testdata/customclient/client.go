@@ -0,0 +1,69 @@
+// Package api is a hand-written client of the books API, its methods and
+// responses not following the naming of the oapi-codegen clients
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type AddBookJSONRequestBody struct {
+	Name   *string `json:"Name,omitempty"`
+	Author *string `json:"Author,omitempty"`
+}
+
+type ListBooksParams struct {
+	NameFilter *string `json:"NameFilter,omitempty"`
+}
+
+// Result is the response of all the calls
+type Result struct {
+	Code    int
+	Status  string
+	Payload string
+}
+
+type Client struct {
+	host string
+}
+
+func NewClientWithResponses(host string) (*Client, error) {
+	return &Client{host: host}, nil
+}
+
+func (c *Client) CallAddBook(ctx context.Context, body AddBookJSONRequestBody) (*Result, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.call(ctx, http.MethodPut, "/AddBook", bytes.NewReader(data))
+}
+
+func (c *Client) CallListBooks(ctx context.Context, params *ListBooksParams) (*Result, error) {
+	query := url.Values{}
+	if params.NameFilter != nil {
+		query.Set("NameFilter", *params.NameFilter)
+	}
+	return c.call(ctx, http.MethodGet, "/ListBooks?"+query.Encode(), nil)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, body io.Reader) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Code: resp.StatusCode, Status: resp.Status, Payload: string(payload)}, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// addTokenSource adds the security provider of the oauth2 auth. It fetches
+// the access tokens of the client credentials flow and keeps each one until
+// shortly before its expires_in, replacing it at the latest halfway through
+// its lifetime. The cache is shared by all the handlers, so
+// the token endpoint is called about once per token lifetime. A token the API
+// rejects with a 401 status is dropped and the request sent once more with a
+// new one.
+func addTokenSource(f *jen.File) {
+	f.Comment("tokenRefreshMargin is how long before its expiry an access token is replaced, at most")
+	f.Comment("half its lifetime")
+	f.Const().Id("tokenRefreshMargin").Op("=").Lit(30).Op("*").Qual("time", "Second")
+
+	f.Comment("tokenSource sends the access tokens of the OAuth2 client credentials flow, caching")
+	f.Comment("each one until it is about to expire")
+	f.Type().Id("tokenSource").Struct(
+		jen.Id("tokenURL").String(),
+		jen.Id("clientID").String(),
+		jen.Id("clientSecret").String(),
+		jen.Line(),
+		jen.Id("mu").Qual("sync", "Mutex"),
+		jen.Id("token").String(),
+		jen.Id("refresh").Qual("time", "Time"),
+		jen.Id("rejected").String(),
+	)
+
+	f.Comment("newTokenSource returns a token source fetching the access tokens from the token endpoint")
+	f.Func().Id("newTokenSource").Params(
+		jen.List(jen.Id("tokenURL"), jen.Id("clientID"), jen.Id("clientSecret")).String(),
+	).Params(jen.Op("*").Id("tokenSource"), jen.Error()).Block(
+		jen.If(jen.List(jen.Id("_"), jen.Err()).Op(":=").Qual("net/url", "ParseRequestURI").Call(jen.Id("tokenURL")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid token endpoint: %w"), jen.Err())),
+		),
+		jen.Return(jen.Op("&").Id("tokenSource").Values(jen.Dict{
+			jen.Id("tokenURL"):     jen.Id("tokenURL"),
+			jen.Id("clientID"):     jen.Id("clientID"),
+			jen.Id("clientSecret"): jen.Id("clientSecret"),
+		}), jen.Nil()),
+	)
+
+	f.Comment("Intercept sends the access token as the bearer token of the request")
+	f.Func().Params(jen.Id("s").Op("*").Id("tokenSource")).Id("Intercept").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Error().Block(
+		jen.List(jen.Id("token"), jen.Err()).Op(":=").Id("s").Dot("accessToken").Call(jen.Id("ctx")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Err()),
+		),
+		jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit("Authorization"), jen.Lit("Bearer ").Op("+").Id("token")),
+		jen.Return(jen.Nil()),
+	)
+
+	f.Comment("accessToken returns the cached access token, fetching a new one when there is none")
+	f.Comment("or it is about to expire. Tokens without expires_in are kept for the server lifetime.")
+	f.Func().Params(jen.Id("s").Op("*").Id("tokenSource")).Id("accessToken").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+	).Params(jen.String(), jen.Error()).Block(
+		jen.Id("s").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("s").Dot("mu").Dot("Unlock").Call(),
+		jen.If(jen.Id("s").Dot("token").Op("!=").Lit("").Op("&&").Parens(
+			jen.Id("s").Dot("refresh").Dot("IsZero").Call().Op("||").Qual("time", "Now").Call().Dot("Before").Call(jen.Id("s").Dot("refresh")),
+		)).Block(
+			jen.Return(jen.Id("s").Dot("token"), jen.Nil()),
+		),
+
+		jen.Id("form").Op(":=").Qual("net/url", "Values").Values(jen.Dict{
+			jen.Lit("grant_type"): jen.Values(jen.Lit("client_credentials")),
+		}),
+		jen.List(jen.Id("req"), jen.Err()).Op(":=").Qual("net/http", "NewRequestWithContext").Call(
+			jen.Id("ctx"), jen.Qual("net/http", "MethodPost"), jen.Id("s").Dot("tokenURL"),
+			jen.Qual("strings", "NewReader").Call(jen.Id("form").Dot("Encode").Call()),
+		),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Lit(""), jen.Err()),
+		),
+		jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit("Content-Type"), jen.Lit("application/x-www-form-urlencoded")),
+		jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit("Accept"), jen.Lit("application/json")),
+		jen.Id("req").Dot("SetBasicAuth").Call(
+			jen.Qual("net/url", "QueryEscape").Call(jen.Id("s").Dot("clientID")),
+			jen.Qual("net/url", "QueryEscape").Call(jen.Id("s").Dot("clientSecret")),
+		),
+
+		jen.List(jen.Id("resp"), jen.Err()).Op(":=").Qual("net/http", "DefaultClient").Dot("Do").Call(jen.Id("req")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Lit(""), jen.Qual("fmt", "Errorf").Call(jen.Lit("error fetching access token: %w"), jen.Err())),
+		),
+		jen.Defer().Id("resp").Dot("Body").Dot("Close").Call(),
+		jen.If(jen.Id("resp").Dot("StatusCode").Op("!=").Qual("net/http", "StatusOK")).Block(
+			jen.Return(jen.Lit(""), jen.Qual("fmt", "Errorf").Call(jen.Lit("error fetching access token: status %d"), jen.Id("resp").Dot("StatusCode"))),
+		),
+
+		jen.Var().Id("token").Struct(
+			jen.Id("AccessToken").String().Tag(map[string]string{"json": "access_token"}),
+			jen.Id("ExpiresIn").Int64().Tag(map[string]string{"json": "expires_in"}),
+		),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body")).Dot("Decode").Call(jen.Op("&").Id("token")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Lit(""), jen.Qual("fmt", "Errorf").Call(jen.Lit("invalid token response: %w"), jen.Err())),
+		),
+		jen.If(jen.Id("token").Dot("AccessToken").Op("==").Lit("")).Block(
+			jen.Return(jen.Lit(""), jen.Qual("errors", "New").Call(jen.Lit("invalid token response: no access_token"))),
+		),
+
+		jen.Id("s").Dot("token").Op("=").Id("token").Dot("AccessToken"),
+		jen.Id("s").Dot("refresh").Op("=").Qual("time", "Time").Values(),
+		jen.If(jen.Id("token").Dot("ExpiresIn").Op(">").Lit(0)).Block(
+			jen.Id("lifetime").Op(":=").Qual("time", "Duration").Call(jen.Id("token").Dot("ExpiresIn")).Op("*").Qual("time", "Second"),
+			jen.Id("s").Dot("refresh").Op("=").Qual("time", "Now").Call().Dot("Add").Call(
+				jen.Id("lifetime").Op("-").Min(jen.Id("lifetime").Op("/").Lit(2), jen.Id("tokenRefreshMargin")),
+			),
+		),
+		jen.Qual("log/slog", "Debug").Call(jen.Lit("Fetched access token"), jen.Lit("expires_in"), jen.Id("token").Dot("ExpiresIn")),
+		jen.Return(jen.Id("s").Dot("token"), jen.Nil()),
+	)
+
+	f.Comment("drop forgets the cached access token when it is the given one, rejected by the API, and")
+	f.Comment("reports whether the token was sent by this source")
+	f.Func().Params(jen.Id("s").Op("*").Id("tokenSource")).Id("drop").Params(
+		jen.Id("token").String(),
+	).Bool().Block(
+		jen.Id("s").Dot("mu").Dot("Lock").Call(),
+		jen.Defer().Id("s").Dot("mu").Dot("Unlock").Call(),
+		jen.If(jen.Id("token").Op("!=").Lit("").Op("&&").Id("s").Dot("token").Op("==").Id("token")).Block(
+			jen.Id("s").Dot("token").Op("=").Lit(""),
+			jen.Id("s").Dot("rejected").Op("=").Id("token"),
+		),
+		jen.Return(jen.Id("token").Op("!=").Lit("").Op("&&").Id("s").Dot("rejected").Op("==").Id("token")),
+	)
+
+	f.Comment("tokenDoer sends the requests whose access token the API rejects once more with a new token")
+	f.Type().Id("tokenDoer").Struct(
+		jen.Id("doer").Qual(CLI.ClientImport, "HttpRequestDoer"),
+		jen.Id("sources").Index().Op("*").Id("tokenSource"),
+	)
+
+	f.Comment("Do sends the request and, on a 401 status, drops the access token it carried and sends it")
+	f.Comment("again with a new token, unless its body cannot be sent again")
+	f.Func().Params(jen.Id("d").Op("*").Id("tokenDoer")).Id("Do").Params(
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Params(jen.Op("*").Qual("net/http", "Response"), jen.Error()).Block(
+		jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("d").Dot("doer").Dot("Do").Call(jen.Id("req")),
+		jen.If(jen.Err().Op("!=").Nil().Op("||").Id("resp").Dot("StatusCode").Op("!=").Qual("net/http", "StatusUnauthorized")).Block(
+			jen.Return(jen.Id("resp"), jen.Err()),
+		),
+		jen.List(jen.Id("token"), jen.Id("ok")).Op(":=").Qual("strings", "CutPrefix").Call(jen.Id("req").Dot("Header").Dot("Get").Call(jen.Lit("Authorization")), jen.Lit("Bearer ")),
+		jen.If(jen.Op("!").Id("ok").Op("||").Parens(jen.Id("req").Dot("Body").Op("!=").Nil().Op("&&").Id("req").Dot("GetBody").Op("==").Nil())).Block(
+			jen.Return(jen.Id("resp"), jen.Nil()),
+		),
+		jen.For(jen.List(jen.Id("_"), jen.Id("source")).Op(":=").Range().Id("d").Dot("sources")).Block(
+			jen.If(jen.Op("!").Id("source").Dot("drop").Call(jen.Id("token"))).Block(
+				jen.Continue(),
+			),
+			jen.Id("retry").Op(":=").Id("req").Dot("Clone").Call(jen.Id("req").Dot("Context").Call()),
+			jen.If(jen.Id("req").Dot("GetBody").Op("!=").Nil()).Block(
+				jen.List(jen.Id("body"), jen.Id("bodyErr")).Op(":=").Id("req").Dot("GetBody").Call(),
+				jen.If(jen.Id("bodyErr").Op("!=").Nil()).Block(
+					jen.Return(jen.Id("resp"), jen.Nil()),
+				),
+				jen.Id("retry").Dot("Body").Op("=").Id("body"),
+			),
+			jen.If(jen.Id("source").Dot("Intercept").Call(jen.Id("req").Dot("Context").Call(), jen.Id("retry")).Op("!=").Nil()).Block(
+				jen.Return(jen.Id("resp"), jen.Nil()),
+			),
+			jen.Id("resp").Dot("Body").Dot("Close").Call(),
+			jen.Qual("log/slog", "Info").Call(jen.Lit("Access token rejected by the API, retrying with a new one"), jen.Lit("url"), jen.Id("req").Dot("URL").Dot("Redacted").Call()),
+			jen.Return(jen.Id("d").Dot("doer").Dot("Do").Call(jen.Id("retry"))),
+		),
+		jen.Return(jen.Id("resp"), jen.Nil()),
+	)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTokenSource(t *testing.T) {
+	var fetches atomic.Int32
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		n := fetches.Add(1)
+		respond(http.StatusOK, "application/json", fmt.Sprintf(`{"access_token":"token-%d","expires_in":3600}`, n))(w, r)
+	}))
+	defer tokens.Close()
+
+	// The API accepts a single token, revoking the previous ones
+	var valid atomic.Value
+	valid.Store("Bearer token-1")
+	authorizations := make(chan string, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Header.Get("Authorization")
+		switch {
+		case r.Header.Get("Authorization") != valid.Load():
+			respond(http.StatusUnauthorized, "text/plain", "token revoked")(w, r)
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), "Dune") {
+				respond(http.StatusBadRequest, "text/plain", "no book")(w, r)
+				return
+			}
+			respond(http.StatusOK, "application/json", `{"Id":1,"Name":"Dune"}`)(w, r)
+		default:
+			respond(http.StatusOK, "application/json", `[]`)(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--auth-type", "oauth2", "--token-url", "http://127.0.0.1/token")
+	session := startServer(t, binary, []string{"API_CLIENT_ID=client", "API_CLIENT_SECRET=s3cret"},
+		"--host", upstream.URL, "--token-url", tokens.URL)
+
+	// The cached token is used by all the calls
+	for range 3 {
+		if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+			t.Fatalf("ListBooks failed: %s", result.text())
+		}
+		if got := <-authorizations; got != "Bearer token-1" {
+			t.Errorf("ListBooks was called with %q, want the first token", got)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("the token endpoint was called %d times, want once", got)
+	}
+
+	// A rejected token is replaced and the request sent again, with its body
+	valid.Store("Bearer token-2")
+	if result := session.callTool(t, "AddBook", map[string]any{"Name": "Dune"}); result.IsError {
+		t.Fatalf("AddBook failed: %s", result.text())
+	}
+	if got, want := []string{<-authorizations, <-authorizations}, "Bearer token-1,Bearer token-2"; strings.Join(got, ",") != want {
+		t.Errorf("AddBook was called with %v, want %s", got, want)
+	}
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	if got := <-authorizations; got != "Bearer token-2" {
+		t.Errorf("ListBooks was called with %q, want the new token", got)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("the token endpoint was called %d times, want twice", got)
+	}
+}
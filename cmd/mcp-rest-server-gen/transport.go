@@ -0,0 +1,152 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// httpDoerCode returns the statements building the httpDoer used by the REST
+// client, or nothing when the default HTTP client is enough
+func httpDoerCode(f *jen.File) []jen.Code {
+	limitRead := CLI.MaxReadBytes > 0 && !readsWholeBody()
+	if !limitRead && CLI.TLSServerName == "" && CLI.FallbackHost == "" {
+		return nil
+	}
+
+	code := []jen.Code{
+		jen.Var().Id("httpDoer").Qual(CLI.ClientImport, "HttpRequestDoer").Op("=").Qual("net/http", "DefaultClient"),
+	}
+
+	// Connections reached by IP still need the expected name for SNI and
+	// certificate verification
+	if CLI.TLSServerName != "" {
+		code = append(code,
+			jen.Id("transport").Op(":=").Qual("net/http", "DefaultTransport").Assert(jen.Op("*").Qual("net/http", "Transport")).Dot("Clone").Call(),
+			jen.Id("transport").Dot("TLSClientConfig").Op("=").Op("&").Qual("crypto/tls", "Config").Values(jen.Dict{
+				jen.Id("ServerName"): jen.Id("cli").Dot("TLSServerName"),
+			}),
+			jen.Id("httpDoer").Op("=").Op("&").Qual("net/http", "Client").Values(jen.Dict{
+				jen.Id("Transport"): jen.Id("transport"),
+			}),
+		)
+	}
+
+	// The fallback host gets a single attempt, through the default client as
+	// the TLS server name is the one of the primary host
+	if CLI.FallbackHost != "" {
+		addFailoverDoer(f)
+		code = append(code,
+			jen.If(jen.Id("cli").Dot("FallbackHost").Op("!=").Lit("")).Block(
+				jen.Id("httpDoer").Op("=").Op("&").Id("failoverDoer").Values(jen.Dict{
+					jen.Id("primary"):      jen.Id("httpDoer"),
+					jen.Id("fallback"):     jen.Qual("net/http", "DefaultClient"),
+					jen.Id("primaryHost"):  jen.Qual("strings", "TrimSuffix").Call(jen.Id("cli").Dot("Host"), jen.Lit("/")),
+					jen.Id("fallbackHost"): jen.Qual("strings", "TrimSuffix").Call(jen.Id("cli").Dot("FallbackHost"), jen.Lit("/")),
+				}),
+			),
+		)
+	}
+
+	// The tail and smart truncations need the whole body
+	if limitRead {
+		addLimitedBodyDoer(f)
+		code = append(code,
+			jen.Id("httpDoer").Op("=").Op("&").Id("limitedBodyDoer").Values(jen.Dict{
+				jen.Id("doer"):  jen.Id("httpDoer"),
+				jen.Id("limit"): jen.Id("cli").Dot("MaxReadBytes"),
+			}),
+		)
+	}
+
+	return code
+}
+
+// addLimitedBodyDoer adds the HTTP doer capping the response body size
+func addLimitedBodyDoer(f *jen.File) {
+	f.Comment("truncatedHeader marks the responses whose body was cut by limitedBodyDoer")
+	f.Const().Id("truncatedHeader").Op("=").Lit("X-Mcp-Rest-Truncated")
+
+	f.Comment("limitedBodyDoer caps the size of the response bodies read from the API,")
+	f.Comment("truncated responses become plain text so the client does not try to decode them")
+	f.Type().Id("limitedBodyDoer").Struct(
+		jen.Id("doer").Qual(CLI.ClientImport, "HttpRequestDoer"),
+		jen.Id("limit").Int64(),
+	)
+
+	f.Comment("Do sends the request and reads at most limit bytes of the response body")
+	f.Func().Params(jen.Id("d").Op("*").Id("limitedBodyDoer")).Id("Do").Params(
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Params(jen.Op("*").Qual("net/http", "Response"), jen.Error()).Block(
+		jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("d").Dot("doer").Dot("Do").Call(jen.Id("req")),
+		jen.If(jen.Err().Op("!=").Nil().Op("||").Id("d").Dot("limit").Op("<=").Lit(0)).Block(
+			jen.Return(jen.Id("resp"), jen.Err()),
+		),
+		jen.Defer().Id("resp").Dot("Body").Dot("Close").Call(),
+
+		jen.List(jen.Id("body"), jen.Err()).Op(":=").Qual("io", "ReadAll").Call(
+			jen.Qual("io", "LimitReader").Call(jen.Id("resp").Dot("Body"), jen.Id("d").Dot("limit").Op("+").Lit(1)),
+		),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		),
+		jen.If(jen.Int64().Call(jen.Len(jen.Id("body"))).Op(">").Id("d").Dot("limit")).Block(
+			jen.Id("body").Op("=").Id("body").Index(jen.Op(":").Id("d").Dot("limit")),
+			jen.Id("resp").Dot("Header").Dot("Set").Call(jen.Id("truncatedHeader"), jen.Qual("strconv", "FormatInt").Call(jen.Id("d").Dot("limit"), jen.Lit(10))),
+			jen.Id("resp").Dot("Header").Dot("Set").Call(jen.Lit("Content-Type"), jen.Lit("text/plain; charset=utf-8")),
+		),
+		jen.Id("resp").Dot("Body").Op("=").Qual("io", "NopCloser").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("body"))),
+		jen.Return(jen.Id("resp"), jen.Nil()),
+	)
+}
+
+// addFailoverDoer adds the HTTP doer sending the requests that fail on the
+// primary host to the fallback host
+func addFailoverDoer(f *jen.File) {
+	f.Comment("failoverDoer sends the requests failing on the primary host, with a network error or a")
+	f.Comment("5xx status, once more to the fallback host")
+	f.Type().Id("failoverDoer").Struct(
+		jen.List(jen.Id("primary"), jen.Id("fallback")).Qual(CLI.ClientImport, "HttpRequestDoer"),
+		jen.List(jen.Id("primaryHost"), jen.Id("fallbackHost")).String(),
+	)
+
+	f.Comment("Do sends the request to the primary host and, when it fails, to the fallback host,")
+	f.Comment("requests not addressed to the primary host or whose body cannot be sent again are not")
+	f.Comment("sent to the fallback host")
+	f.Func().Params(jen.Id("d").Op("*").Id("failoverDoer")).Id("Do").Params(
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+	).Params(jen.Op("*").Qual("net/http", "Response"), jen.Error()).Block(
+		jen.List(jen.Id("resp"), jen.Err()).Op(":=").Id("d").Dot("primary").Dot("Do").Call(jen.Id("req")),
+		jen.If(jen.Err().Op("==").Nil().Op("&&").Id("resp").Dot("StatusCode").Op("<").Lit(500)).Block(
+			jen.Return(jen.Id("resp"), jen.Nil()),
+		),
+		jen.If(jen.Id("req").Dot("Context").Call().Dot("Err").Call().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("resp"), jen.Err()),
+		),
+
+		jen.List(jen.Id("path"), jen.Id("ok")).Op(":=").Qual("strings", "CutPrefix").Call(jen.Id("req").Dot("URL").Dot("String").Call(), jen.Id("d").Dot("primaryHost")),
+		jen.If(jen.Op("!").Id("ok").Op("||").Parens(jen.Id("req").Dot("Body").Op("!=").Nil().Op("&&").Id("req").Dot("GetBody").Op("==").Nil())).Block(
+			jen.Return(jen.Id("resp"), jen.Err()),
+		),
+		jen.List(jen.Id("fallbackURL"), jen.Id("urlErr")).Op(":=").Qual("net/url", "Parse").Call(jen.Id("d").Dot("fallbackHost").Op("+").Id("path")),
+		jen.If(jen.Id("urlErr").Op("!=").Nil()).Block(
+			jen.Return(jen.Id("resp"), jen.Err()),
+		),
+		jen.Id("fallbackReq").Op(":=").Id("req").Dot("Clone").Call(jen.Id("req").Dot("Context").Call()),
+		jen.Id("fallbackReq").Dot("URL").Op("=").Id("fallbackURL"),
+		jen.Id("fallbackReq").Dot("Host").Op("=").Lit(""),
+		jen.If(jen.Id("req").Dot("GetBody").Op("!=").Nil()).Block(
+			jen.List(jen.Id("body"), jen.Id("bodyErr")).Op(":=").Id("req").Dot("GetBody").Call(),
+			jen.If(jen.Id("bodyErr").Op("!=").Nil()).Block(
+				jen.Return(jen.Id("resp"), jen.Err()),
+			),
+			jen.Id("fallbackReq").Dot("Body").Op("=").Id("body"),
+		),
+
+		jen.Id("reason").Op(":=").Qual("fmt", "Sprint").Call(jen.Err()),
+		jen.If(jen.Err().Op("==").Nil()).Block(
+			jen.Id("reason").Op("=").Id("resp").Dot("Status"),
+			jen.Id("resp").Dot("Body").Dot("Close").Call(),
+		),
+		jen.Qual("log/slog", "Warn").Call(jen.Lit("Primary host failed, calling the fallback host"), jen.Lit("url"), jen.Id("fallbackURL").Dot("Redacted").Call(), jen.Lit("reason"), jen.Id("reason")),
+		jen.Return(jen.Id("d").Dot("fallback").Dot("Do").Call(jen.Id("fallbackReq"))),
+	)
+}
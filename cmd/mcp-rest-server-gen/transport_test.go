@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxReadBytes(t *testing.T) {
+	body := `["` + strings.Repeat("a", 1<<20) + `"]`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("NameFilter") == "small" {
+			respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`)(w, r)
+			return
+		}
+		respond(http.StatusOK, "application/json", body)(w, r)
+	}))
+	defer upstream.Close()
+
+	binary := buildServer(t, booksSpec, "--max-read-bytes", "100")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+	result := session.callTool(t, "ListBooks", map[string]any{})
+	if want := body[:100] + "\n[response truncated to 100 bytes]"; result.text() != want {
+		t.Errorf("the oversized response is %d bytes: %.150q", len(result.text()), result.text())
+	}
+
+	// The responses under the limit are returned whole
+	result = session.callTool(t, "ListBooks", map[string]any{"NameFilter": "small"})
+	if want := `[{"Id":1,"Name":"Dune"}]`; result.text() != want {
+		t.Errorf("the small response is %q, want %q", result.text(), want)
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	code := generate(t, booksSpec, "--tls-server-name", "books.internal")
+	assertContains(t, code,
+		`TLSServerName string `+"`"+`default:"books.internal"`,
+		`transport.TLSClientConfig = &tls.Config{ServerName: cli.TLSServerName}`,
+	)
+
+	serverNames := make(chan string, 1)
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverNames <- r.TLS.ServerName
+		respond(http.StatusOK, "application/json", `[]`)(w, r)
+	}))
+	defer upstream.Close()
+
+	// The certificate of the test server is valid for example.com, it is
+	// trusted through the certificate file read by the server
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw})
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binary := buildServer(t, booksSpec, "--tls-server-name", "example.com")
+	session := startServer(t, binary, []string{"SSL_CERT_FILE=" + certFile}, "--host", upstream.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError {
+		t.Fatalf("ListBooks failed: %s", result.text())
+	}
+	if got := <-serverNames; got != "example.com" {
+		t.Errorf("the server name is %q, want example.com", got)
+	}
+}
+
+// failoverDoerTest is compiled with the generated server, checking which
+// requests its failover doer sends again to the fallback host
+const failoverDoerTest = `package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFailoverDoer(t *testing.T) {
+	var fallbackBodies []string
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fallbackBodies = append(fallbackBodies, r.Method+" "+r.URL.Path+" "+string(body))
+	}))
+	defer fallback.Close()
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	doer := func(host string) *failoverDoer {
+		return &failoverDoer{primary: http.DefaultClient, fallback: http.DefaultClient, primaryHost: host, fallbackHost: fallback.URL}
+	}
+
+	// The 5xx statuses and the network errors are sent once to the fallback host
+	for _, host := range []string{primary.URL, closed.URL} {
+		req, _ := http.NewRequest(http.MethodPost, host+"/books", strings.NewReader("Dune"))
+		resp, err := doer(host).Do(req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("the request to %s failed over with %v, %v", host, resp, err)
+		}
+		resp.Body.Close()
+	}
+	if got := strings.Join(fallbackBodies, ", "); got != "POST /books Dune, POST /books Dune" {
+		t.Errorf("the fallback host received %q", got)
+	}
+
+	// The bodies that cannot be read again are not sent twice
+	req, _ := http.NewRequest(http.MethodPost, primary.URL+"/books", io.NopCloser(strings.NewReader("Emma")))
+	resp, err := doer(primary.URL).Do(req)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("the request without GetBody returned %v, %v", resp, err)
+	}
+	resp.Body.Close()
+	if len(fallbackBodies) != 2 {
+		t.Errorf("the request without GetBody was sent to the fallback host: %q", fallbackBodies)
+	}
+}
+`
+
+func TestFallbackHost(t *testing.T) {
+	code := generate(t, booksSpec, "--fallback-host", "https://backup.example.com")
+	assertContains(t, code, `FallbackHost string `+"`"+`default:"https://backup.example.com"`)
+	assertNotContains(t, generate(t, booksSpec), "failoverDoer")
+
+	fallback := httptest.NewServer(respond(http.StatusOK, "application/json", `[{"Id":1,"Name":"Dune"}]`))
+	defer fallback.Close()
+	primary := httptest.NewServer(respond(http.StatusBadGateway, "text/plain", "down"))
+	defer primary.Close()
+
+	binary := buildServer(t, booksSpec, "--fallback-host", fallback.URL)
+	dir := filepath.Dir(binary)
+	if err := os.WriteFile(filepath.Join(dir, "failover_test.go"), []byte(failoverDoerTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	test := exec.Command("go", "test", "-run", "TestFailoverDoer", ".")
+	test.Dir = dir
+	test.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if output, err := test.CombinedOutput(); err != nil {
+		t.Errorf("testing the generated failover doer: %v\n%s", err, output)
+	}
+
+	session := startServer(t, binary, nil, "--host", primary.URL)
+	if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError || !strings.Contains(result.text(), "Dune") {
+		t.Errorf("ListBooks returned %+v", result)
+	}
+	session.waitForLog(t, "Primary host failed, calling the fallback host")
+
+	// An empty fallback host disables the failover
+	session = startServer(t, binary, nil, "--host", primary.URL, "--fallback-host", "")
+	if result := session.callTool(t, "ListBooks", map[string]any{}); !result.IsError {
+		t.Errorf("ListBooks without fallback host returned %+v", result)
+	}
+}
+
+func TestTruncationStrategy(t *testing.T) {
+	titles := make([]string, 8)
+	for i := range titles {
+		titles[i] = fmt.Sprintf(`"Book %02d with a long title"`, i)
+	}
+	body := "[" + strings.Join(titles, ",") + "]"
+	upstream := httptest.NewServer(respond(http.StatusOK, "application/json", body))
+	defer upstream.Close()
+
+	tests := []struct {
+		strategy, want string
+	}{
+		{"head", body[:100] + "\n[response truncated to 100 bytes]"},
+		{"tail", "[response truncated to its last 100 bytes]\n" + body[len(body)-100:]},
+		// The arrays are halved until the body fits
+		{"smart", "[" + titles[0] + "," + titles[1] + `,"[6 more items]"]` + "\n[response truncated to 100 bytes]"},
+	}
+	for _, test := range tests {
+		t.Run(test.strategy, func(t *testing.T) {
+			binary := buildServer(t, "testdata/pages.yaml", "--max-read-bytes", "100", "--truncation-strategy", test.strategy)
+			session := startServer(t, binary, nil, "--host", upstream.URL)
+			if result := session.callTool(t, "ListBooks", map[string]any{}); result.IsError || result.text() != test.want {
+				t.Errorf("the truncated response is %q, want %q", result.text(), test.want)
+			}
+
+			// The bodies under the limit are returned whole
+			session = startServer(t, binary, nil, "--host", upstream.URL, "--max-read-bytes", "1000")
+			if result := session.callTool(t, "ListBooks", map[string]any{}); result.text() != body {
+				t.Errorf("the response under the limit is %q, want %q", result.text(), body)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// readsWholeBody reports whether the response bodies are read whole and
+// truncated for the tool results instead of being cut while read from the API
+func readsWholeBody() bool {
+	return CLI.TruncationStrategy == "tail" || CLI.TruncationStrategy == "smart"
+}
+
+// truncationCode returns the body steps truncating the response body over the
+// limit with the strategy, or noting the truncation made when it was read
+func truncationCode() []jen.Code {
+	limit := jen.Id("cli").Dot("MaxReadBytes")
+	switch CLI.TruncationStrategy {
+	case "tail":
+		return []jen.Code{
+			jen.If(jen.List(jen.Id("tail"), jen.Id("cut")).Op(":=").Id("truncateTail").Call(jen.Id("body"), limit), jen.Id("cut")).Block(
+				jen.Id("body").Op("=").Append(
+					jen.Index().Byte().Call(jen.Qual("fmt", "Sprintf").Call(jen.Lit("[response truncated to its last %d bytes]\n"), limit.Clone())),
+					jen.Id("tail").Op("..."),
+				),
+			),
+		}
+	case "smart":
+		return []jen.Code{
+			jen.If(jen.List(jen.Id("short"), jen.Id("cut")).Op(":=").Id("truncateSmart").Call(jen.Id("body"), limit), jen.Id("cut")).Block(
+				jen.Id("body").Op("=").Append(
+					jen.Id("short"),
+					jen.Qual("fmt", "Sprintf").Call(jen.Lit("\n[response truncated to %d bytes]"), limit.Clone()).Op("..."),
+				),
+			),
+		}
+	}
+	return []jen.Code{
+		jen.If(jen.Id("resp").Dot("HTTPResponse").Dot("Header").Dot("Get").Call(jen.Id("truncatedHeader")).Op("!=").Lit("")).Block(
+			jen.Id("body").Op("=").Append(
+				jen.Id("body"),
+				jen.Qual("fmt", "Sprintf").Call(jen.Lit("\n[response truncated to %d bytes]"), limit.Clone()).Op("..."),
+			),
+		),
+	}
+}
+
+// addTruncateTail adds the function keeping the end of the bodies over the
+// limit
+func addTruncateTail(f *jen.File) {
+	f.Comment("truncateTail returns the last limit bytes of the body and whether it was cut, a limit")
+	f.Comment("of 0 keeps the whole body")
+	f.Func().Id("truncateTail").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("limit").Int64(),
+	).Params(jen.Index().Byte(), jen.Bool()).Block(
+		jen.If(jen.Id("limit").Op("<=").Lit(0).Op("||").Int64().Call(jen.Len(jen.Id("body"))).Op("<=").Id("limit")).Block(
+			jen.Return(jen.Id("body"), jen.False()),
+		),
+		jen.Id("tail").Op(":=").Id("body").Index(jen.Int64().Call(jen.Len(jen.Id("body"))).Op("-").Id("limit").Op(":")),
+		jen.Return(jen.Index().Byte().Call(jen.Qual("strings", "ToValidUTF8").Call(jen.String().Call(jen.Id("tail")), jen.Lit(""))), jen.True()),
+	)
+}
+
+// addTruncateSmart adds the functions shortening the arrays of the JSON
+// bodies over the limit
+func addTruncateSmart(f *jen.File) {
+	f.Comment("truncateSmart returns the body under limit bytes and whether it was cut. The arrays of")
+	f.Comment("JSON bodies keep their first items, halving them until the body fits, with the count of")
+	f.Comment("the items left out in their place, so the structure of the body is kept. Bodies that")
+	f.Comment("are not JSON or do not fit this way are cut after limit bytes. A limit of 0 keeps the")
+	f.Comment("whole body")
+	f.Func().Id("truncateSmart").Params(
+		jen.Id("body").Index().Byte(),
+		jen.Id("limit").Int64(),
+	).Params(jen.Index().Byte(), jen.Bool()).Block(
+		jen.If(jen.Id("limit").Op("<=").Lit(0).Op("||").Int64().Call(jen.Len(jen.Id("body"))).Op("<=").Id("limit")).Block(
+			jen.Return(jen.Id("body"), jen.False()),
+		),
+		jen.Id("head").Op(":=").Index().Byte().Call(jen.Qual("strings", "ToValidUTF8").Call(jen.String().Call(jen.Id("body").Index(jen.Op(":").Id("limit"))), jen.Lit(""))),
+
+		// Numbers are decoded as written so they are encoded back unchanged
+		jen.Id("decoder").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("body"))),
+		jen.Id("decoder").Dot("UseNumber").Call(),
+		jen.Var().Id("value").Any(),
+		jen.If(jen.Err().Op(":=").Id("decoder").Dot("Decode").Call(jen.Op("&").Id("value")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Id("head"), jen.True()),
+		),
+		jen.For(jen.Id("keep").Op(":=").Id("longestArray").Call(jen.Id("value")).Op("/").Lit(2), jen.Id("keep").Op(">=").Lit(0), jen.Id("keep").Op("/=").Lit(2)).Block(
+			jen.List(jen.Id("data"), jen.Err()).Op(":=").Qual("encoding/json", "Marshal").Call(jen.Id("shortenArrays").Call(jen.Id("value"), jen.Id("keep"))),
+			jen.If(jen.Err().Op("==").Nil().Op("&&").Int64().Call(jen.Len(jen.Id("data"))).Op("<=").Id("limit")).Block(
+				jen.Return(jen.Id("data"), jen.True()),
+			),
+			jen.If(jen.Id("keep").Op("==").Lit(0)).Block(
+				jen.Break(),
+			),
+		),
+		jen.Return(jen.Id("head"), jen.True()),
+	)
+
+	f.Comment("longestArray returns the length of the longest array in the JSON value")
+	f.Func().Id("longestArray").Params(jen.Id("value").Any()).Int().Block(
+		jen.Id("longest").Op(":=").Lit(0),
+		jen.Switch(jen.Id("v").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Index().Any()).Block(
+				jen.Id("longest").Op("=").Len(jen.Id("v")),
+				jen.For(jen.List(jen.Id("_"), jen.Id("item")).Op(":=").Range().Id("v")).Block(
+					jen.Id("longest").Op("=").Max(jen.Id("longest"), jen.Id("longestArray").Call(jen.Id("item"))),
+				),
+			),
+			jen.Case(jen.Map(jen.String()).Any()).Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("child")).Op(":=").Range().Id("v")).Block(
+					jen.Id("longest").Op("=").Max(jen.Id("longest"), jen.Id("longestArray").Call(jen.Id("child"))),
+				),
+			),
+		),
+		jen.Return(jen.Id("longest")),
+	)
+
+	f.Comment("shortenArrays returns the JSON value with its arrays cut to their first keep items,")
+	f.Comment("followed by the count of the items left out")
+	f.Func().Id("shortenArrays").Params(jen.Id("value").Any(), jen.Id("keep").Int()).Any().Block(
+		jen.Switch(jen.Id("v").Op(":=").Id("value").Assert(jen.Type())).Block(
+			jen.Case(jen.Index().Any()).Block(
+				jen.Id("short").Op(":=").Make(jen.Index().Any(), jen.Lit(0), jen.Min(jen.Len(jen.Id("v")), jen.Id("keep")).Op("+").Lit(1)),
+				jen.For(jen.List(jen.Id("_"), jen.Id("item")).Op(":=").Range().Id("v").Index(jen.Op(":").Min(jen.Len(jen.Id("v")), jen.Id("keep")))).Block(
+					jen.Id("short").Op("=").Append(jen.Id("short"), jen.Id("shortenArrays").Call(jen.Id("item"), jen.Id("keep"))),
+				),
+				jen.If(jen.Len(jen.Id("v")).Op(">").Id("keep")).Block(
+					jen.Id("short").Op("=").Append(jen.Id("short"), jen.Qual("fmt", "Sprintf").Call(jen.Lit("[%d more items]"), jen.Len(jen.Id("v")).Op("-").Id("keep"))),
+				),
+				jen.Return(jen.Id("short")),
+			),
+			jen.Case(jen.Map(jen.String()).Any()).Block(
+				jen.Id("short").Op(":=").Make(jen.Map(jen.String()).Any(), jen.Len(jen.Id("v"))),
+				jen.For(jen.List(jen.Id("key"), jen.Id("child")).Op(":=").Range().Id("v")).Block(
+					jen.Id("short").Index(jen.Id("key")).Op("=").Id("shortenArrays").Call(jen.Id("child"), jen.Id("keep")),
+				),
+				jen.Return(jen.Id("short")),
+			),
+		),
+		jen.Return(jen.Id("value")),
+	)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/dave/jennifer/jen"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// isSuccessResponse reports whether the response key of the spec applies to
+// success statuses, a 2xx code, the 2XX range or the default response
+func isSuccessResponse(key string) bool {
+	return key == "default" || len(key) == 3 && key[0] == '2'
+}
+
+// hasJSONResponseSchema reports whether the operation declares a JSON schema
+// for one of its success responses, the ones the responses are validated
+// against
+func hasJSONResponseSchema(operation *openapi3.Operation) bool {
+	if operation.Responses == nil {
+		return false
+	}
+	for key, response := range operation.Responses.Map() {
+		if !isSuccessResponse(key) || response == nil || response.Value == nil {
+			continue
+		}
+		mediaType := response.Value.Content.Get("application/json")
+		if mediaType != nil && mediaType.Schema != nil && mediaType.Schema.Value != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// addValidateResponse adds the functions validating the response bodies
+// against the schemas of the embedded spec
+func addValidateResponse(f *jen.File) {
+	f.Comment("responseSchemas holds the success response schemas of the embedded spec by operation ID")
+	f.Comment("and response key, the status code, 2XX or default")
+	f.Var().Id("responseSchemas").Op("=").Id("loadResponseSchemas").Call()
+
+	f.Comment("loadResponseSchemas loads the success response schemas of the embedded spec, none when")
+	f.Comment("it cannot be loaded so the responses are not validated")
+	f.Func().Id("loadResponseSchemas").Params().Map(jen.String()).Map(jen.String()).Op("*").Qual("github.com/getkin/kin-openapi/openapi3", "Schema").Block(
+		jen.List(jen.Id("doc"), jen.Err()).Op(":=").Qual("github.com/getkin/kin-openapi/openapi3", "NewLoader").Call().Dot("LoadFromData").Call(jen.Index().Byte().Parens(jen.Id("openAPISpec"))),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Qual("log/slog", "Warn").Call(jen.Lit("responses not validated, cannot load the embedded spec"), jen.Lit("error"), jen.Err()),
+			jen.Return(jen.Nil()),
+		),
+
+		// The error messages are kept short for the tool results
+		jen.Qual("github.com/getkin/kin-openapi/openapi3", "SchemaErrorDetailsDisabled").Op("=").True(),
+		jen.Id("schemas").Op(":=").Make(jen.Map(jen.String()).Map(jen.String()).Op("*").Qual("github.com/getkin/kin-openapi/openapi3", "Schema")),
+		jen.For(jen.List(jen.Id("_"), jen.Id("pathItem")).Op(":=").Range().Id("doc").Dot("Paths").Dot("Map").Call()).Block(
+			jen.For(jen.List(jen.Id("_"), jen.Id("operation")).Op(":=").Range().Id("pathItem").Dot("Operations").Call()).Block(
+				jen.If(jen.Id("operation").Dot("Responses").Op("==").Nil()).Block(
+					jen.Continue(),
+				),
+				jen.For(jen.List(jen.Id("key"), jen.Id("response")).Op(":=").Range().Id("operation").Dot("Responses").Dot("Map").Call()).Block(
+					jen.Id("key").Op("=").Qual("strings", "ToUpper").Call(jen.Id("key")),
+					jen.If(jen.Id("key").Op("!=").Lit("DEFAULT").Op("&&").Parens(jen.Len(jen.Id("key")).Op("!=").Lit(3).Op("||").Id("key").Index(jen.Lit(0)).Op("!=").LitRune('2'))).Block(
+						jen.Continue(),
+					),
+					jen.If(jen.Id("response").Op("==").Nil().Op("||").Id("response").Dot("Value").Op("==").Nil()).Block(
+						jen.Continue(),
+					),
+					jen.If(jen.Id("mediaType").Op(":=").Id("response").Dot("Value").Dot("Content").Dot("Get").Call(jen.Lit("application/json")), jen.Id("mediaType").Op("!=").Nil().Op("&&").Id("mediaType").Dot("Schema").Op("!=").Nil()).Block(
+						jen.If(jen.Id("schemas").Index(jen.Id("operation").Dot("OperationID")).Op("==").Nil()).Block(
+							jen.Id("schemas").Index(jen.Id("operation").Dot("OperationID")).Op("=").Make(jen.Map(jen.String()).Op("*").Qual("github.com/getkin/kin-openapi/openapi3", "Schema")),
+						),
+						jen.Id("schemas").Index(jen.Id("operation").Dot("OperationID")).Index(jen.Id("key")).Op("=").Id("mediaType").Dot("Schema").Dot("Value"),
+					),
+				),
+			),
+		),
+		jen.Return(jen.Id("schemas")),
+	)
+
+	f.Comment("validateResponse validates the JSON body of a success response against the response schema")
+	f.Comment("the operation declares for its status, its 2XX range or by default, the responses without")
+	f.Comment("schema are not validated")
+	f.Func().Id("validateResponse").Params(
+		jen.Id("op").String(),
+		jen.Id("status").Int(),
+		jen.Id("body").Index().Byte(),
+	).Error().Block(
+		jen.Id("schemas").Op(":=").Id("responseSchemas").Index(jen.Id("op")),
+		jen.Id("schema").Op(":=").Id("schemas").Index(jen.Qual("strconv", "Itoa").Call(jen.Id("status"))),
+		jen.If(jen.Id("schema").Op("==").Nil()).Block(
+			jen.Id("schema").Op("=").Id("schemas").Index(jen.Lit("2XX")),
+		),
+		jen.If(jen.Id("schema").Op("==").Nil()).Block(
+			jen.Id("schema").Op("=").Id("schemas").Index(jen.Lit("DEFAULT")),
+		),
+		jen.If(jen.Id("schema").Op("==").Nil()).Block(
+			jen.Return(jen.Nil()),
+		),
+		jen.Var().Id("value").Any(),
+		jen.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("value")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Qual("fmt", "Errorf").Call(jen.Lit("response is not JSON: %w"), jen.Err())),
+		),
+		jen.Return(jen.Id("schema").Dot("VisitJSON").Call(jen.Id("value"), jen.Qual("github.com/getkin/kin-openapi/openapi3", "MultiErrors").Call())),
+	)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateResponses(t *testing.T) {
+	var handler http.HandlerFunc
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(w, r) }))
+	defer upstream.Close()
+
+	binary := buildServer(t, "testdata/validation.yaml", "--auth-type", "none", "--validate-responses")
+	session := startServer(t, binary, nil, "--host", upstream.URL)
+
+	// The body is checked against the schema of its status, its range or none
+	tests := []struct {
+		tool     string
+		status   int
+		body     string
+		mismatch bool
+	}{
+		{"AddBook", http.StatusCreated, `{"id":1,"name":"Dune"}`, false},
+		{"AddBook", http.StatusCreated, `{"name":"Dune"}`, true},
+		{"AddBook", http.StatusNoContent, ``, false},
+		{"ListBooks", http.StatusOK, `[{"id":1}]`, false},
+		{"ListBooks", http.StatusPartialContent, `[{"name":"Dune"}]`, true},
+	}
+	for _, test := range tests {
+		handler = respond(test.status, "application/json", test.body)
+		result := session.callTool(t, test.tool, map[string]any{})
+		if result.IsError {
+			t.Errorf("%s with a %d status failed: %s", test.tool, test.status, result.text())
+			continue
+		}
+		if got := strings.Contains(result.text(), "[response does not match the spec:"); got != test.mismatch {
+			t.Errorf("%s with a %d status and body %s returned %q, want mismatch %v", test.tool, test.status, test.body, result.text(), test.mismatch)
+		}
+	}
+}
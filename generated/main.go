@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/alecthomas/kong"
@@ -10,16 +11,33 @@ import (
 	"github.com/renato0307/go-mcp-rest/generated/api"
 	"log"
 	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
 func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 	var cli = struct {
-		Host     string `default:"https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend" help:"API server host"`
-		Username string `env:"API_USERNAME" help:"API username"`
-		Password string `env:"API_PASSWORD" help:"API password"`
+		Host         string `default:"https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend" help:"API server host"`
+		Username     string `env:"API_USERNAME" help:"API username"`
+		Password     string `env:"API_PASSWORD" help:"API password"`
+		MaxErrorBody int    `default:"2048" help:"Maximum number of response body bytes included in the errors of the failed calls"`
 	}{}
 	kong.Parse(&cli)
-	done := make(chan struct{})
+	serverCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var missingCredentials []string
+	if cli.Username == "" {
+		missingCredentials = append(missingCredentials, "API_USERNAME")
+	}
+	if cli.Password == "" {
+		missingCredentials = append(missingCredentials, "API_PASSWORD")
+	}
+	if len(missingCredentials) > 0 {
+		log.Fatalf("missing API credentials, set %s", strings.Join(missingCredentials, ", "))
+	}
 	basicAuth, err := securityprovider.NewSecurityProviderBasicAuth(cli.Username, cli.Password)
 	if err != nil {
 		log.Fatal(err)
@@ -30,12 +48,12 @@ func main() {
 	}
 	server := mcp_golang.NewServer(stdio.NewStdioServerTransport())
 	err = server.RegisterTool("AddBook", "Adds a new book", func(arguments api.AddBookJSONRequestBody) (*mcp_golang.ToolResponse, error) {
-		resp, err := restClient.AddBookWithResponse(context.TODO(), arguments)
+		resp, err := restClient.AddBookWithResponse(serverCtx, arguments)
 		if err != nil {
 			return nil, fmt.Errorf("error calling AddBook: %v", err)
 		}
-		if resp.StatusCode() != 200 {
-			return nil, fmt.Errorf("error on AddBook: %s", resp.Status())
+		if !successStatus(resp.StatusCode()) {
+			return nil, fmt.Errorf("error on AddBook: %s%s", resp.Status(), errorDetail(resp.Body, cli.MaxErrorBody))
 		}
 		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(resp.Body))), nil
 	})
@@ -43,12 +61,12 @@ func main() {
 		panic(err)
 	}
 	err = server.RegisterTool("ListBooks", "Lists books filtering by name.", func(arguments api.ListBooksParams) (*mcp_golang.ToolResponse, error) {
-		resp, err := restClient.ListBooksWithResponse(context.TODO(), &arguments)
+		resp, err := restClient.ListBooksWithResponse(serverCtx, &arguments)
 		if err != nil {
 			return nil, fmt.Errorf("error calling ListBooks: %v", err)
 		}
-		if resp.StatusCode() != 200 {
-			return nil, fmt.Errorf("error on ListBooks: %s", resp.Status())
+		if !successStatus(resp.StatusCode()) {
+			return nil, fmt.Errorf("error on ListBooks: %s%s", resp.Status(), errorDetail(resp.Body, cli.MaxErrorBody))
 		}
 		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(resp.Body))), nil
 	})
@@ -60,5 +78,24 @@ func main() {
 		panic(err)
 	}
 	slog.Info("Server started")
-	<-done
+	<-serverCtx.Done()
+	slog.Info("Server stopped")
+}
+
+// errorDetail returns the response body appended to the error of a failed call, trimmed
+// to the limit, empty when the response has no body
+func errorDetail(body []byte, limit int) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	if len(body) > limit {
+		return ": " + strings.ToValidUTF8(string(body[:limit]), "") + " [truncated]"
+	}
+	return ": " + string(body)
+}
+
+// successStatus reports whether the status code of an API response is a success
+func successStatus(status int) bool {
+	return status >= 200 && status <= 299
 }
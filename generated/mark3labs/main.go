@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/kong"
+	jsonschema "github.com/invopop/jsonschema"
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider"
+	"github.com/renato0307/go-mcp-rest/generated/api"
+	"log"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	var cli = struct {
+		Host         string `default:"https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend" help:"API server host"`
+		Username     string `env:"API_USERNAME" help:"API username"`
+		Password     string `env:"API_PASSWORD" help:"API password"`
+		MaxErrorBody int    `default:"2048" help:"Maximum number of response body bytes included in the errors of the failed calls"`
+	}{}
+	kong.Parse(&cli)
+	serverCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var missingCredentials []string
+	if cli.Username == "" {
+		missingCredentials = append(missingCredentials, "API_USERNAME")
+	}
+	if cli.Password == "" {
+		missingCredentials = append(missingCredentials, "API_PASSWORD")
+	}
+	if len(missingCredentials) > 0 {
+		log.Fatalf("missing API credentials, set %s", strings.Join(missingCredentials, ", "))
+	}
+	basicAuth, err := securityprovider.NewSecurityProviderBasicAuth(cli.Username, cli.Password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	restClient, err := api.NewClientWithResponses(cli.Host, api.WithRequestEditorFn(basicAuth.Intercept))
+	if err != nil {
+		panic(err)
+	}
+	server := mcpserver.NewMCPServer("Backend", "1.0", mcpserver.WithToolCapabilities(false))
+	server.AddTool(mcp.NewToolWithRawSchema("AddBook", "Adds a new book", inputSchema[api.AddBookJSONRequestBody]()), toolHandler(func(arguments api.AddBookJSONRequestBody) (*mcp.CallToolResult, error) {
+		resp, err := restClient.AddBookWithResponse(serverCtx, arguments)
+		if err != nil {
+			return nil, fmt.Errorf("error calling AddBook: %v", err)
+		}
+		if !successStatus(resp.StatusCode()) {
+			return nil, fmt.Errorf("error on AddBook: %s%s", resp.Status(), errorDetail(resp.Body, cli.MaxErrorBody))
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(resp.Body))}}, nil
+	}))
+	server.AddTool(mcp.NewTool("ListBooks", mcp.WithDescription("Lists books filtering by name."), mcp.WithString("NameFilter")), toolHandler(func(arguments api.ListBooksParams) (*mcp.CallToolResult, error) {
+		resp, err := restClient.ListBooksWithResponse(serverCtx, &arguments)
+		if err != nil {
+			return nil, fmt.Errorf("error calling ListBooks: %v", err)
+		}
+		if !successStatus(resp.StatusCode()) {
+			return nil, fmt.Errorf("error on ListBooks: %s%s", resp.Status(), errorDetail(resp.Body, cli.MaxErrorBody))
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(resp.Body))}}, nil
+	}))
+	go func() {
+		if err := mcpserver.NewStdioServer(server).Listen(serverCtx, os.Stdin, os.Stdout); err != nil && serverCtx.Err() == nil {
+			slog.Error("Error serving MCP", "error", err)
+		}
+		stop()
+	}()
+	slog.Info("Server started")
+	<-serverCtx.Done()
+	slog.Info("Server stopped")
+}
+
+// toolHandler adapts a handler taking the decoded tool arguments to a mark3labs tool
+// handler, the errors of the handler are returned as tool errors
+func toolHandler[T any](handler func(T) (*mcp.CallToolResult, error)) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var arguments T
+		if err := request.BindArguments(&arguments); err != nil {
+			return mcp.NewToolResultError("invalid arguments: " + err.Error()), nil
+		}
+		result, err := handler(arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return result, nil
+	}
+}
+
+// inputSchema returns the JSON schema of the tool arguments type, reflected from the
+// jsonschema tags of its fields
+func inputSchema[T any]() json.RawMessage {
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties:  true,
+		Anonymous:                  true,
+		DoNotReference:             true,
+		ExpandedStruct:             true,
+		RequiredFromJSONSchemaTags: true,
+	}
+	schema, err := json.Marshal(reflector.ReflectFromType(reflect.TypeFor[T]()))
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// argumentSchema returns a property option giving a tool argument the schema reflected
+// from its field of the arguments type
+func argumentSchema[T any](name string) mcp.PropertyOption {
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(inputSchema[T](), &schema); err != nil {
+		panic(err)
+	}
+	return func(property map[string]any) {
+		maps.Copy(property, schema.Properties[name])
+	}
+}
+
+// errorDetail returns the response body appended to the error of a failed call, trimmed
+// to the limit, empty when the response has no body
+func errorDetail(body []byte, limit int) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	if len(body) > limit {
+		return ": " + strings.ToValidUTF8(string(body[:limit]), "") + " [truncated]"
+	}
+	return ": " + string(body)
+}
+
+// successStatus reports whether the status code of an API response is a success
+func successStatus(status int) bool {
+	return status >= 200 && status <= 299
+}
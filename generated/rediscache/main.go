@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alecthomas/kong"
+	"github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/stdio"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider"
+	"github.com/redis/go-redis/v9"
+	"github.com/renato0307/go-mcp-rest/generated/api"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// resultCache stores the encoded tool results by key, it must be safe for concurrent use
+type resultCache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// cacheEntry is a result cached in memory until it expires
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// memoryCache is the result cache of a single server
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// newMemoryCache returns an empty in-memory result cache
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[string]cacheEntry{}}
+}
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+func (c *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{
+		expires: now.Add(ttl),
+		value:   value,
+	}
+}
+
+// redisCache is the result cache shared by the servers using the same Redis server, its
+// failures are logged and treated as cache misses
+type redisCache struct {
+	client *redis.Client
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Warn("Could not read the result cache", "error", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if err := c.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		slog.Warn("Could not write the result cache", "error", err)
+	}
+}
+
+// newResultCache returns the cache shared through the Redis server at the URL, or an
+// in-memory cache when the URL is empty
+func newResultCache(redisURL string) (resultCache, error) {
+	if redisURL == "" {
+		return newMemoryCache(), nil
+	}
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &redisCache{client: redis.NewClient(options)}, nil
+}
+
+// credentialIdentity returns the hash of the credentials, keeping the results cached for
+// some credentials from the callers of others without storing them
+func credentialIdentity(credentials ...string) string {
+	hash := sha256.New()
+	for _, credential := range credentials {
+		hash.Write([]byte(credential))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	var cli = struct {
+		Host         string        `default:"https://eng-test-us-01-dev.outsystems.app/MCPBackend/rest/Backend" help:"API server host"`
+		Username     string        `env:"API_USERNAME" help:"API username"`
+		Password     string        `env:"API_PASSWORD" help:"API password"`
+		MaxErrorBody int           `default:"2048" help:"Maximum number of response body bytes included in the errors of the failed calls"`
+		CacheTTL     time.Duration `default:"1m0s" help:"Time the successful GET tool results are cached, 0 to disable"`
+		RedisURL     string        `env:"API_REDIS_URL" help:"URL of the Redis server sharing the cached results between replicas, such as redis://localhost:6379/0, the results are cached in memory when empty"`
+	}{}
+	kong.Parse(&cli)
+	serverCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var missingCredentials []string
+	if cli.Username == "" {
+		missingCredentials = append(missingCredentials, "API_USERNAME")
+	}
+	if cli.Password == "" {
+		missingCredentials = append(missingCredentials, "API_PASSWORD")
+	}
+	if len(missingCredentials) > 0 {
+		log.Fatalf("missing API credentials, set %s", strings.Join(missingCredentials, ", "))
+	}
+	basicAuth, err := securityprovider.NewSecurityProviderBasicAuth(cli.Username, cli.Password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	restClient, err := api.NewClientWithResponses(cli.Host, api.WithRequestEditorFn(basicAuth.Intercept))
+	if err != nil {
+		panic(err)
+	}
+	server := mcp_golang.NewServer(stdio.NewStdioServerTransport())
+	cache, err := newResultCache(cli.RedisURL)
+	if err != nil {
+		log.Fatalf("invalid Redis URL: %v", err)
+	}
+	cacheIdentity := credentialIdentity(cli.Username, cli.Password)
+	err = server.RegisterTool("AddBook", "Adds a new book", func(arguments api.AddBookJSONRequestBody) (*mcp_golang.ToolResponse, error) {
+		resp, err := restClient.AddBookWithResponse(serverCtx, arguments)
+		if err != nil {
+			return nil, fmt.Errorf("error calling AddBook: %v", err)
+		}
+		if !successStatus(resp.StatusCode()) {
+			return nil, fmt.Errorf("error on AddBook: %s%s", resp.Status(), errorDetail(resp.Body, cli.MaxErrorBody))
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(resp.Body))), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	err = server.RegisterTool("ListBooks", "Lists books filtering by name.", cacheHandler(cli.Host, func() string {
+		return cacheIdentity
+	}, "ListBooks", cache, cli.CacheTTL, func(arguments api.ListBooksParams) (*mcp_golang.ToolResponse, error) {
+		resp, err := restClient.ListBooksWithResponse(serverCtx, &arguments)
+		if err != nil {
+			return nil, fmt.Errorf("error calling ListBooks: %v", err)
+		}
+		if !successStatus(resp.StatusCode()) {
+			return nil, fmt.Errorf("error on ListBooks: %s%s", resp.Status(), errorDetail(resp.Body, cli.MaxErrorBody))
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(resp.Body))), nil
+	}))
+	if err != nil {
+		panic(err)
+	}
+	err = server.Serve()
+	if err != nil {
+		panic(err)
+	}
+	slog.Info("Server started")
+	<-serverCtx.Done()
+	slog.Info("Server stopped")
+}
+
+// errorDetail returns the response body appended to the error of a failed call, trimmed
+// to the limit, empty when the response has no body
+func errorDetail(body []byte, limit int) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	if len(body) > limit {
+		return ": " + strings.ToValidUTF8(string(body[:limit]), "") + " [truncated]"
+	}
+	return ": " + string(body)
+}
+
+// successStatus reports whether the status code of an API response is a success
+func successStatus(status int) bool {
+	return status >= 200 && status <= 299
+}
+
+// cacheHandler returns a handler answering the calls with the result cached for their
+// arguments and credentials, calling the API and caching its result for the ttl otherwise,
+// identity is nil when no credentials are sent
+func cacheHandler[T any](host string, identity func() string, operation string, cache resultCache, ttl time.Duration, handler func(T) (*mcp_golang.ToolResponse, error)) func(T) (*mcp_golang.ToolResponse, error) {
+	return func(arguments T) (*mcp_golang.ToolResponse, error) {
+		if ttl <= 0 {
+			return handler(arguments)
+		}
+		data, err := json.Marshal(arguments)
+		if err != nil {
+			return handler(arguments)
+		}
+		scope := host + " "
+		if identity != nil {
+			scope += identity() + " "
+		}
+		sum := sha256.Sum256(append([]byte(scope+operation+" "), data...))
+		key := "mcp-rest:" + operation + ":" + hex.EncodeToString(sum[:])
+		ctx := context.Background()
+		if cached, ok := cache.Get(ctx, key); ok {
+			result := new(mcp_golang.ToolResponse)
+			if err := json.Unmarshal(cached, result); err == nil {
+				slog.Debug("Call answered from the cache", "operation", operation)
+				return result, nil
+			}
+		}
+		result, err := handler(arguments)
+		if err != nil {
+			return nil, err
+		}
+		if encoded, err := json.Marshal(result); err == nil {
+			cache.Set(ctx, key, encoded, ttl)
+		}
+		return result, nil
+	}
+}
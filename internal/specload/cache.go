@@ -0,0 +1,56 @@
+package specload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CachePath returns the file caching the spec fetched from the URL, named by
+// the hash of the URL, so both generators share the cache
+func CachePath(dir, specURL string) string {
+	sum := sha256.Sum256([]byte(specURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".spec")
+}
+
+// Cached returns the content of the spec fetched from the URL and cached
+// in the directory less than ttl ago, false when there is none
+func Cached(dir, specURL string, ttl time.Duration) ([]byte, bool) {
+	if dir == "" || ttl <= 0 {
+		return nil, false
+	}
+	path := CachePath(dir, specURL)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > ttl {
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// Cache writes the content of the spec fetched from the URL to the cache
+// directory, replacing the file at once so a concurrent run never reads it
+// half written
+func Cache(dir, specURL string, content []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, "spec-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), CachePath(dir, specURL))
+}
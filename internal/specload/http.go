@@ -0,0 +1,55 @@
+package specload
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Headers returns the headers sent with the requests fetching a remote spec,
+// given as "Name: Value" values and as a bearer token
+func Headers(values []string, bearer string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, header := range values {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid spec header %q, expected Name: Value", header)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	if bearer != "" {
+		headers.Set("Authorization", "Bearer "+bearer)
+	}
+	return headers, nil
+}
+
+// headerTransport adds the spec headers to the requests sent to the host of
+// the spec, the credentials are not sent to the other hosts the spec
+// references
+type headerTransport struct {
+	host    string
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.base.RoundTrip(req)
+}
+
+// HTTPClient returns the HTTP client fetching the spec at the given URL and
+// the files it references, sending the headers to the host of the spec only
+func HTTPClient(specURL *url.URL, headers http.Header) *http.Client {
+	if len(headers) == 0 {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: &headerTransport{host: specURL.Host, headers: headers, base: http.DefaultTransport}}
+}
@@ -0,0 +1,223 @@
+// Package specload holds the spec handling shared by mcp-rest-server-gen and
+// mcp-rest-client-gen: fetching and caching remote specs, and giving the
+// operations the IDs the generated tools and client methods are named after,
+// so both generators name them the same way.
+package specload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Operation is an operation of the spec with its path and method
+type Operation struct {
+	Path      string
+	Method    string
+	Operation *openapi3.Operation
+}
+
+// RenameDuplicates gives the operations sharing an operationId unique IDs,
+// suffixed with the path segment telling them apart, such as ListBooksV2 for
+// /v2/books, or with the method when they share the path. The suffix is camel
+// cased so the ID is also the name given by oapi-codegen to the client method.
+// It returns the renames made, as "old -> new" in path order.
+func RenameDuplicates(doc *openapi3.T) []string {
+	byID, ids := duplicateOperationIDs(doc)
+	taken := make(map[string]bool, len(byID))
+	for id := range byID {
+		taken[id] = true
+	}
+
+	var renames []string
+	for _, id := range ids {
+		for _, op := range byID[id] {
+			suffix := operationSuffix(op, byID[id])
+			renamed := id + suffix
+			for n := 2; taken[renamed]; n++ {
+				renamed = fmt.Sprintf("%s%s%d", id, suffix, n)
+			}
+			taken[renamed] = true
+			op.Operation.OperationID = renamed
+			renames = append(renames, fmt.Sprintf("%s %s: %s -> %s", op.Method, op.Path, id, renamed))
+		}
+	}
+	return renames
+}
+
+// duplicateOperationIDs groups the operations of the spec by operationId, in
+// path then method order, and returns the groups with the sorted IDs shared by
+// several operations
+func duplicateOperationIDs(doc *openapi3.T) (map[string][]Operation, []string) {
+	byID := make(map[string][]Operation)
+	for _, op := range Operations(doc) {
+		if op.Operation.OperationID != "" {
+			byID[op.Operation.OperationID] = append(byID[op.Operation.OperationID], op)
+		}
+	}
+
+	var ids []string
+	for id, group := range byID {
+		if len(group) > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return byID, ids
+}
+
+// CheckDuplicates returns an error listing every operationId shared
+// by several operations with the method and path of these operations, which
+// would otherwise be reported one pair at a time by the spec validation
+func CheckDuplicates(doc *openapi3.T) error {
+	byID, ids := duplicateOperationIDs(doc)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	collisions := make([]string, 0, len(ids))
+	for _, id := range ids {
+		operations := make([]string, 0, len(byID[id]))
+		for _, op := range byID[id] {
+			operations = append(operations, op.Method+" "+op.Path)
+		}
+		collisions = append(collisions, fmt.Sprintf("%s (%s)", id, strings.Join(operations, ", ")))
+	}
+	return fmt.Errorf("operations share an operationId: %s; give them unique IDs in the spec or generate with --dedupe-strategy=rename", strings.Join(collisions, "; "))
+}
+
+// Operations returns the operations of the spec in path then method order
+func Operations(doc *openapi3.T) []Operation {
+	var ops []Operation
+	paths := doc.Paths.InMatchingOrder()
+	sort.Strings(paths)
+	for _, path := range paths {
+		operations := doc.Paths.Value(path).Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			ops = append(ops, Operation{path, method, operations[method]})
+		}
+	}
+	return ops
+}
+
+// SynthesizeIDs gives the operations without an operationId one made of their
+// method and path, the path parameters prefixed with By, such as GetBooksById
+// for GET /books/{id}. The ID is camel cased so it is also the name given by
+// oapi-codegen to the client method, and numbered when another operation has
+// it. It returns the IDs given, as "METHOD path: ID" in path order.
+func SynthesizeIDs(doc *openapi3.T) []string {
+	ops := Operations(doc)
+	taken := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		taken[op.Operation.OperationID] = true
+	}
+
+	var synthesized []string
+	for _, op := range ops {
+		if op.Operation.OperationID != "" {
+			continue
+		}
+		id := synthesizedOperationID(op.Method, op.Path)
+		for n := 2; taken[id]; n++ {
+			id = fmt.Sprintf("%s%d", synthesizedOperationID(op.Method, op.Path), n)
+		}
+		taken[id] = true
+		op.Operation.OperationID = id
+		synthesized = append(synthesized, fmt.Sprintf("%s %s: %s", op.Method, op.Path, id))
+	}
+	return synthesized
+}
+
+// synthesizedOperationID returns the operation ID made of the method and the
+// path segments, Root standing for the root path
+func synthesizedOperationID(method, path string) string {
+	id := CamelCase(strings.ToLower(method))
+	segments := pathSegments(path)
+	if len(segments) == 0 {
+		return id + "Root"
+	}
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			id += "By"
+		}
+		id += CamelCase(segment)
+	}
+	return id
+}
+
+// operationSuffix returns the camel cased suffix telling the operation apart
+// from the others of its group: the first segment of its path after the ones
+// shared with the other paths, all its segments from there when that one does
+// not tell the paths apart, then the method when another operation shares the
+// path
+func operationSuffix(op Operation, group []Operation) string {
+	segments := pathSegments(op.Path)
+	common, others, samePath := len(segments), [][]string{}, false
+	for _, other := range group {
+		if other.Operation == op.Operation {
+			continue
+		}
+		if other.Path == op.Path {
+			samePath = true
+			continue
+		}
+		others = append(others, pathSegments(other.Path))
+		common = min(common, sharedSegments(segments, others[len(others)-1]))
+	}
+
+	suffix := ""
+	switch {
+	case len(others) == 0:
+	case common == len(segments) && common == 0:
+		suffix = "Root"
+	case common == len(segments):
+		// The path is a prefix of another one
+		suffix = CamelCase(segments[common-1])
+	default:
+		suffix = CamelCase(segments[common])
+		for _, other := range others {
+			if common < len(other) && CamelCase(other[common]) == suffix {
+				suffix = CamelCase(strings.Join(segments[common:], "_"))
+				break
+			}
+		}
+	}
+	if samePath {
+		suffix += CamelCase(strings.ToLower(op.Method))
+	}
+	return suffix
+}
+
+// sharedSegments returns the number of leading segments shared by the paths
+func sharedSegments(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// pathSegments returns the segments of the path
+func pathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
+
+// CamelCase returns the text in camel case, each run of letters and digits
+// starting with an upper case letter
+func CamelCase(text string) string {
+	var suffix strings.Builder
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		suffix.WriteString(string(runes))
+	}
+	return suffix.String()
+}
@@ -0,0 +1,192 @@
+// Package tooldesc builds the descriptions of the MCP tools of OpenAPI
+// operations. It is used by mcp-rest-server-gen and by the generated servers
+// reloading their tool descriptions, so both describe a tool the same way.
+package tooldesc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// CategoryExtension is the operation extension grouping the tools into categories
+const CategoryExtension = "x-mcp-category"
+
+// Options choose how the tool descriptions are built, they mirror the
+// generator flags of the same names
+type Options struct {
+	// Source is the text of the operations used as description, description,
+	// summary or both, the other one being used when it is missing
+	Source string
+	// DescribeLinks documents the OpenAPI response links of the operations
+	DescribeLinks bool
+	// Suffixes are the texts appended to the descriptions, by tool name
+	Suffixes map[string]string
+}
+
+// Operation returns the description of the tool named name calling the
+// operation, falling back to its method and path when the operation has
+// neither a summary nor a description
+func Operation(name, method, path string, operation *openapi3.Operation, pathParameters openapi3.Parameters, options Options) string {
+	description := Text(operation.Summary, operation.Description, options.Source)
+	if description == "" {
+		description = fmt.Sprintf("%s %s", method, path)
+	}
+
+	if category := Category(operation); category != "" {
+		description = "[" + category + "] " + description
+	}
+
+	if note := ContentParameters(EffectiveParameters(operation, pathParameters)); note != "" {
+		description = description + "\n\n" + note
+	}
+
+	if options.DescribeLinks {
+		if links := Links(operation); links != "" {
+			description = description + "\n\n" + links
+		}
+	}
+
+	if suffix := options.Suffixes[name]; suffix != "" {
+		description = description + "\n\n" + suffix
+	}
+	return description
+}
+
+// Text returns the summary, the description or both, separated by a newline,
+// as chosen with the source, falling back to the other one when the chosen
+// one is missing
+func Text(summary, description, source string) string {
+	if summary == "" {
+		return description
+	}
+	if description == "" {
+		return summary
+	}
+
+	switch source {
+	case "summary":
+		return summary
+	case "both":
+		if summary == description {
+			return summary
+		}
+		return summary + "\n" + description
+	}
+	return description
+}
+
+// Category returns the trimmed category of the operation, empty when it has
+// none or when the extension is not a string
+func Category(operation *openapi3.Operation) string {
+	category, _ := operation.Extensions[CategoryExtension].(string)
+	return strings.TrimSpace(category)
+}
+
+// Links renders the OpenAPI links declared on the operation responses as
+// text, so the LLM knows which operations can be chained after this one
+func Links(operation *openapi3.Operation) string {
+	if operation.Responses == nil {
+		return ""
+	}
+
+	var lines []string
+	responses := operation.Responses.Map()
+	statuses := make([]string, 0, len(responses))
+	for status := range responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	for _, status := range statuses {
+		response := responses[status]
+		if response == nil || response.Value == nil {
+			continue
+		}
+
+		names := make([]string, 0, len(response.Value.Links))
+		for name := range response.Value.Links {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			link := response.Value.Links[name]
+			if link == nil || link.Value == nil {
+				continue
+			}
+
+			// Links may point to the target by ID or by reference
+			target := link.Value.OperationID
+			if target == "" {
+				target = link.Value.OperationRef
+			}
+			if target == "" {
+				continue
+			}
+
+			line := fmt.Sprintf("- %s (after a %s response)", target, status)
+
+			params := make([]string, 0, len(link.Value.Parameters))
+			for param, expr := range link.Value.Parameters {
+				params = append(params, fmt.Sprintf("%s=%v", param, expr))
+			}
+			sort.Strings(params)
+			if len(params) > 0 {
+				line += ": pass " + strings.Join(params, ", ")
+			}
+			if link.Value.Description != "" {
+				line += ". " + link.Value.Description
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return "Related operations:\n" + strings.Join(lines, "\n")
+}
+
+// ContentParameters tells how the parameters declaring non-JSON content are
+// sent, as the tool only sees them as plain strings. JSON content parameters
+// are exposed with their schema and encoded by the client.
+func ContentParameters(parameters []*openapi3.Parameter) string {
+	var lines []string
+	for _, param := range parameters {
+		for contentType := range param.Content {
+			if strings.Contains(contentType, "json") {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("The %s parameter is sent as-is and must be encoded as %s.", param.Name, contentType))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// EffectiveParameters returns the parameters applying to the operation, the
+// ones of the path item followed by the ones of the operation, which override
+// path item parameters with the same name and location
+func EffectiveParameters(operation *openapi3.Operation, pathParameters openapi3.Parameters) []*openapi3.Parameter {
+	var parameters []*openapi3.Parameter
+	for _, paramRef := range pathParameters {
+		if paramRef == nil || paramRef.Value == nil {
+			continue
+		}
+		if operation.Parameters.GetByInAndName(paramRef.Value.In, paramRef.Value.Name) != nil {
+			continue
+		}
+		parameters = append(parameters, paramRef.Value)
+	}
+	for _, paramRef := range operation.Parameters {
+		if paramRef == nil || paramRef.Value == nil {
+			continue
+		}
+		parameters = append(parameters, paramRef.Value)
+	}
+	return parameters
+}
@@ -0,0 +1,100 @@
+package tooldesc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const spec = `
+openapi: 3.0.3
+info: {title: Books, version: "1"}
+paths:
+  /books/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+      - {name: filter, in: query, content: {text/plain: {schema: {type: string}}}}
+    get:
+      summary: Gets a book
+      description: Gets a book by its identifier
+      x-mcp-category: " Catalog "
+      responses:
+        "200":
+          description: ok
+          links:
+            Author: {operationId: GetAuthor, parameters: {id: "$response.body#/authorId"}, description: The author of the book}
+    delete:
+      responses:
+        "204": {description: deleted}
+`
+
+func loadOperation(t *testing.T, method string) (*openapi3.Operation, openapi3.Parameters) {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pathItem := doc.Paths.Value("/books/{id}")
+	return pathItem.GetOperation(method), pathItem.Parameters
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		summary, description, source, want string
+	}{
+		{"Gets", "Gets a book", "description", "Gets a book"},
+		{"Gets", "Gets a book", "summary", "Gets"},
+		{"Gets", "Gets a book", "both", "Gets\nGets a book"},
+		{"Gets", "Gets", "both", "Gets"},
+		{"", "Gets a book", "summary", "Gets a book"},
+		{"Gets", "", "description", "Gets"},
+	}
+	for _, test := range tests {
+		if got := Text(test.summary, test.description, test.source); got != test.want {
+			t.Errorf("Text(%q, %q, %q) = %q, want %q", test.summary, test.description, test.source, got, test.want)
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	operation, pathParameters := loadOperation(t, "GET")
+	options := Options{Source: "summary", DescribeLinks: true, Suffixes: map[string]string{"GetBook": "Cached for a day."}}
+
+	want := "[Catalog] Gets a book\n\n" +
+		"The filter parameter is sent as-is and must be encoded as text/plain.\n\n" +
+		"Related operations:\n- GetAuthor (after a 200 response): pass id=$response.body#/authorId. The author of the book\n\n" +
+		"Cached for a day."
+	if got := Operation("GetBook", "GET", "/books/{id}", operation, pathParameters, options); got != want {
+		t.Errorf("Operation() = %q, want %q", got, want)
+	}
+
+	// The links and the suffixes of the other tools are left out
+	options = Options{Source: "summary", Suffixes: map[string]string{"ListBooks": "Paginated."}}
+	want = "[Catalog] Gets a book\n\nThe filter parameter is sent as-is and must be encoded as text/plain."
+	if got := Operation("GetBook", "GET", "/books/{id}", operation, pathParameters, options); got != want {
+		t.Errorf("Operation() = %q, want %q", got, want)
+	}
+}
+
+func TestOperationWithoutText(t *testing.T) {
+	operation, _ := loadOperation(t, "DELETE")
+	if got, want := Operation("DeleteBook", "DELETE", "/books/{id}", operation, nil, Options{}), "DELETE /books/{id}"; got != want {
+		t.Errorf("Operation() = %q, want %q", got, want)
+	}
+}
+
+func TestEffectiveParameters(t *testing.T) {
+	operation, pathParameters := loadOperation(t, "GET")
+	operation.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewQueryParameter("filter").WithSchema(openapi3.NewStringSchema())},
+	}
+
+	parameters := EffectiveParameters(operation, pathParameters)
+	if len(parameters) != 2 || parameters[0].Name != "id" || parameters[1] != operation.Parameters[0].Value {
+		t.Errorf("EffectiveParameters() = %v, want the id path parameter and the filter of the operation", parameters)
+	}
+}